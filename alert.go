@@ -0,0 +1,158 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 告警中附带的错误日志数量上限
+const maxAlertSamples = 5
+
+// errorAlert AlertWindow内的错误日志达到AlertThreshold时，发送告警到webhook
+// 每个窗口最多告警一次
+type errorAlert struct {
+	mu         sync.Mutex
+	url        string
+	kind       string
+	threshold  int
+	window     time.Duration
+	service    string
+	attributes map[string]interface{}
+	client     *http.Client
+	start      time.Time
+	count      int
+	samples    []string
+}
+
+// newErrorAlert threshold默认为10，window默认为1分钟
+func newErrorAlert(conf Config, service string, attributes []Field) *errorAlert {
+	a := &errorAlert{
+		url:        conf.AlertWebhook,
+		kind:       conf.AlertWebhookType,
+		threshold:  conf.AlertThreshold,
+		window:     conf.AlertWindow,
+		service:    service,
+		attributes: map[string]interface{}{},
+		client:     &http.Client{Timeout: 3 * time.Second},
+	}
+	if a.threshold <= 0 {
+		a.threshold = 10
+	}
+	if a.window <= 0 {
+		a.window = time.Minute
+	}
+	if a.kind == "" {
+		a.kind = alertKindOf(a.url)
+	}
+	for _, attr := range attributes {
+		a.attributes[attr.Key] = attr.Value()
+	}
+	return a
+}
+
+// alertKindOf 根据webhook的地址判断类型
+func alertKindOf(webhook string) string {
+	u, err := url.Parse(webhook)
+	if err != nil {
+		return "webhook"
+	}
+	switch u.Hostname() {
+	case "hooks.slack.com":
+		return "slack"
+	case "oapi.dingtalk.com":
+		return "dingtalk"
+	case "qyapi.weixin.qq.com":
+		return "wecom"
+	}
+	return "webhook"
+}
+
+// alertError 记录一条错误日志，达到阈值时发送告警
+func alertError(msg string) {
+	if a := loadState().alert; a != nil {
+		a.record(msg, time.Now())
+	}
+}
+
+func (a *errorAlert) record(msg string, now time.Time) {
+	a.mu.Lock()
+	if now.Sub(a.start) > a.window {
+		a.start = now
+		a.count = 0
+		a.samples = nil
+	}
+	a.count++
+	if len(a.samples) < maxAlertSamples {
+		a.samples = append(a.samples, msg)
+	}
+	if a.count != a.threshold {
+		a.mu.Unlock()
+		return
+	}
+	count, samples := a.count, append([]string(nil), a.samples...)
+	a.mu.Unlock()
+	go func() {
+		if err := a.send(count, samples); err != nil {
+			// 不能记录为Error日志，否则会再次触发告警
+			log.Printf("logx: send alert failed: %v", err)
+		}
+	}()
+}
+
+// payload 按webhook的类型生成告警内容
+func (a *errorAlert) payload(count int, samples []string) interface{} {
+	var text strings.Builder
+	fmt.Fprintf(&text, "[%s] %d errors in %s", a.service, count, a.window)
+	keys := make([]string, 0, len(a.attributes))
+	for key := range a.attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&text, "\n%s: %v", key, a.attributes[key])
+	}
+	for _, sample := range samples {
+		text.WriteString("\n- " + sample)
+	}
+	switch a.kind {
+	case "slack":
+		return map[string]string{"text": text.String()}
+	case "dingtalk", "wecom":
+		return map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]string{"content": text.String()},
+		}
+	}
+	return map[string]interface{}{
+		"service":    a.service,
+		"attributes": a.attributes,
+		"count":      count,
+		"window":     a.window.String(),
+		"samples":    samples,
+		"text":       text.String(),
+	}
+}
+
+func (a *errorAlert) send(count int, samples []string) error {
+	body, err := json.Marshal(a.payload(count, samples))
+	if err != nil {
+		return err
+	}
+	resp, err := a.client.Post(a.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package logx
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// auditRequired 审计日志必须包含的字段，action为Audit的参数
+var auditRequired = []string{"actor", "target", "result"}
+
+// auditReserved 审计日志的保留字段，fields中的同名字段被忽略
+var auditReserved = map[string]bool{"time": true, "seq": true, "prev_hash": true, "hash": true, "action": true, "service": true}
+
+type auditWriter struct {
+	mu       sync.Mutex
+	file     *os.File
+	service  string
+	seq      int64
+	prevHash string
+}
+
+// newAuditWriter 以追加的方式打开审计日志文件，从最后一条记录继续序号及hash链
+func newAuditWriter(path, service string) (*auditWriter, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	w := &auditWriter{service: service}
+	if last, err := lastAuditRecord(path); err != nil {
+		return nil, err
+	} else if last != nil {
+		if err := json.Unmarshal(last["seq"], &w.seq); err != nil {
+			return nil, fmt.Errorf("logx: invalid audit record: %w", err)
+		}
+		if err := json.Unmarshal(last["hash"], &w.prevHash); err != nil {
+			return nil, fmt.Errorf("logx: invalid audit record: %w", err)
+		}
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, err
+	}
+	w.file = file
+	return w, nil
+}
+
+// lastAuditRecord 读取审计日志文件的最后一条记录，文件不存在时返回nil
+func lastAuditRecord(path string) (map[string]json.RawMessage, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 {
+		return nil, nil
+	}
+	if i := bytes.LastIndexByte(content, '\n'); i >= 0 {
+		content = content[i+1:]
+	}
+	var record map[string]json.RawMessage
+	if err := json.Unmarshal(content, &record); err != nil {
+		return nil, fmt.Errorf("logx: invalid audit record: %w", err)
+	}
+	return record, nil
+}
+
+func (w *auditWriter) write(record map[string]interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	record["seq"] = w.seq + 1
+	record["prev_hash"] = w.prevHash
+	hash, err := auditHash(record)
+	if err != nil {
+		return err
+	}
+	record["hash"] = hash
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if _, err := w.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	// 审计日志不允许丢失，每条都写入磁盘
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	w.seq++
+	w.prevHash = hash
+	return nil
+}
+
+func (w *auditWriter) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// auditHash 不含hash字段的记录的sha256，记录中包含上一条的hash，形成hash链
+func auditHash(record map[string]interface{}) (string, error) {
+	content, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Audit 记录审计日志，写入AuditFile，不切割、不采样且不受日志等级限制
+// fields中必须包含actor(操作人),target(操作对象),result(结果)，ctx中的trace_id,span_id及WithFields保存的字段会一同记录
+// 每条记录包含递增的seq及上一条记录的hash(prev_hash)，可通过VerifyAudit检查是否被篡改
+//
+// example:
+//
+//	logx.Audit(ctx, "user.delete", logx.String("actor", "admin"), logx.String("target", "user:1"), logx.String("result", "success"))
+func Audit(ctx context.Context, action string, fields ...Field) error {
+	w := loadState().audit
+	if w == nil {
+		return errors.New("logx: AuditFile is not configured")
+	}
+	fields = withContextFields(ctx, fields)
+	for _, key := range auditRequired {
+		if _, ok := Fields(fields).Get(key); !ok {
+			return fmt.Errorf("logx: audit field %s is required", key)
+		}
+	}
+	record := map[string]interface{}{
+		"time":    time.Now().Format(time.RFC3339Nano),
+		"action":  action,
+		"service": w.service,
+	}
+	if traceID := TraceID(ctx); traceID != "" {
+		record["trace_id"] = traceID
+		record["span_id"] = SpanID(ctx)
+	}
+	st := loadState()
+	for _, f := range fields {
+		f = st.redactField(f)
+		if auditReserved[f.Key] {
+			continue
+		}
+		switch f.Type {
+		case namespaceType:
+		case errType:
+			record[f.Key] = f.Err.Error()
+		case objectType, arrayType:
+			record[f.Key] = marshalerValue(f)
+		case durationType:
+			record[f.Key] = f.Duration.String()
+		default:
+			record[f.Key] = f.Value()
+		}
+	}
+	return w.write(record)
+}
+
+// VerifyAudit 检查审计日志文件的seq是否连续及hash链是否完整，返回第一处不一致的错误
+func VerifyAudit(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16<<20)
+	var seq int64
+	prevHash := ""
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		seq++
+		var record map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		if err := dec.Decode(&record); err != nil {
+			return fmt.Errorf("logx: audit record %d: %w", seq, err)
+		}
+		if n, ok := record["seq"].(json.Number); !ok || n.String() != fmt.Sprint(seq) {
+			return fmt.Errorf("logx: audit record %d: unexpected seq %v", seq, record["seq"])
+		}
+		if record["prev_hash"] != prevHash {
+			return fmt.Errorf("logx: audit record %d: prev_hash mismatch", seq)
+		}
+		hash, _ := record["hash"].(string)
+		delete(record, "hash")
+		if expected, err := auditHash(record); err != nil || expected != hash {
+			return fmt.Errorf("logx: audit record %d: hash mismatch", seq)
+		}
+		prevHash = hash
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,87 @@
+package logx
+
+import (
+	"context"
+	"sync"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// errorTraces 发生错误的trace及剩余可提升的日志条数
+var errorTraces = &boostTraces{remaining: map[string]int{}}
+
+// maxBoostTraces 同时记录的trace数量上限，超过时淘汰最早的
+const maxBoostTraces = 1024
+
+type boostTraces struct {
+	mu        sync.Mutex
+	remaining map[string]int
+	order     []string
+}
+
+// add 记录发生错误的trace
+func (b *boostTraces) add(traceID string, count int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.remaining[traceID]; ok {
+		return
+	}
+	if len(b.order) >= maxBoostTraces {
+		delete(b.remaining, b.order[0])
+		b.order = b.order[1:]
+	}
+	b.remaining[traceID] = count
+	b.order = append(b.order, traceID)
+}
+
+// take 消耗一条可提升的日志，返回是否提升
+func (b *boostTraces) take(traceID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n, ok := b.remaining[traceID]
+	if !ok || n <= 0 {
+		return false
+	}
+	b.remaining[traceID] = n - 1
+	return true
+}
+
+// boosted sc所在的trace是否还有可提升的日志
+func (b *boostTraces) boosted(sc oteltrace.SpanContext) bool {
+	if !sc.IsValid() {
+		return false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.remaining[sc.TraceID().String()] > 0
+}
+
+// boostTrace 标记ctx所在的trace发生了错误，并写入该trace之前保存的日志
+func boostTrace(ctx context.Context) {
+	s := loadState()
+	if s.boost == nil {
+		return
+	}
+	if sc := oteltrace.SpanContextFromContext(ctx); sc.IsValid() {
+		traceID := sc.TraceID().String()
+		flushErrorBuffer(s.boost, traceID)
+		if s.config.ErrorBoost > 0 {
+			errorTraces.add(traceID, s.config.ErrorBoost)
+		}
+	}
+}
+
+// levelLogger 返回记录level日志的logger
+// 所在的trace发生过错误且level未开启时，返回不受日志等级限制的logger
+func levelLogger(ctx context.Context, level zapcore.Level) *zap.Logger {
+	s := loadState()
+	if s.boost == nil || s.logger.Core().Enabled(level) {
+		return s.logger
+	}
+	if sc := oteltrace.SpanContextFromContext(ctx); sc.IsValid() && errorTraces.take(sc.TraceID().String()) {
+		return s.boost
+	}
+	return s.logger
+}
@@ -0,0 +1,477 @@
+package logx
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PutLogEvents的限制
+const (
+	cloudwatchMaxEvents     = 10000
+	cloudwatchMaxBatchBytes = 1048576
+	cloudwatchEventOverhead = 26
+	cloudwatchMaxEventBytes = 262144 - cloudwatchEventOverhead
+)
+
+// cloudwatchSink 批量写入AWS CloudWatch Logs
+type cloudwatchSink struct {
+	mu       sync.Mutex
+	group    string
+	stream   string
+	region   string
+	endpoint string
+	creds    *awsCredentials
+	client   *http.Client
+	events   []cloudwatchEvent
+	size     int
+	// 发送的锁，保证sequenceToken的顺序
+	sendMu  sync.Mutex
+	token   string
+	created bool
+	flush   chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+type cloudwatchEvent struct {
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+// newCloudWatchSink CloudWatchStream默认为{serviceName}/{hostname}，CloudWatchRegion默认为AWS_REGION
+func newCloudWatchSink(conf Config, service string) (*cloudwatchSink, error) {
+	if conf.CloudWatchGroup == "" {
+		return nil, errors.New("cloudwatch log group is required")
+	}
+	s := &cloudwatchSink{
+		group:    conf.CloudWatchGroup,
+		stream:   conf.CloudWatchStream,
+		region:   conf.CloudWatchRegion,
+		endpoint: conf.CloudWatchEndpoint,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		flush:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	if s.stream == "" {
+		host, _ := os.Hostname()
+		s.stream = service + "/" + host
+	}
+	if s.region == "" {
+		s.region = os.Getenv("AWS_REGION")
+	}
+	if s.region == "" {
+		s.region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if s.region == "" {
+		return nil, errors.New("cloudwatch region is required")
+	}
+	if s.endpoint == "" {
+		s.endpoint = "https://logs." + s.region + ".amazonaws.com"
+	}
+	s.creds = &awsCredentials{client: &http.Client{Timeout: time.Second}}
+	interval := conf.CloudWatchFlushInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	s.wg.Add(1)
+	go s.run(interval)
+	return s, nil
+}
+
+func (s *cloudwatchSink) run(interval time.Duration) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.flush:
+		case <-s.done:
+			return
+		}
+		s.Sync()
+	}
+}
+
+func (s *cloudwatchSink) Write(p []byte) (int, error) {
+	message := string(bytes.TrimSuffix(p, []byte("\n")))
+	// 按utf8字符的边界截断
+	message = message[:splitPoint(message, cloudwatchMaxEventBytes)]
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, cloudwatchEvent{Timestamp: time.Now().UnixMilli(), Message: message})
+	s.size += len(message) + cloudwatchEventOverhead
+	if len(s.events) >= cloudwatchMaxEvents || s.size >= cloudwatchMaxBatchBytes {
+		select {
+		case s.flush <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Sync 立即发送缓存的日志，按PutLogEvents的限制分批
+func (s *cloudwatchSink) Sync() error {
+	s.mu.Lock()
+	events := s.events
+	s.events = nil
+	s.size = 0
+	s.mu.Unlock()
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	var errs []error
+	for len(events) > 0 {
+		n, size := 0, 0
+		for n < len(events) && n < cloudwatchMaxEvents {
+			size += len(events[n].Message) + cloudwatchEventOverhead
+			if size > cloudwatchMaxBatchBytes {
+				break
+			}
+			n++
+		}
+		if err := s.put(events[:n]); err != nil {
+			stats.writeErrors.Add(int64(n))
+			errs = append(errs, err)
+		}
+		events = events[n:]
+	}
+	return errors.Join(errs...)
+}
+
+// put 发送一批日志，日志流不存在时创建，sequenceToken错误时使用期望的token重试一次
+func (s *cloudwatchSink) put(events []cloudwatchEvent) error {
+	if !s.created {
+		if err := s.createStream(); err != nil {
+			return err
+		}
+		s.created = true
+	}
+	var err error
+	for i := 0; i < 2; i++ {
+		request := map[string]interface{}{
+			"logGroupName":  s.group,
+			"logStreamName": s.stream,
+			"logEvents":     events,
+		}
+		if s.token != "" {
+			request["sequenceToken"] = s.token
+		}
+		var response struct {
+			NextSequenceToken string `json:"nextSequenceToken"`
+		}
+		if err = s.call("PutLogEvents", request, &response); err == nil {
+			s.token = response.NextSequenceToken
+			return nil
+		}
+		var awsErr *awsError
+		if !errors.As(err, &awsErr) || awsErr.ExpectedSequenceToken == "" {
+			return err
+		}
+		if awsErr.is("DataAlreadyAcceptedException") {
+			s.token = awsErr.ExpectedSequenceToken
+			return nil
+		}
+		s.token = awsErr.ExpectedSequenceToken
+	}
+	return err
+}
+
+// createStream 创建日志流，日志组不存在时先创建日志组
+func (s *cloudwatchSink) createStream() error {
+	stream := map[string]string{"logGroupName": s.group, "logStreamName": s.stream}
+	err := s.call("CreateLogStream", stream, nil)
+	var awsErr *awsError
+	if errors.As(err, &awsErr) && awsErr.is("ResourceNotFoundException") {
+		err = s.call("CreateLogGroup", map[string]string{"logGroupName": s.group}, nil)
+		if err == nil || (errors.As(err, &awsErr) && awsErr.is("ResourceAlreadyExistsException")) {
+			err = s.call("CreateLogStream", stream, nil)
+		}
+	}
+	if errors.As(err, &awsErr) && awsErr.is("ResourceAlreadyExistsException") {
+		return nil
+	}
+	return err
+}
+
+// call 调用CloudWatch Logs的接口
+func (s *cloudwatchSink) call(target string, request, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	creds, err := s.creds.get()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "Logs_20140328."+target)
+	signAWSRequest(req, body, creds, s.region, "logs", time.Now())
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		awsErr := &awsError{Status: resp.Status}
+		json.Unmarshal(data, awsErr)
+		return awsErr
+	}
+	if response != nil {
+		return json.Unmarshal(data, response)
+	}
+	return nil
+}
+
+// Close 发送剩余的日志并停止定时发送
+func (s *cloudwatchSink) Close() error {
+	close(s.done)
+	s.wg.Wait()
+	return s.Sync()
+}
+
+// awsError AWS json协议返回的错误
+type awsError struct {
+	Status                string `json:"-"`
+	Type                  string `json:"__type"`
+	Message               string `json:"message"`
+	ExpectedSequenceToken string `json:"expectedSequenceToken"`
+}
+
+func (e *awsError) Error() string {
+	return fmt.Sprintf("%s: %s %s", e.Status, e.Type, e.Message)
+}
+
+// is 错误类型是否匹配，__type可能带有命名空间前缀，如com.amazonaws.logs#ResourceNotFoundException
+func (e *awsError) is(name string) bool {
+	return e.Type == name || strings.HasSuffix(e.Type, "#"+name)
+}
+
+// awsCreds AWS的访问凭证
+type awsCreds struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	Token           string    `json:"Token"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+// awsCredentials 按顺序查找凭证：环境变量，共享凭证文件，ECS容器凭证，EC2实例角色
+// 容器及实例角色的凭证在过期前5分钟刷新
+type awsCredentials struct {
+	mu     sync.Mutex
+	client *http.Client
+	cached awsCreds
+}
+
+func (c *awsCredentials) get() (awsCreds, error) {
+	if creds, ok := envCredentials(); ok {
+		return creds, nil
+	}
+	if creds, ok := sharedCredentials(); ok {
+		return creds, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cached.AccessKeyID != "" && time.Until(c.cached.Expiration) > 5*time.Minute {
+		return c.cached, nil
+	}
+	creds, err := c.containerCredentials()
+	if err != nil {
+		creds, err = c.instanceCredentials()
+	}
+	if err != nil {
+		return awsCreds{}, errors.New("no aws credentials found")
+	}
+	c.cached = creds
+	return creds, nil
+}
+
+func envCredentials() (awsCreds, bool) {
+	creds := awsCreds{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		Token:           os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	return creds, creds.AccessKeyID != "" && creds.SecretAccessKey != ""
+}
+
+// sharedCredentials 读取~/.aws/credentials中AWS_PROFILE（默认default）的凭证
+func sharedCredentials() (awsCreds, bool) {
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return awsCreds{}, false
+		}
+		path = filepath.Join(home, ".aws", "credentials")
+	}
+	profile := os.Getenv("AWS_PROFILE")
+	if profile == "" {
+		profile = "default"
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return awsCreds{}, false
+	}
+	defer file.Close()
+	var creds awsCreds
+	var section string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || section != profile {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "aws_access_key_id":
+			creds.AccessKeyID = strings.TrimSpace(value)
+		case "aws_secret_access_key":
+			creds.SecretAccessKey = strings.TrimSpace(value)
+		case "aws_session_token":
+			creds.Token = strings.TrimSpace(value)
+		}
+	}
+	return creds, creds.AccessKeyID != "" && creds.SecretAccessKey != ""
+}
+
+// containerCredentials ECS,EKS Pod Identity等容器环境的凭证
+func (c *awsCredentials) containerCredentials() (awsCreds, error) {
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		endpoint = "http://169.254.170.2" + uri
+	}
+	if endpoint == "" {
+		return awsCreds{}, errors.New("not in container")
+	}
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return awsCreds{}, err
+	}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	var creds awsCreds
+	err = c.getJSON(req, &creds)
+	return creds, err
+}
+
+// instanceCredentials EC2实例角色的凭证，使用IMDSv2
+func (c *awsCredentials) instanceCredentials() (awsCreds, error) {
+	const imds = "http://169.254.169.254/latest"
+	req, _ := http.NewRequest(http.MethodPut, imds+"/api/token", nil)
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600")
+	token, err := c.getString(req)
+	if err != nil {
+		return awsCreds{}, err
+	}
+	req, _ = http.NewRequest(http.MethodGet, imds+"/meta-data/iam/security-credentials/", nil)
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+	role, err := c.getString(req)
+	if err != nil {
+		return awsCreds{}, err
+	}
+	role, _, _ = strings.Cut(strings.TrimSpace(role), "\n")
+	req, _ = http.NewRequest(http.MethodGet, imds+"/meta-data/iam/security-credentials/"+url.PathEscape(role), nil)
+	req.Header.Set("X-aws-ec2-metadata-token", token)
+	var creds awsCreds
+	err = c.getJSON(req, &creds)
+	return creds, err
+}
+
+func (c *awsCredentials) getString(req *http.Request) (string, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	return string(data), err
+}
+
+func (c *awsCredentials) getJSON(req *http.Request, v interface{}) error {
+	data, err := c.getString(req)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(data), v)
+}
+
+// signAWSRequest 使用AWS Signature Version 4签名请求
+func signAWSRequest(req *http.Request, body []byte, creds awsCreds, region, service string, t time.Time) {
+	date := t.UTC().Format("20060102T150405Z")
+	day := date[:8]
+	req.Header.Set("X-Amz-Date", date)
+	if creds.Token != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.Token)
+	}
+	headers := map[string]string{"host": req.URL.Host}
+	for key, values := range req.Header {
+		headers[strings.ToLower(key)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	bodyHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+	scope := day + "/" + region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + date + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), day)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,130 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Cmd 附带追踪的exec.Cmd
+// 子进程的stdout,stderr按行记录为日志，执行过程记录为span
+type Cmd struct {
+	*exec.Cmd
+	ctx    context.Context
+	stdout *cmdWriter
+	stderr *cmdWriter
+}
+
+// CommandContext 创建附带追踪的子进程命令
+// 追踪信息以环境变量的方式传递给子进程，子进程可以使用ExtractEnv解析
+// 需要调用Run或Start,Wait，以结束span
+//
+// example:
+// err := CommandContext(ctx, "sh", "-c", "echo hello").Run()
+func CommandContext(ctx context.Context, name string, args ...string) *Cmd {
+	spanCtx := Start(ctx, "exec "+name, String("process.command", name), StringSlice("process.command_args", args))
+	cmd := &Cmd{Cmd: exec.CommandContext(ctx, name, args...), ctx: spanCtx}
+	cmd.Env = os.Environ()
+	for key, value := range Inject(spanCtx) {
+		cmd.Env = append(cmd.Env, strings.ToUpper(key)+"="+value)
+	}
+	cmd.stdout = &cmdWriter{cmd: cmd, stream: "stdout"}
+	cmd.stderr = &cmdWriter{cmd: cmd, stream: "stderr"}
+	cmd.Stdout = cmd.stdout
+	cmd.Stderr = cmd.stderr
+	return cmd
+}
+
+// Context 返回子进程span的context
+func (c *Cmd) Context() context.Context {
+	return c.ctx
+}
+
+// Start 启动子进程，失败时结束span
+func (c *Cmd) Start() error {
+	err := c.Cmd.Start()
+	if err != nil {
+		EndWithError(c.ctx, err)
+	}
+	return err
+}
+
+// Wait 等待子进程结束，记录退出码并结束span
+func (c *Cmd) Wait() error {
+	err := c.Cmd.Wait()
+	c.stdout.flush()
+	c.stderr.flush()
+	if c.ProcessState != nil {
+		SetSpanAttr(c.ctx, Int("process.exit_code", c.ProcessState.ExitCode()))
+	}
+	EndWithError(c.ctx, err)
+	return err
+}
+
+// Run 启动子进程并等待结束
+func (c *Cmd) Run() error {
+	if err := c.Start(); err != nil {
+		return err
+	}
+	return c.Wait()
+}
+
+// cmdWriter 将子进程的输出按行记录为日志，stdout为info，stderr为warn
+type cmdWriter struct {
+	mu     sync.Mutex
+	cmd    *Cmd
+	stream string
+	buf    []byte
+}
+
+func (w *cmdWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.log(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush 记录剩余不足一行的输出
+func (w *cmdWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.log(string(w.buf))
+		w.buf = nil
+	}
+}
+
+func (w *cmdWriter) log(line string) {
+	fields := []Field{String("stream", w.stream)}
+	if w.cmd.Process != nil {
+		fields = append(fields, Int("pid", w.cmd.Process.Pid))
+	}
+	if w.stream == "stderr" {
+		Warn(w.cmd.ctx, line, fields...)
+	} else {
+		Info(w.cmd.ctx, line, fields...)
+	}
+}
+
+// ExtractEnv 从环境变量中解析CommandContext传递的追踪信息
+// 用于子进程中，使其日志及span与父进程关联
+func ExtractEnv(ctx context.Context) context.Context {
+	carrier := map[string]string{}
+	for _, key := range propagatorOf().Fields() {
+		if value := os.Getenv(strings.ToUpper(key)); value != "" {
+			carrier[key] = value
+		}
+	}
+	return Extract(ctx, carrier)
+}
@@ -0,0 +1,83 @@
+package logx
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// selfCompress 由logx压缩备份文件，lumberjack只支持默认级别的gzip
+func selfCompress(conf Config) bool {
+	return conf.Compress && (conf.CompressType == "zstd" || conf.CompressLevel != 0)
+}
+
+// lumberjackCompress 是否由lumberjack压缩备份文件
+func lumberjackCompress(conf Config) bool {
+	return conf.Compress && !selfCompress(conf)
+}
+
+// compressExt 压缩后备份文件的扩展名
+func compressExt(conf Config) string {
+	if conf.CompressType == "zstd" {
+		return ".zst"
+	}
+	return ".gz"
+}
+
+// compressFile 按CompressType,CompressLevel压缩备份文件，成功后删除原文件，返回压缩后的文件名
+func compressFile(src string, conf Config) (string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return "", err
+	}
+	dst := src + compressExt(conf)
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, info.Mode())
+	if err != nil {
+		return "", err
+	}
+	if err := compressTo(out, in, conf); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return "", err
+	}
+	// 保留修改时间，按时间清理备份文件时与未压缩的一致
+	os.Chtimes(dst, info.ModTime(), info.ModTime())
+	return dst, os.Remove(src)
+}
+
+func compressTo(w io.Writer, r io.Reader, conf Config) error {
+	var enc io.WriteCloser
+	var err error
+	if conf.CompressType == "zstd" {
+		var opts []zstd.EOption
+		if conf.CompressLevel > 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(conf.CompressLevel)))
+		}
+		enc, err = zstd.NewWriter(w, opts...)
+	} else {
+		level := gzip.DefaultCompression
+		if conf.CompressLevel != 0 {
+			level = conf.CompressLevel
+		}
+		enc, err = gzip.NewWriterLevel(w, level)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(enc, r); err != nil {
+		enc.Close()
+		return err
+	}
+	return enc.Close()
+}
@@ -0,0 +1,150 @@
+package logx
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// datePattern 日志文件路径中的日期模板，如./logs/{service}-{2006-01-02}.log
+var datePattern = regexp.MustCompile(`\{([0-9_.\-T]+)\}`)
+
+// isFileTemplate {service}及日期模板不作为分区字段
+func isFileTemplate(placeholder string) bool {
+	return placeholder == "{service}" || datePattern.MatchString(placeholder)
+}
+
+// expandFile 替换日志文件路径中的{service}及日期模板
+func expandFile(file, service string, t time.Time) string {
+	file = strings.ReplaceAll(file, "{service}", service)
+	return datePattern.ReplaceAllStringFunc(file, func(layout string) string {
+		return t.Format(layout[1 : len(layout)-1])
+	})
+}
+
+// fileWriter 日志文件
+type fileWriter interface {
+	zapcore.WriteSyncer
+	fileRotator
+	io.Closer
+}
+
+// newFileWriter 打开conf.File，包含日期模板时日期变化后切换文件
+func newFileWriter(conf Config, service string) fileWriter {
+	if datePattern.MatchString(conf.File) {
+		return newDatedWriter(conf, service)
+	}
+	conf.File = expandFile(conf.File, service, time.Now())
+	w := newRotateWriter(&lumberjack.Logger{
+		Filename:   conf.File,
+		MaxSize:    conf.MaxSize,
+		MaxBackups: conf.MaxBackups,
+		MaxAge:     conf.MaxAge,
+		LocalTime:  true,
+		Compress:   lumberjackCompress(conf),
+	}, conf, service)
+	w.pruneTotalSize()
+	if conf.FileLink != "" {
+		updateFileLink(conf.FileLink, conf.File)
+	}
+	return w
+}
+
+// datedWriter 文件名包含日期模板时，日期变化后写入新的文件
+type datedWriter struct {
+	mu        sync.Mutex
+	conf      Config
+	service   string
+	current   *rotateWriter
+	lastCheck int64
+}
+
+func newDatedWriter(conf Config, service string) *datedWriter {
+	w := &datedWriter{conf: conf, service: service}
+	w.open(expandFile(conf.File, service, time.Now()))
+	return w
+}
+
+// open 打开日志文件，并更新FileLink
+func (w *datedWriter) open(filename string) {
+	conf := w.conf
+	conf.File = filename
+	w.current = newRotateWriter(&lumberjack.Logger{
+		Filename:   filename,
+		MaxSize:    conf.MaxSize,
+		MaxBackups: conf.MaxBackups,
+		MaxAge:     conf.MaxAge,
+		LocalTime:  true,
+		Compress:   lumberjackCompress(conf),
+	}, conf, w.service)
+	w.current.datedPattern = datePattern.ReplaceAllLiteralString(strings.ReplaceAll(w.conf.File, "{service}", w.service), "*")
+	w.current.pruneTotalSize()
+	if w.conf.FileLink != "" {
+		updateFileLink(w.conf.FileLink, filename)
+	}
+}
+
+// writer 当前的日志文件，每秒最多检查一次日期是否变化
+func (w *datedWriter) writer() *rotateWriter {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	if now.Unix() != w.lastCheck {
+		w.lastCheck = now.Unix()
+		if filename := expandFile(w.conf.File, w.service, now); filename != w.current.lum.Filename {
+			w.current.Close()
+			w.open(filename)
+		}
+	}
+	return w.current
+}
+
+func (w *datedWriter) Write(p []byte) (int, error) {
+	return w.writer().Write(p)
+}
+
+func (w *datedWriter) Sync() error {
+	return nil
+}
+
+// Rotate 切割当前的日志文件
+func (w *datedWriter) Rotate() error {
+	return w.writer().Rotate()
+}
+
+// Reopen 重新打开当前的日志文件
+func (w *datedWriter) Reopen() error {
+	return w.writer().Reopen()
+}
+
+// Close 关闭当前的日志文件
+func (w *datedWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.current.Close()
+}
+
+// updateFileLink 将link指向当前的日志文件，先创建临时链接再重命名，避免出现link不存在的时刻
+func updateFileLink(link, target string) {
+	if rel, err := filepath.Rel(filepath.Dir(link), target); err == nil {
+		target = rel
+	}
+	if err := os.MkdirAll(filepath.Dir(link), 0o755); err != nil {
+		return
+	}
+	tmp := link + ".tmp"
+	os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return
+	}
+	if err := os.Rename(tmp, link); err != nil {
+		os.Remove(tmp)
+	}
+}
@@ -0,0 +1,22 @@
+package logx
+
+import (
+	"sync/atomic"
+)
+
+// disabled 未配置任何输出及追踪时为true，日志及追踪的函数直接返回
+var disabled atomic.Bool
+
+// Disable 关闭日志及追踪，之后的Debug,Info,Warn,Error,Start等直接返回，不分配内存
+// 不修改otel全局的propagator，用于单元测试等不需要日志的场景，等同于Init(Config{Output: "none"}, "")
+func Disable() {
+	Init(Config{Output: "none"}, "")
+}
+
+// isDisabled 未配置日志输出、自定义zap core、追踪、透传的traceID格式及header、Loki,Sentry,审计,告警时，完全关闭日志及追踪
+// 此时Start不再记录上级的traceID，TraceID等返回空
+func isDisabled(conf Config, o initOptions) bool {
+	return (conf.Output == "" || conf.Output == "none") && len(o.zapCores) == 0 && !conf.EnableTrace &&
+		len(conf.TraceIDFormats) == 0 && len(conf.PropagationHeaders) == 0 &&
+		conf.LokiServer == "" && conf.SentryDSN == "" && conf.AuditFile == "" && conf.AlertWebhook == ""
+}
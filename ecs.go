@@ -0,0 +1,80 @@
+package logx
+
+import (
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// ecsVersion 遵循的Elastic Common Schema版本
+const ecsVersion = "8.11.0"
+
+// ecsKeys logx的字段对应的ECS字段
+// ECS没有错误链的字段，error_chain放在error对象中
+var ecsKeys = map[string]string{
+	"trace_id":    "trace.id",
+	"span_id":     "span.id",
+	"error":       "error.message",
+	"error_chain": "error.chain",
+	"error_stack": "error.stack_trace",
+}
+
+// ecsEncoderConfig Elastic Common Schema的字段，写入Elastic后无需ingest pipeline转换
+func ecsEncoderConfig(encoderConfig zapcore.EncoderConfig) zapcore.EncoderConfig {
+	encoderConfig.TimeKey = "@timestamp"
+	encoderConfig.LevelKey = "log.level"
+	encoderConfig.MessageKey = "message"
+	encoderConfig.NameKey = "log.logger"
+	encoderConfig.CallerKey = "log.origin"
+	encoderConfig.StacktraceKey = "error.stack_trace"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	encoderConfig.EncodeCaller = objectCallerEncoder("file.name", "file.line", "function")
+	return encoderConfig
+}
+
+// ecsCore 将trace_id,span_id,error等字段重命名为ECS的字段
+// namespace为AttributeNamespace，添加了该前缀的error等字段同样重命名
+// Namespace之后的字段嵌套在对象中，不是ECS的字段，保持不变
+type ecsCore struct {
+	zapcore.Core
+	namespace string
+	nested    bool
+}
+
+func (c ecsCore) With(fields []zapcore.Field) zapcore.Core {
+	renamed, nested := c.ecsFields(fields)
+	return ecsCore{Core: c.Core.With(renamed), namespace: c.namespace, nested: nested}
+}
+
+func (c ecsCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkInner(c.Core, ent, ce, func(checked *zapcore.CheckedEntry, fields []zapcore.Field) {
+		renamed, _ := c.ecsFields(fields)
+		checked.Write(renamed...)
+	})
+}
+
+func (c ecsCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	renamed, _ := c.ecsFields(fields)
+	return c.Core.Write(ent, renamed)
+}
+
+// ecsFields 返回重命名后的字段，及之后的字段是否嵌套在Namespace的对象中
+func (c ecsCore) ecsFields(fields []zapcore.Field) ([]zapcore.Field, bool) {
+	nested := c.nested
+	renamed := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		if f.Type == zapcore.NamespaceType {
+			nested = true
+		} else if !nested {
+			key := f.Key
+			if c.namespace != "" {
+				key = strings.TrimPrefix(key, c.namespace+".")
+			}
+			if ecsKey, ok := ecsKeys[key]; ok {
+				f.Key = ecsKey
+			}
+		}
+		renamed[i] = f
+	}
+	return renamed, nested
+}
@@ -0,0 +1,230 @@
+package logx
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"gopkg.in/yaml.v3"
+)
+
+// applyEnv 使用otel标准的环境变量补充未配置的项
+//
+// 优先级为：Init的参数及Config > 环境变量 > 默认值
+//
+//	OTEL_SERVICE_NAME serviceName为空时使用
+//	OTEL_EXPORTER_OTLP_TRACES_ENDPOINT,OTEL_EXPORTER_OTLP_ENDPOINT OTLPEndpoint为空时使用，如http://collector:4318
+//	OTEL_TRACES_SAMPLER,OTEL_TRACES_SAMPLER_ARG TraceSampleRatio为0且未配置Sampler,未使用WithSampler时使用
+//	OTEL_RESOURCE_ATTRIBUTES 追加到应用属性，已存在的key不会被覆盖
+func applyEnv(conf *Config, serviceName string, o *initOptions) string {
+	if serviceName == "" {
+		serviceName = os.Getenv("OTEL_SERVICE_NAME")
+	}
+	if conf.OTLPEndpoint == "" {
+		if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+			applyEnvEndpoint(conf, endpoint, "")
+		} else if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+			applyEnvEndpoint(conf, endpoint, "/v1/traces")
+		}
+	}
+	if conf.TraceSampleRatio == 0 && conf.Sampler == "" && o.sampler == nil {
+		applyEnvSampler(conf, o, os.Getenv("OTEL_TRACES_SAMPLER"), os.Getenv("OTEL_TRACES_SAMPLER_ARG"))
+	}
+	keys := map[string]bool{}
+	for _, f := range o.resource {
+		keys[f.Key] = true
+	}
+	for _, pair := range strings.Split(os.Getenv("OTEL_RESOURCE_ATTRIBUTES"), ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || keys[key] {
+			continue
+		}
+		if unescaped, err := url.PathUnescape(strings.TrimSpace(value)); err == nil {
+			o.resource = append(o.resource, String(key, unescaped))
+		}
+	}
+	return serviceName
+}
+
+// applyEnvEndpoint 解析endpoint，suffix为通用endpoint需要追加的路径
+func applyEnvEndpoint(conf *Config, endpoint, suffix string) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return
+	}
+	conf.OTLPEndpoint = u.Host
+	if conf.OTLPEndpointURLPath == "" {
+		conf.OTLPEndpointURLPath = strings.TrimSuffix(u.Path, "/") + suffix
+	}
+	if u.Scheme == "http" {
+		conf.OLTPInsecure = true
+	}
+}
+
+// applyEnvSampler 支持always_on,always_off,traceidratio及对应的parentbased_*
+func applyEnvSampler(conf *Config, o *initOptions, sampler, arg string) {
+	ratio := 1.0
+	if r, err := strconv.ParseFloat(arg, 64); err == nil && r >= 0 && r <= 1 {
+		ratio = r
+	}
+	parentBased := strings.HasPrefix(sampler, "parentbased_")
+	switch strings.TrimPrefix(sampler, "parentbased_") {
+	case "always_on":
+		conf.TraceSampleRatio = 1
+	case "always_off":
+		conf.TraceSampleRatio = 0
+	case "traceidratio":
+		conf.TraceSampleRatio = ratio
+	default:
+		return
+	}
+	// 仍使用traceSampler，以支持SetTraceSampleRatio
+	if parentBased {
+		o.sampler = sdktrace.ParentBased(traceSampler.set(conf.TraceSampleRatio))
+	}
+}
+
+// envPrefix ConfigFromEnv读取的环境变量前缀
+const envPrefix = "LOGX_"
+
+// ConfigFromEnv 从环境变量读取配置，用于容器部署时无需配置文件
+//
+// 环境变量名为LOGX_加上yaml tag的大写，如LOGX_OUTPUT,LOGX_LEVEL,LOGX_MAX_SIZE
+// 嵌套的配置以_连接，如LOGX_LOG_SAMPLING_INITIAL；数组以逗号分隔，如LOGX_KAFKA_BROKERS=a:9092,b:9092
+// 时长如LOGX_BATCH_TIMEOUT=5s；SampleRules等结构体数组使用json，如LOGX_SAMPLE_RULES=[{"route":"/healthz","ratio":0}]
+//
+// 另外支持：
+//
+//	LOGX_OTLP_ENDPOINT 同LOGX_OLTP_ENDPOINT，可以为http://collector:4318格式
+//	OTEL_EXPORTER_OTLP_TRACES_ENDPOINT,OTEL_EXPORTER_OTLP_ENDPOINT 未配置endpoint时使用
+//	OTEL_EXPORTER_OTLP_INSECURE 为true时使用http
+//	OTEL_TRACES_SAMPLER,OTEL_TRACES_SAMPLER_ARG 转换为Sampler及TraceSampleRatio
+//	OTEL_SDK_DISABLED 为true时关闭追踪
+//
+// 配置了endpoint且未设置LOGX_ENABLE_TRACE时开启追踪，返回所有无法解析的环境变量的错误
+func ConfigFromEnv() (Config, error) {
+	var conf Config
+	errs := envFields(reflect.ValueOf(&conf).Elem(), envPrefix)
+	if conf.OTLPEndpoint == "" {
+		if endpoint := os.Getenv("LOGX_OTLP_ENDPOINT"); strings.Contains(endpoint, "://") {
+			applyEnvEndpoint(&conf, endpoint, "")
+		} else if endpoint != "" {
+			conf.OTLPEndpoint = endpoint
+		} else if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+			applyEnvEndpoint(&conf, endpoint, "")
+		} else if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+			applyEnvEndpoint(&conf, endpoint, "/v1/traces")
+		}
+	}
+	if insecure, err := strconv.ParseBool(os.Getenv("OTEL_EXPORTER_OTLP_INSECURE")); err == nil && insecure {
+		conf.OLTPInsecure = true
+	}
+	if conf.Sampler == "" && conf.TraceSampleRatio == 0 {
+		sampler := os.Getenv("OTEL_TRACES_SAMPLER")
+		ratio, err := strconv.ParseFloat(os.Getenv("OTEL_TRACES_SAMPLER_ARG"), 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			ratio = 1
+		}
+		switch strings.TrimPrefix(sampler, "parentbased_") {
+		case "always_on":
+			conf.TraceSampleRatio = 1
+		case "always_off":
+			conf.TraceSampleRatio = 0
+		case "traceidratio":
+			conf.TraceSampleRatio = ratio
+		default:
+			sampler = ""
+		}
+		if strings.HasPrefix(sampler, "parentbased_") {
+			conf.Sampler = "parentbased_ratio"
+		} else if sampler != "" {
+			conf.Sampler = "ratio"
+		}
+	}
+	if _, ok := os.LookupEnv(envPrefix + "ENABLE_TRACE"); !ok && conf.OTLPEndpoint != "" {
+		conf.EnableTrace = true
+	}
+	if disabled, err := strconv.ParseBool(os.Getenv("OTEL_SDK_DISABLED")); err == nil && disabled {
+		conf.EnableTrace = false
+	}
+	return conf, errors.Join(errs...)
+}
+
+// envFields 按yaml tag读取结构体的各字段
+func envFields(v reflect.Value, prefix string) []error {
+	var errs []error
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name := prefix + strings.ToUpper(tag)
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct {
+			errs = append(errs, envFields(field, name+"_")...)
+			continue
+		}
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		if err := setEnvValue(field, value); err != nil {
+			errs = append(errs, fmt.Errorf("logx: invalid %s: %w", name, err))
+		}
+	}
+	return errs
+}
+
+// setEnvValue 按字段的类型解析环境变量的值
+func setEnvValue(field reflect.Value, value string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(value)
+		field.SetInt(int64(d))
+		return err
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.String {
+			var values []string
+			for _, s := range strings.Split(value, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					values = append(values, s)
+				}
+			}
+			field.Set(reflect.ValueOf(values))
+			return nil
+		}
+		return yaml.Unmarshal([]byte(value), field.Addr().Interface())
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
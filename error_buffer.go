@@ -0,0 +1,95 @@
+package logx
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"time"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// errorBuffers 各trace中未达到日志等级的最近ErrorBuffer条日志
+var errorBuffers = &traceBuffers{entries: map[string][]bufferedEntry{}}
+
+type bufferedEntry struct {
+	entry  zapcore.Entry
+	fields []zapcore.Field
+}
+
+type traceBuffers struct {
+	mu      sync.Mutex
+	entries map[string][]bufferedEntry
+	order   []string
+}
+
+// add 保存trace的日志，超过size条时丢弃最早的，trace数量超过maxBoostTraces时淘汰最早的trace
+func (b *traceBuffers) add(traceID string, size int, e bufferedEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entries, ok := b.entries[traceID]
+	if !ok {
+		if len(b.order) >= maxBoostTraces {
+			delete(b.entries, b.order[0])
+			b.order = b.order[1:]
+		}
+		b.order = append(b.order, traceID)
+	}
+	if len(entries) >= size {
+		entries = append(entries[:0], entries[len(entries)-size+1:]...)
+	}
+	b.entries[traceID] = append(entries, e)
+}
+
+// take 取出并清除trace保存的日志
+func (b *traceBuffers) take(traceID string) []bufferedEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entries := b.entries[traceID]
+	delete(b.entries, traceID)
+	return entries
+}
+
+// reset 清除保存的日志
+func (b *traceBuffers) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries = map[string][]bufferedEntry{}
+	b.order = nil
+}
+
+// bufferEntry 保存未达到日志等级的日志，由Debug,Info等直接调用
+func bufferEntry(ctx context.Context, level zapcore.Level, msg string, attributes []Field) {
+	s := loadState()
+	if s.boost == nil {
+		return
+	}
+	sc := oteltrace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	entry := zapcore.Entry{Level: level, Time: time.Now(), Message: msg}
+	if !s.config.DisableCaller {
+		// 0为bufferEntry，1为Debug等，2为调用方
+		if pc, file, line, ok := runtime.Caller(2 + s.config.CallerSkip); ok {
+			entry.Caller = zapcore.NewEntryCaller(pc, file, line, ok)
+			if fn := runtime.FuncForPC(pc); fn != nil {
+				entry.Caller.Function = fn.Name()
+			}
+		}
+	}
+	errorBuffers.add(sc.TraceID().String(), s.config.ErrorBuffer, bufferedEntry{entry: entry, fields: FieldsToZapFields(ctx, attributes...)})
+}
+
+// flushErrorBuffer trace中发生错误时，写入该trace保存的日志
+func flushErrorBuffer(boost *zap.Logger, traceID string) {
+	core := boost.Core()
+	for _, e := range errorBuffers.take(traceID) {
+		// 经Check写入，zap.Hooks包装的core直接Write不会输出
+		if ce := core.Check(e.entry, nil); ce != nil {
+			ce.Write(e.fields...)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package logx
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// esSink 通过bulk接口批量写入Elasticsearch/OpenSearch，按天创建索引
+type esSink struct {
+	mu       sync.Mutex
+	url      string
+	index    string
+	username string
+	password string
+	batch    int
+	retry    int
+	client   *http.Client
+	buf      bytes.Buffer
+	n        int
+	flush    chan struct{}
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// newESSink ESBatchSize默认500，ESFlushInterval默认1秒，ESRetry默认3次
+func newESSink(conf Config, service string) (*esSink, error) {
+	if conf.ESServer == "" {
+		return nil, errors.New("elasticsearch server is required")
+	}
+	s := &esSink{
+		url:      strings.TrimSuffix(conf.ESServer, "/") + "/_bulk",
+		index:    conf.ESIndex,
+		username: conf.ESUsername,
+		password: conf.ESPassword,
+		batch:    conf.ESBatchSize,
+		retry:    conf.ESRetry,
+		flush:    make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	if s.index == "" {
+		s.index = "logs-" + service
+	}
+	if s.batch <= 0 {
+		s.batch = 500
+	}
+	if s.retry <= 0 {
+		s.retry = 3
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if conf.ESInsecureSkipVerify {
+		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	}
+	s.client = &http.Client{Timeout: 10 * time.Second, Transport: transport}
+	interval := conf.ESFlushInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	s.wg.Add(1)
+	go s.run(interval)
+	return s, nil
+}
+
+func (s *esSink) run(interval time.Duration) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.flush:
+		case <-s.done:
+			return
+		}
+		s.Sync()
+	}
+}
+
+func (s *esSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 索引名称的日期，如logs-service-2024.05.01
+	fmt.Fprintf(&s.buf, `{"index":{"_index":%q}}`+"\n", s.index+"-"+time.Now().Format("2006.01.02"))
+	s.buf.Write(bytes.TrimSuffix(p, []byte("\n")))
+	s.buf.WriteByte('\n')
+	s.n++
+	if s.n >= s.batch {
+		select {
+		case s.flush <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Sync 立即发送缓存的日志
+func (s *esSink) Sync() error {
+	s.mu.Lock()
+	if s.n == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	body := bytes.Clone(s.buf.Bytes())
+	n := s.n
+	s.buf.Reset()
+	s.n = 0
+	s.mu.Unlock()
+	err := s.send(body)
+	if err != nil {
+		stats.writeErrors.Add(int64(n))
+	}
+	return err
+}
+
+// send 发送bulk请求，请求失败或返回429,5xx时按指数退避重试
+func (s *esSink) send(body []byte) error {
+	var err error
+	backoff := 100 * time.Millisecond
+	for i := 0; i < s.retry; i++ {
+		if i > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		var retry bool
+		if retry, err = s.bulk(body); !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// bulk 返回是否需要重试
+func (s *esSink) bulk(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return true, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if resp.StatusCode >= 300 {
+		return false, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	// 部分日志写入失败时不重试，计入WriteErrors
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Error json.RawMessage `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err == nil && result.Errors {
+		for _, item := range result.Items {
+			for _, action := range item {
+				if len(action.Error) > 0 {
+					stats.writeErrors.Add(1)
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// Close 发送剩余的日志并停止定时发送
+func (s *esSink) Close() error {
+	close(s.done)
+	s.wg.Wait()
+	return s.Sync()
+}
@@ -3,6 +3,10 @@ package logx
 import (
 	"errors"
 	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type FieldType int
@@ -22,6 +26,18 @@ const (
 	stringerType
 	anyType
 	errType
+	uintType
+	uint64Type
+	float32Type
+	durationType
+	timeType
+	byteStringType
+	binaryType
+	grpcStatusType
+	objectType
+	arrayType
+	namespaceType
+	secretType
 )
 
 type Field struct {
@@ -38,6 +54,14 @@ type Field struct {
 	Strings    []string
 	Float64s   []float64
 	Any        interface{}
+	Uinteger   uint
+	Uinteger64 uint64
+	Float32    float32
+	Duration   time.Duration
+	Time       time.Time
+	Bytes      []byte
+	Err        error
+	Stack      string
 }
 
 // Bool
@@ -70,6 +94,21 @@ func Int64Slice(key string, val []int64) Field {
 	return Field{Key: key, Type: int64SliceType, Integer64s: val}
 }
 
+// Uint
+func Uint(key string, val uint) Field {
+	return Field{Key: key, Type: uintType, Uinteger: val}
+}
+
+// Uint64
+func Uint64(key string, val uint64) Field {
+	return Field{Key: key, Type: uint64Type, Uinteger64: val}
+}
+
+// Float32
+func Float32(key string, val float32) Field {
+	return Field{Key: key, Type: float32Type, Float32: val}
+}
+
 // Float64
 func Float64(key string, val float64) Field {
 	return Field{Key: key, Type: float64Type, Float64: val}
@@ -90,6 +129,26 @@ func StringSlice(key string, val []string) Field {
 	return Field{Key: key, Type: stringSliceType, Strings: val}
 }
 
+// ByteString UTF-8编码的[]byte
+func ByteString(key string, val []byte) Field {
+	return Field{Key: key, Type: byteStringType, Bytes: val}
+}
+
+// Binary 非文本的二进制数据，日志中以base64编码输出
+func Binary(key string, val []byte) Field {
+	return Field{Key: key, Type: binaryType, Bytes: val}
+}
+
+// Duration
+func Duration(key string, val time.Duration) Field {
+	return Field{Key: key, Type: durationType, Duration: val}
+}
+
+// Time
+func Time(key string, val time.Time) Field {
+	return Field{Key: key, Type: timeType, Time: val}
+}
+
 // Stringer
 func Stringer(key string, val fmt.Stringer) Field {
 	return Field{Key: key, Type: stringerType, String: val.String()}
@@ -107,3 +166,63 @@ func Err(err error) Field {
 	}
 	return Field{Key: "error", Type: stringType, String: err.Error()}
 }
+
+// Namespace 之后的字段嵌套在key对象中，如Namespace("http"),String("method","GET")输出为{"http":{"method":"GET"}}
+// span中展开为http.method
+func Namespace(key string) Field {
+	return Field{Key: key, Type: namespaceType}
+}
+
+// ErrStack 记录错误及调用堆栈
+// 日志中输出error,error_chain(errors.Unwrap展开的错误链),error_stack
+// span中按照otel语义输出exception.type,exception.message,exception.stacktrace
+func ErrStack(err error) Field {
+	if err == nil {
+		err = errors.New("nil")
+	}
+	return Field{Key: "error", Type: errType, Err: err, Stack: stacktrace(2)}
+}
+
+// namespaceKey 为key添加命名空间前缀，已包含该前缀的key保持不变
+func namespaceKey(namespace, key string) string {
+	if namespace == "" || strings.HasPrefix(key, namespace+".") {
+		return key
+	}
+	return namespace + "." + key
+}
+
+// fieldsCap 转换后的字段数，err类型的字段额外占用errExtra个，用于预分配避免扩容
+func fieldsCap(fields []Field, errExtra int) int {
+	n := len(fields)
+	for i := range fields {
+		if fields[i].Type == errType {
+			n += errExtra
+		}
+	}
+	return n
+}
+
+// errorChain 展开错误链
+func errorChain(err error) []string {
+	var chain []string
+	for ; err != nil; err = errors.Unwrap(err) {
+		chain = append(chain, err.Error())
+	}
+	return chain
+}
+
+// stacktrace 获取调用堆栈
+func stacktrace(skip int) string {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var builder strings.Builder
+	for {
+		frame, more := frames.Next()
+		builder.WriteString(frame.Function + "\n\t" + frame.File + ":" + strconv.Itoa(frame.Line) + "\n")
+		if !more {
+			break
+		}
+	}
+	return builder.String()
+}
@@ -0,0 +1,154 @@
+package logx
+
+import (
+	"math"
+	"time"
+)
+
+// Fields 附带类型安全的取值方法的Field列表，用于Sampler等扩展
+// key重复时，以最后一个为准
+type Fields []Field
+
+// Value 返回Field的值，如string,int64,[]string等，errType返回error
+func (f Field) Value() interface{} {
+	switch f.Type {
+	case boolType:
+		return f.Bool
+	case boolSliceType:
+		return f.Bools
+	case intType, grpcStatusType:
+		return f.Integer
+	case intSliceType:
+		return f.Integers
+	case int64Type:
+		return f.Integer64
+	case int64SliceType:
+		return f.Integer64s
+	case float64Type:
+		return f.Float64
+	case float64SliceType:
+		return f.Float64s
+	case stringType, stringerType:
+		return f.String
+	case stringSliceType:
+		return f.Strings
+	case anyType, objectType, arrayType:
+		return f.Any
+	case errType:
+		return f.Err
+	case uintType:
+		return f.Uinteger
+	case uint64Type:
+		return f.Uinteger64
+	case float32Type:
+		return f.Float32
+	case durationType:
+		return f.Duration
+	case timeType:
+		return f.Time
+	case byteStringType, binaryType:
+		return f.Bytes
+	case secretType:
+		return redactedValue
+	}
+	return nil
+}
+
+// Get 获取key对应的Field
+func (fs Fields) Get(key string) (Field, bool) {
+	for i := len(fs) - 1; i >= 0; i-- {
+		if fs[i].Key == key {
+			return fs[i], true
+		}
+	}
+	return Field{}, false
+}
+
+// GetString 获取string类型的值，包括Stringer,ByteString
+func (fs Fields) GetString(key string) (string, bool) {
+	f, ok := fs.Get(key)
+	if !ok {
+		return "", false
+	}
+	switch f.Type {
+	case stringType, stringerType:
+		return f.String, true
+	case byteStringType:
+		return string(f.Bytes), true
+	}
+	return "", false
+}
+
+// GetInt 获取整数类型的值，包括Int,Int64,Uint,Uint64，超过int64范围的返回false
+func (fs Fields) GetInt(key string) (int64, bool) {
+	f, ok := fs.Get(key)
+	if !ok {
+		return 0, false
+	}
+	switch f.Type {
+	case intType, grpcStatusType:
+		return int64(f.Integer), true
+	case int64Type:
+		return f.Integer64, true
+	case uintType:
+		if uint64(f.Uinteger) <= math.MaxInt64 {
+			return int64(f.Uinteger), true
+		}
+	case uint64Type:
+		if f.Uinteger64 <= math.MaxInt64 {
+			return int64(f.Uinteger64), true
+		}
+	}
+	return 0, false
+}
+
+// GetFloat 获取浮点类型的值，包括Float32,Float64
+func (fs Fields) GetFloat(key string) (float64, bool) {
+	f, ok := fs.Get(key)
+	if !ok {
+		return 0, false
+	}
+	switch f.Type {
+	case float64Type:
+		return f.Float64, true
+	case float32Type:
+		return float64(f.Float32), true
+	}
+	return 0, false
+}
+
+// GetBool 获取bool类型的值
+func (fs Fields) GetBool(key string) (bool, bool) {
+	f, ok := fs.Get(key)
+	if !ok || f.Type != boolType {
+		return false, false
+	}
+	return f.Bool, true
+}
+
+// GetDuration 获取Duration类型的值
+func (fs Fields) GetDuration(key string) (time.Duration, bool) {
+	f, ok := fs.Get(key)
+	if !ok || f.Type != durationType {
+		return 0, false
+	}
+	return f.Duration, true
+}
+
+// GetTime 获取Time类型的值
+func (fs Fields) GetTime(key string) (time.Time, bool) {
+	f, ok := fs.Get(key)
+	if !ok || f.Type != timeType {
+		return time.Time{}, false
+	}
+	return f.Time, true
+}
+
+// Range 依次遍历Field，fn返回false时停止
+func (fs Fields) Range(fn func(key string, value interface{}) bool) {
+	for _, f := range fs {
+		if !fn(f.Key, f.Value()) {
+			return
+		}
+	}
+}
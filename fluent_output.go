@@ -0,0 +1,117 @@
+package logx
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ugorji/go/codec"
+)
+
+// fluentSink 通过Fluent forward协议（msgpack over TCP）发送日志到Fluentd/Fluent Bit
+type fluentSink struct {
+	mu      sync.Mutex
+	address string
+	tag     *strings.Replacer
+	pattern string
+	conn    net.Conn
+	json    *codec.JsonHandle
+	msgpack *codec.MsgpackHandle
+}
+
+// fluentEventTime forward协议的EventTime，纳秒精度的时间
+type fluentEventTime time.Time
+
+type fluentEventTimeExt struct{}
+
+func (fluentEventTimeExt) WriteExt(v interface{}) []byte {
+	t := time.Time(*v.(*fluentEventTime))
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, uint32(t.Unix()))
+	binary.BigEndian.PutUint32(b[4:], uint32(t.Nanosecond()))
+	return b
+}
+
+func (fluentEventTimeExt) ReadExt(dst interface{}, src []byte) {
+	sec := binary.BigEndian.Uint32(src)
+	nsec := binary.BigEndian.Uint32(src[4:])
+	*dst.(*fluentEventTime) = fluentEventTime(time.Unix(int64(sec), int64(nsec)))
+}
+
+// newFluentSink FluentAddress默认127.0.0.1:24224，FluentTag默认{service}.{level}
+func newFluentSink(conf Config, service string) (*fluentSink, error) {
+	s := &fluentSink{
+		address: conf.FluentAddress,
+		pattern: conf.FluentTag,
+		json:    &codec.JsonHandle{},
+		msgpack: &codec.MsgpackHandle{WriteExt: true},
+	}
+	if s.address == "" {
+		s.address = "127.0.0.1:24224"
+	}
+	if s.pattern == "" {
+		s.pattern = "{service}.{level}"
+	}
+	host, _ := os.Hostname()
+	s.tag = strings.NewReplacer("{service}", service, "{host}", host)
+	s.json.MapType = reflect.TypeOf(map[string]interface{}(nil))
+	if err := s.msgpack.SetBytesExt(reflect.TypeOf(fluentEventTime{}), 0, fluentEventTimeExt{}); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Write 以Message模式[tag, time, record]发送，{level}替换为日志等级
+func (s *fluentSink) Write(p []byte) (int, error) {
+	var record map[string]interface{}
+	if err := codec.NewDecoderBytes(p, s.json).Decode(&record); err != nil {
+		return 0, err
+	}
+	level, _ := record["level"].(string)
+	tag := strings.ReplaceAll(s.tag.Replace(s.pattern), "{level}", level)
+	var msg []byte
+	now := fluentEventTime(time.Now())
+	if err := codec.NewEncoderBytes(&msg, s.msgpack).Encode([]interface{}{tag, &now, record}); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 连接断开时重新连接一次
+	for i := 0; i < 2; i++ {
+		if s.conn == nil {
+			conn, err := net.DialTimeout("tcp", s.address, 3*time.Second)
+			if err != nil {
+				return 0, err
+			}
+			s.conn = conn
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(3 * time.Second))
+		if _, err := s.conn.Write(msg); err == nil {
+			return len(p), nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	return 0, errors.New("send to fluent failed")
+}
+
+func (s *fluentSink) Sync() error {
+	return nil
+}
+
+// Close 关闭连接
+func (s *fluentSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
@@ -0,0 +1,80 @@
+package logx
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// flushTraces 立即导出已结束的span，不等待批量导出的间隔
+func flushTraces() {
+	provider := loadState().provider
+	if provider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	_ = provider.ForceFlush(ctx)
+}
+
+// flushOnFatal 配置FlushOnFatal时，结束ctx的span并立即导出，避免进程退出后丢失
+func flushOnFatal(ctx context.Context) {
+	if conf := loadState().config; !conf.FlushOnFatal || !conf.EnableTrace {
+		return
+	}
+	if loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext); ok {
+		loggerSpanContext.span.SetStatus(codes.Error, "fatal")
+		loggerSpanContext.span.End()
+	}
+	flushTraces()
+}
+
+// errorFlushProcessor 窗口内错误状态的span达到阈值时，立即导出
+type errorFlushProcessor struct {
+	mu        sync.Mutex
+	threshold int
+	window    time.Duration
+	ends      []time.Time
+}
+
+// newErrorFlushProcessor window默认为1分钟
+func newErrorFlushProcessor(threshold int, window time.Duration) *errorFlushProcessor {
+	if window <= 0 {
+		window = time.Minute
+	}
+	return &errorFlushProcessor{threshold: threshold, window: window}
+}
+
+func (p *errorFlushProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {}
+
+func (p *errorFlushProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.Status().Code != codes.Error {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	ends := p.ends[:0]
+	for _, t := range p.ends {
+		if now.Sub(t) < p.window {
+			ends = append(ends, t)
+		}
+	}
+	p.ends = append(ends, now)
+	if len(p.ends) >= p.threshold {
+		p.ends = p.ends[:0]
+		// OnEnd在span.End中同步调用，异步导出
+		go flushTraces()
+	}
+}
+
+func (p *errorFlushProcessor) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func (p *errorFlushProcessor) ForceFlush(ctx context.Context) error {
+	return nil
+}
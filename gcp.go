@@ -0,0 +1,55 @@
+package logx
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// gcpSeverities 日志等级对应的Cloud Logging severity
+var gcpSeverities = map[zapcore.Level]string{
+	zapcore.DebugLevel:  "DEBUG",
+	zapcore.InfoLevel:   "INFO",
+	zapcore.WarnLevel:   "WARNING",
+	zapcore.ErrorLevel:  "ERROR",
+	zapcore.DPanicLevel: "CRITICAL",
+	zapcore.PanicLevel:  "ALERT",
+	zapcore.FatalLevel:  "EMERGENCY",
+}
+
+// gcpEncoderConfig Cloud Logging结构化日志的字段，GKE,Cloud Run等采集标准输出时自动识别
+func gcpEncoderConfig(encoderConfig zapcore.EncoderConfig) zapcore.EncoderConfig {
+	encoderConfig.LevelKey = "severity"
+	encoderConfig.MessageKey = "message"
+	encoderConfig.TimeKey = "time"
+	encoderConfig.CallerKey = "logging.googleapis.com/sourceLocation"
+	encoderConfig.EncodeLevel = func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(gcpSeverities[l])
+	}
+	encoderConfig.EncodeTime = func(t time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		enc.AppendString(t.Format(time.RFC3339Nano))
+	}
+	encoderConfig.EncodeCaller = objectCallerEncoder("file", "line", "function")
+	return encoderConfig
+}
+
+// gcpTraceFields 关联Cloud Trace的字段，project为GCPProject,默认为环境变量GOOGLE_CLOUD_PROJECT
+func gcpTraceFields(ctx context.Context, project string) []Field {
+	sc := spanContext(ctx)
+	if !sc.IsValid() {
+		return nil
+	}
+	if project == "" {
+		project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	trace := sc.TraceID().String()
+	if project != "" {
+		trace = "projects/" + project + "/traces/" + trace
+	}
+	return []Field{
+		String("logging.googleapis.com/trace", trace),
+		String("logging.googleapis.com/spanId", sc.SpanID().String()),
+	}
+}
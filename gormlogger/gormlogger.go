@@ -0,0 +1,126 @@
+// Package gormlogger 实现gorm的logger.Interface
+//
+// 通过logx记录sql，影响行数及耗时，并为每次查询创建子span
+//
+// example:
+// db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: gormlogger.New(gormlogger.Config{})})
+package gormlogger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/itmisx/logx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const tracerName = "github.com/itmisx/logx/gormlogger"
+
+// Config 配置项
+type Config struct {
+	// 慢查询的阈值，超过的查询记录为warn日志，默认200ms
+	SlowThreshold time.Duration
+	// 日志等级，默认为logger.Warn，即仅记录错误及慢查询
+	LogLevel logger.LogLevel
+	// 是否忽略gorm.ErrRecordNotFound错误
+	IgnoreRecordNotFoundError bool
+}
+
+type gormLogger struct {
+	conf Config
+}
+
+// New 创建gorm的logger
+func New(conf Config) logger.Interface {
+	if conf.SlowThreshold == 0 {
+		conf.SlowThreshold = 200 * time.Millisecond
+	}
+	if conf.LogLevel == 0 {
+		conf.LogLevel = logger.Warn
+	}
+	return gormLogger{conf: conf}
+}
+
+// LogMode 设置日志等级
+func (l gormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	l.conf.LogLevel = level
+	return l
+}
+
+// Info record info
+func (l gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.conf.LogLevel >= logger.Info {
+		logx.Info(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Warn record warn
+func (l gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.conf.LogLevel >= logger.Warn {
+		logx.Warn(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Error record error
+func (l gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.conf.LogLevel >= logger.Error {
+		logx.Error(ctx, fmt.Sprintf(msg, data...))
+	}
+}
+
+// Trace 记录一次查询，并创建对应的子span
+func (l gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	elapsed := time.Since(begin)
+	sql, rows := fc()
+	ignored := l.conf.IgnoreRecordNotFoundError && errors.Is(err, gorm.ErrRecordNotFound)
+
+	// 查询已结束，使用begin作为span的开始时间
+	_, span := otel.Tracer(tracerName).Start(ctx, spanName(sql),
+		oteltrace.WithTimestamp(begin),
+		oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(
+			semconv.DBStatementKey.String(sql),
+			attribute.Int64("db.rows_affected", rows),
+		),
+	)
+	if err != nil && !ignored {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+
+	if l.conf.LogLevel <= logger.Silent {
+		return
+	}
+	fields := []logx.Field{
+		logx.String("db.statement", sql),
+		logx.Int64("db.rows_affected", rows),
+		logx.Duration("latency", elapsed),
+	}
+	switch {
+	case err != nil && !ignored && l.conf.LogLevel >= logger.Error:
+		logx.Error(ctx, "gorm query error", append(fields, logx.Err(err))...)
+	case elapsed > l.conf.SlowThreshold && l.conf.LogLevel >= logger.Warn:
+		logx.Warn(ctx, "gorm slow query", append(fields, logx.Duration("slow_threshold", l.conf.SlowThreshold))...)
+	case l.conf.LogLevel >= logger.Info:
+		logx.Info(ctx, "gorm query", fields...)
+	}
+}
+
+// spanName 使用sql的操作作为span名字，如gorm.SELECT
+func spanName(sql string) string {
+	op, _, _ := strings.Cut(strings.TrimSpace(sql), " ")
+	if op == "" {
+		return "gorm.query"
+	}
+	return "gorm." + strings.ToUpper(op)
+}
@@ -0,0 +1,40 @@
+package gormlogger
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestTrace(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logx.InitWithOptions(logx.Config{}, "local-test", logx.WithZapCore(core))
+
+	l := New(Config{SlowThreshold: time.Second, IgnoreRecordNotFoundError: true})
+	sql := func() (string, int64) { return "SELECT * FROM users", 1 }
+	ctx := context.Background()
+
+	l.Trace(ctx, time.Now(), sql, nil)
+	l.Trace(ctx, time.Now(), sql, gorm.ErrRecordNotFound)
+	assert.Len(t, logs.TakeAll(), 0)
+
+	l.Trace(ctx, time.Now().Add(-2*time.Second), sql, nil)
+	l.Trace(ctx, time.Now(), sql, errors.New("foo"))
+	entries := logs.TakeAll()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, zapcore.WarnLevel, entries[0].Level)
+	assert.Equal(t, zapcore.ErrorLevel, entries[1].Level)
+	assert.Equal(t, "SELECT * FROM users", entries[1].ContextMap()["db.statement"])
+
+	l.LogMode(logger.Info).Trace(ctx, time.Now(), sql, nil)
+	assert.Len(t, logs.TakeAll(), 1)
+}
@@ -0,0 +1,211 @@
+package logx
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcStatusKey otel语义约定的grpc状态码属性
+const grpcStatusKey = "rpc.grpc.status_code"
+
+// GRPCStatus 记录grpc状态码，key为rpc.grpc.status_code，不添加命名空间前缀
+// 用于Start,SetSpanAttr时，非OK的状态码会将span状态设置为错误
+func GRPCStatus(code grpccodes.Code) Field {
+	return Field{Key: grpcStatusKey, Type: grpcStatusType, Integer: int(code)}
+}
+
+// setGRPCSpanStatus 根据fields中的grpc状态码设置span状态
+func setGRPCSpanStatus(span oteltrace.Span, fields []Field) {
+	for _, f := range fields {
+		if f.Type != grpcStatusType {
+			continue
+		}
+		if code := grpccodes.Code(f.Integer); code != grpccodes.OK {
+			span.SetStatus(codes.Error, code.String())
+		}
+	}
+}
+
+// UnaryServerInterceptor grpc服务端的拦截器，从metadata中解析追踪信息并为每个请求启动server类型的span
+// 请求结束时记录grpc.code及rpc.grpc.status_code，非OK时将span状态设置为错误
+//
+// example:
+//
+//	server := grpc.NewServer(
+//		grpc.UnaryInterceptor(logx.UnaryServerInterceptor()),
+//		grpc.StreamInterceptor(logx.StreamServerInterceptor()),
+//	)
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx = StartServer(extractGRPC(ctx), grpcSpanName(info.FullMethod))
+		setGRPCMethod(ctx, info.FullMethod)
+		resp, err := handler(ctx, req)
+		endGRPC(ctx, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor grpc服务端流式调用的拦截器，同UnaryServerInterceptor
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := StartServer(extractGRPC(ss.Context()), grpcSpanName(info.FullMethod))
+		setGRPCMethod(ctx, info.FullMethod)
+		err := handler(srv, &grpcServerStream{ServerStream: ss, ctx: ctx})
+		endGRPC(ctx, err)
+		return err
+	}
+}
+
+// UnaryClientInterceptor grpc客户端的拦截器，为每个请求启动client类型的span，并将追踪信息写入metadata
+// 请求结束时记录grpc.code及rpc.grpc.status_code，非OK时将span状态设置为错误
+//
+// example:
+//
+//	conn, err := grpc.NewClient(target,
+//		grpc.WithUnaryInterceptor(logx.UnaryClientInterceptor()),
+//		grpc.WithStreamInterceptor(logx.StreamClientInterceptor()),
+//	)
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = StartClient(ctx, grpcSpanName(method))
+		setGRPCMethod(ctx, method)
+		err := invoker(injectGRPC(ctx), method, req, reply, cc, opts...)
+		endGRPC(ctx, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor grpc客户端流式调用的拦截器，同UnaryClientInterceptor
+// 接收到服务端的结束(io.EOF)或错误时结束span
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		ctx = StartClient(ctx, grpcSpanName(method))
+		setGRPCMethod(ctx, method)
+		cs, err := streamer(injectGRPC(ctx), desc, cc, method, opts...)
+		if err != nil {
+			endGRPC(ctx, err)
+			return nil, err
+		}
+		return &grpcClientStream{ClientStream: cs, ctx: ctx, serverStreams: desc.ServerStreams}, nil
+	}
+}
+
+// grpcServerStream 使用附带span的context
+type grpcServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *grpcServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcClientStream 流结束时结束span
+type grpcClientStream struct {
+	grpc.ClientStream
+	ctx           context.Context
+	serverStreams bool
+	once          sync.Once
+}
+
+func (s *grpcClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	// 服务端非流式时只接收一次响应
+	if err != nil || !s.serverStreams {
+		if err == io.EOF {
+			s.end(nil)
+		} else {
+			s.end(err)
+		}
+	}
+	return err
+}
+
+func (s *grpcClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	// io.EOF时错误由RecvMsg返回
+	if err != nil && err != io.EOF {
+		s.end(err)
+	}
+	return err
+}
+
+func (s *grpcClientStream) end(err error) {
+	s.once.Do(func() {
+		endGRPC(s.ctx, err)
+	})
+}
+
+// extractGRPC 从incoming metadata中解析追踪信息，使用与Inject相同的propagator
+func extractGRPC(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = propagatorOf().Extract(ctx, grpcMetadataCarrier(md))
+	return withRemoteSpanContext(ctx, oteltrace.SpanContextFromContext(ctx))
+}
+
+// injectGRPC 将追踪信息写入outgoing metadata，保留已有的metadata
+func injectGRPC(ctx context.Context) context.Context {
+	md, ok := metadata.FromOutgoingContext(ctx)
+	if ok {
+		md = md.Copy()
+	} else {
+		md = metadata.MD{}
+	}
+	propagatorOf().Inject(ctx, grpcMetadataCarrier(md))
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+// endGRPC 记录状态码并结束span
+func endGRPC(ctx context.Context, err error) {
+	code := status.Code(err)
+	oteltrace.SpanFromContext(ctx).SetAttributes(attribute.String("grpc.code", code.String()))
+	SetSpanAttr(ctx, GRPCStatus(code))
+	End(ctx)
+}
+
+// grpcSpanName 去掉FullMethod开头的/，如/pkg.Service/Method为pkg.Service/Method
+func grpcSpanName(fullMethod string) string {
+	return strings.TrimPrefix(fullMethod, "/")
+}
+
+// setGRPCMethod 记录otel语义约定的rpc.system,rpc.service,rpc.method，不添加命名空间前缀
+func setGRPCMethod(ctx context.Context, fullMethod string) {
+	service, method, _ := strings.Cut(grpcSpanName(fullMethod), "/")
+	oteltrace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.service", service),
+		attribute.String("rpc.method", method),
+	)
+}
+
+// grpcMetadataCarrier 将metadata作为propagator的carrier
+type grpcMetadataCarrier metadata.MD
+
+func (c grpcMetadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c grpcMetadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c grpcMetadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
@@ -0,0 +1,80 @@
+package logx
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Entry 传递给Hook的日志条目或span属性
+type Entry struct {
+	Context context.Context
+	// 日志等级，debug/info/warn/error/dpanic/panic/fatal，span的属性时为空
+	Level string
+	// 日志内容，span的属性时为span或事件的名称
+	Message string
+	// 日志的字段或span的属性，包括WithFields保存的字段
+	Fields Fields
+	// 为true时不记录该日志或属性，panic,fatal等级的日志不能丢弃
+	Drop bool
+}
+
+// Hook 在写入日志、推送Loki、Sentry及记录到span之前调用，可以修改Message,Fields或丢弃
+type Hook func(entry *Entry)
+
+var (
+	hooksMu sync.Mutex
+	hooks   atomic.Pointer[[]Hook]
+)
+
+// RegisterHook 注册Hook，按注册的顺序调用，用于统一删除内部字段、重命名key等
+//
+// example:
+//
+//	logx.RegisterHook(func(e *logx.Entry) {
+//		for i, f := range e.Fields {
+//			if f.Key == "uid" {
+//				e.Fields[i].Key = "user_id"
+//			}
+//		}
+//	})
+func RegisterHook(hook Hook) {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	var registered []Hook
+	if p := hooks.Load(); p != nil {
+		registered = *p
+	}
+	registered = append(registered[:len(registered):len(registered)], hook)
+	hooks.Store(&registered)
+}
+
+// ResetHooks 清除注册的Hook
+func ResetHooks() {
+	hooks.Store(nil)
+}
+
+// hookEntry 依次调用Hook，返回修改后的msg及fields，丢弃时ok为false
+func hookEntry(ctx context.Context, level, msg string, fields []Field) (string, []Field, bool) {
+	p := hooks.Load()
+	if p == nil {
+		return msg, fields, true
+	}
+	entry := &Entry{Context: ctx, Level: level, Message: msg, Fields: append(Fields{}, fields...)}
+	for _, hook := range *p {
+		hook(entry)
+	}
+	if entry.Drop && level != "panic" && level != "fatal" {
+		return msg, nil, false
+	}
+	return entry.Message, entry.Fields, true
+}
+
+// hookSpanFields 对span的属性调用Hook，丢弃时返回nil
+func hookSpanFields(ctx context.Context, name string, fields []Field) []Field {
+	_, fields, ok := hookEntry(ctx, "", name, fields)
+	if !ok {
+		return nil
+	}
+	return fields
+}
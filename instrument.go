@@ -0,0 +1,65 @@
+package logx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itmisx/logx/propagation/extract"
+	"google.golang.org/grpc"
+)
+
+// Instrument 一次性为服务安装追踪及日志的中间件，需在Init之后调用
+//
+// 支持的类型：
+//
+//	*gin.Engine 安装GinMiddleware，InstrumentRecovery为true时同时安装GinRecovery
+//	*http.Server Handler使用extract.HTTPMiddleware包装，ErrorLog记录为error日志
+//
+// # InstrumentStdLog为true时，同时将标准库log重定向为info日志
+//
+// *grpc.Server,*sql.DB创建后无法再添加拦截器或替换driver，传入时返回错误：
+// grpc在grpc.NewServer时设置UnaryServerInterceptor,StreamServerInterceptor，database/sql使用sqltrace.Open打开
+//
+// example:
+// err := logx.Instrument("order", engine, server)
+func Instrument(service string, targets ...interface{}) error {
+	conf := loadState().config
+	for _, target := range targets {
+		switch t := target.(type) {
+		case *gin.Engine:
+			t.Use(GinMiddleware(service))
+			if conf.InstrumentRecovery {
+				t.Use(GinRecovery())
+			}
+		case *http.Server:
+			handler := t.Handler
+			if handler == nil {
+				handler = http.DefaultServeMux
+			}
+			t.Handler = extract.HTTPMiddleware(service)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r2 := r.WithContext(ContextWithHeaders(withOTelSpan(r.Context()), r.Header))
+				handler.ServeHTTP(w, r2)
+				// ServeMux匹配的路由用于span的名称
+				r.Pattern = r2.Pattern
+			}))
+			if t.ErrorLog == nil {
+				t.ErrorLog = log.New(Writer(context.Background(), "error"), "", 0)
+			}
+		case *grpc.Server:
+			return errors.New("logx: instrument *grpc.Server is not supported, create it with grpc.UnaryInterceptor(logx.UnaryServerInterceptor()) and grpc.StreamInterceptor(logx.StreamServerInterceptor())")
+		case *sql.DB:
+			return errors.New("logx: instrument *sql.DB is not supported, open it with sqltrace.Open")
+		default:
+			return fmt.Errorf("logx: unsupported instrument target %T", target)
+		}
+	}
+	if conf.InstrumentStdLog {
+		RedirectStdLog()
+	}
+	return nil
+}
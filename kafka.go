@@ -0,0 +1,19 @@
+package logx
+
+import (
+	"context"
+)
+
+// KafkaInject 将追踪信息注入到消息的headers
+// 转换为sarama或kafka-go的header可以参考saramatrace,kafkagotrace
+func KafkaInject(ctx context.Context, headers map[string]string) {
+	for key, value := range Inject(ctx) {
+		headers[key] = value
+	}
+}
+
+// KafkaExtract 从消息的headers中解析追踪信息
+// 返回的context记录的日志附带生产方的traceID，Start启动的span为生产方的子span
+func KafkaExtract(ctx context.Context, headers map[string]string) context.Context {
+	return Extract(ctx, headers)
+}
@@ -0,0 +1,138 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaWriter 发送消息到kafka，*kafka.Writer实现了该接口
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// kafkaSink 将json格式的日志发送到kafka的topic，满KafkaBatchSize条或每KafkaBatchTimeout批量发送
+type kafkaSink struct {
+	mu     sync.Mutex
+	writer KafkaWriter
+	batch  int
+	msgs   []kafka.Message
+	flush  chan struct{}
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// newKafkaSink 使用KafkaBrokers,KafkaTopic等配置创建kafka输出，writer为WithKafkaWriter指定的writer
+func newKafkaSink(conf Config, writer KafkaWriter) (*kafkaSink, error) {
+	s := &kafkaSink{
+		writer: writer,
+		batch:  conf.KafkaBatchSize,
+		flush:  make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+	if s.batch <= 0 {
+		s.batch = 100
+	}
+	if s.writer == nil {
+		writer, err := newKafkaWriter(conf, s.batch)
+		if err != nil {
+			return nil, err
+		}
+		s.writer = writer
+	}
+	interval := conf.KafkaBatchTimeout
+	if interval <= 0 {
+		interval = time.Second
+	}
+	s.wg.Add(1)
+	go s.run(interval)
+	return s, nil
+}
+
+func newKafkaWriter(conf Config, batch int) (*kafka.Writer, error) {
+	if len(conf.KafkaBrokers) == 0 || conf.KafkaTopic == "" {
+		return nil, errors.New("kafka brokers and topic are required")
+	}
+	writer := &kafka.Writer{
+		Addr:      kafka.TCP(conf.KafkaBrokers...),
+		Topic:     conf.KafkaTopic,
+		Balancer:  &kafka.LeastBytes{},
+		BatchSize: batch,
+		// 已由kafkaSink攒批，writer收到后立即发送
+		BatchTimeout: time.Millisecond,
+	}
+	switch conf.KafkaCompression {
+	case "", "none":
+	case "gzip":
+		writer.Compression = kafka.Gzip
+	case "snappy":
+		writer.Compression = kafka.Snappy
+	case "lz4":
+		writer.Compression = kafka.Lz4
+	case "zstd":
+		writer.Compression = kafka.Zstd
+	default:
+		return nil, errors.New("unsupported kafka compression: " + conf.KafkaCompression)
+	}
+	return writer, nil
+}
+
+func (s *kafkaSink) run(interval time.Duration) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-s.flush:
+		case <-s.done:
+			return
+		}
+		s.Sync()
+	}
+}
+
+func (s *kafkaSink) Write(p []byte) (int, error) {
+	// zap会复用p，需复制后异步发送
+	value := bytes.Clone(bytes.TrimSuffix(p, []byte("\n")))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.msgs = append(s.msgs, kafka.Message{Value: value})
+	if len(s.msgs) >= s.batch {
+		select {
+		case s.flush <- struct{}{}:
+		default:
+		}
+	}
+	return len(p), nil
+}
+
+// Sync 立即发送缓存的日志，每次最多KafkaBatchSize条
+func (s *kafkaSink) Sync() error {
+	s.mu.Lock()
+	msgs := s.msgs
+	s.msgs = nil
+	s.mu.Unlock()
+	var errs []error
+	for len(msgs) > 0 {
+		n := min(len(msgs), s.batch)
+		if err := s.writer.WriteMessages(context.Background(), msgs[:n]...); err != nil {
+			stats.writeErrors.Add(int64(n))
+			errs = append(errs, err)
+		}
+		msgs = msgs[n:]
+	}
+	return errors.Join(errs...)
+}
+
+// Close 发送剩余的日志并关闭
+func (s *kafkaSink) Close() error {
+	close(s.done)
+	s.wg.Wait()
+	return errors.Join(s.Sync(), s.writer.Close())
+}
@@ -0,0 +1,47 @@
+// Package kafkagotrace 在kafka-go(github.com/segmentio/kafka-go)的消息中传递追踪信息
+//
+// example:
+// kafkagotrace.Inject(ctx, &msg)
+// writer.WriteMessages(ctx, msg)
+//
+// msg, err := reader.ReadMessage(ctx)
+// ctx = kafkagotrace.Extract(ctx, msg)
+// ctx = logx.Start(ctx, "consume")
+// defer logx.End(ctx)
+package kafkagotrace
+
+import (
+	"context"
+
+	"github.com/itmisx/logx"
+	"github.com/segmentio/kafka-go"
+)
+
+// Inject 将追踪信息注入到消息的headers
+func Inject(ctx context.Context, msg *kafka.Message) {
+	headers := map[string]string{}
+	logx.KafkaInject(ctx, headers)
+	for key, value := range headers {
+		msg.Headers = setHeader(msg.Headers, key, value)
+	}
+}
+
+// Extract 从消息的headers中解析追踪信息
+func Extract(ctx context.Context, msg kafka.Message) context.Context {
+	headers := map[string]string{}
+	for _, header := range msg.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	return logx.KafkaExtract(ctx, headers)
+}
+
+// setHeader 设置header，已存在的会被覆盖
+func setHeader(headers []kafka.Header, key, value string) []kafka.Header {
+	for i := range headers {
+		if headers[i].Key == key {
+			headers[i].Value = []byte(value)
+			return headers
+		}
+	}
+	return append(headers, kafka.Header{Key: key, Value: []byte(value)})
+}
@@ -0,0 +1,108 @@
+package logx
+
+import (
+	"encoding/json"
+	"unicode/utf8"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// limitFields 按MaxFields及MaxFieldLength限制字段的数量及长度
+// 发生截断时在最前面添加truncated=true
+func (s *logState) limitFields(fields []Field) []Field {
+	maxLength, maxFields := s.config.MaxFieldLength, s.config.MaxFields
+	if maxLength <= 0 && maxFields <= 0 {
+		return fields
+	}
+	truncated := false
+	if maxFields > 0 && len(fields) > maxFields {
+		fields = fields[:maxFields]
+		truncated = true
+	}
+	var limited []Field
+	if maxLength > 0 {
+		for i, f := range fields {
+			if short, ok := truncateField(f, maxLength); ok {
+				if limited == nil {
+					limited = append(make([]Field, 0, len(fields)), fields...)
+				}
+				limited[i] = short
+				truncated = true
+			}
+		}
+	}
+	if limited != nil {
+		fields = limited
+	}
+	if !truncated {
+		return fields
+	}
+	// 添加在最前面，避免嵌套到Namespace中
+	return append([]Field{Bool("truncated", true)}, fields...)
+}
+
+// truncateField 截断超过maxLength的值，Any,Object,Array按json的长度计算，截断后为字符串
+func truncateField(f Field, maxLength int) (Field, bool) {
+	switch f.Type {
+	case stringType, stringerType:
+		if len(f.String) > maxLength {
+			f.String = truncateString(f.String, maxLength)
+			return f, true
+		}
+	case byteStringType, binaryType:
+		if len(f.Bytes) > maxLength {
+			f.Bytes = f.Bytes[:maxLength]
+			return f, true
+		}
+	case stringSliceType:
+		var strs []string
+		for i, s := range f.Strings {
+			if len(s) > maxLength {
+				if strs == nil {
+					strs = append([]string{}, f.Strings...)
+				}
+				strs[i] = truncateString(s, maxLength)
+			}
+		}
+		if strs != nil {
+			f.Strings = strs
+			return f, true
+		}
+	case anyType, objectType, arrayType:
+		value := f.Any
+		if f.Type != anyType {
+			value = marshalerValue(f)
+		}
+		str, err := json.Marshal(value)
+		if err == nil && len(str) > maxLength {
+			return String(f.Key, truncateString(string(str), maxLength)), true
+		}
+	}
+	return f, false
+}
+
+// truncateString 按utf8字符的边界截断为不超过maxLength字节，并添加...
+func truncateString(s string, maxLength int) string {
+	if len(s) <= maxLength {
+		return s
+	}
+	cut := maxLength
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
+
+// spanLimitsOf span属性数量及长度的限制，与MaxFields,MaxFieldLength一致，未配置时使用otel的默认值
+func spanLimitsOf(conf Config) sdktrace.TracerProviderOption {
+	limits := sdktrace.NewSpanLimits()
+	if conf.MaxFieldLength > 0 {
+		limits.AttributeValueLengthLimit = conf.MaxFieldLength
+	}
+	if conf.MaxFields > 0 {
+		limits.AttributeCountLimit = conf.MaxFields
+		limits.AttributePerEventCountLimit = conf.MaxFields
+		limits.AttributePerLinkCountLimit = conf.MaxFields
+	}
+	return sdktrace.WithRawSpanLimits(limits)
+}
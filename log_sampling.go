@@ -0,0 +1,75 @@
+package logx
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// LogSampling 日志采样，每个Window内相同等级及msg的日志，记录前Initial条，之后每Thereafter条记录一条
+// 避免循环中的错误日志占满磁盘或Loki
+type LogSampling struct {
+	// 每个窗口内全部记录的条数，为0时不采样
+	Initial int `yaml:"initial" mapstructure:"initial"`
+	// 超过Initial后每Thereafter条记录一条，为0时不再记录
+	Thereafter int `yaml:"thereafter" mapstructure:"thereafter"`
+	// 采样的窗口，默认1秒
+	Window time.Duration `yaml:"window" mapstructure:"window"`
+}
+
+// samplingCore 按LogSampling包装zap core，未配置时返回原core
+func samplingCore(sampling LogSampling, core zapcore.Core) zapcore.Core {
+	if sampling.Initial <= 0 {
+		return core
+	}
+	window := sampling.Window
+	if window <= 0 {
+		window = time.Second
+	}
+	return zapcore.NewSamplerWithOptions(core, window, sampling.Initial, sampling.Thereafter,
+		zapcore.SamplerHook(func(_ zapcore.Entry, dec zapcore.SamplingDecision) {
+			if dec&zapcore.LogDropped > 0 {
+				stats.entriesSampled.Add(1)
+			}
+		}))
+}
+
+// onceKeys Once,Every记录的key及上次返回true的时间
+var onceKeys sync.Map
+
+// Once key第一次调用时返回true，之后均返回false，用于只需记录一次的日志
+//
+// example:
+//
+//	if logx.Once("config.deprecated") {
+//		logx.Warn(ctx, "config xxx is deprecated")
+//	}
+func Once(key string) bool {
+	_, loaded := onceKeys.LoadOrStore("once:"+key, time.Now())
+	return !loaded
+}
+
+// Every 同一个key每interval最多返回一次true，用于按key限制日志的频率
+//
+// example:
+//
+//	if logx.Every("db.unavailable", time.Minute) {
+//		logx.Error(ctx, "db unavailable", logx.ErrStack(err))
+//	}
+func Every(key string, interval time.Duration) bool {
+	key = "every:" + key
+	now := time.Now()
+	for {
+		last, loaded := onceKeys.LoadOrStore(key, now)
+		if !loaded {
+			return true
+		}
+		if now.Sub(last.(time.Time)) < interval {
+			return false
+		}
+		if onceKeys.CompareAndSwap(key, last, now) {
+			return true
+		}
+	}
+}
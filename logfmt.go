@@ -0,0 +1,90 @@
+package logx
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var logfmtPool = buffer.NewPool()
+
+// logfmtEncoder 输出key=value格式的日志，如level=info msg="hello world" user_id=1
+// 由json编码器编码后转换，嵌套的对象及数组以json字符串输出
+type logfmtEncoder struct {
+	zapcore.Encoder
+}
+
+func newLogfmtEncoder(encoderConfig zapcore.EncoderConfig) zapcore.Encoder {
+	return logfmtEncoder{zapcore.NewJSONEncoder(encoderConfig)}
+}
+
+func (e logfmtEncoder) Clone() zapcore.Encoder {
+	return logfmtEncoder{e.Encoder.Clone()}
+}
+
+func (e logfmtEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf, err := e.Encoder.EncodeEntry(ent, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer buf.Free()
+	dec := json.NewDecoder(bytes.NewReader(buf.Bytes()))
+	dec.UseNumber()
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	out := logfmtPool.Get()
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			out.Free()
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			out.Free()
+			return nil, err
+		}
+		if out.Len() > 0 {
+			out.AppendByte(' ')
+		}
+		out.AppendString(logfmtKey(token.(string)))
+		out.AppendByte('=')
+		out.AppendString(logfmtValue(value))
+	}
+	out.AppendString(zapcore.DefaultLineEnding)
+	return out, nil
+}
+
+// logfmtKey key中不能包含空格、等号及引号
+func logfmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+}
+
+// logfmtValue 字符串包含空格、等号、引号等时加引号，对象及数组转为json字符串
+func logfmtValue(value json.RawMessage) string {
+	var s string
+	switch value[0] {
+	case '"':
+		if err := json.Unmarshal(value, &s); err != nil {
+			return string(value)
+		}
+	case '{', '[':
+		s = string(value)
+	default:
+		return string(value)
+	}
+	if s == "" || strings.ContainsAny(s, " =\"\\\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
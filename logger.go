@@ -6,7 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"math/rand"
+	"maps"
+	"os"
 	"regexp"
 	"runtime"
 	"strconv"
@@ -15,6 +16,9 @@ import (
 	"github.com/imroc/req/v3"
 	"go.opentelemetry.io/contrib/propagators/b3"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/trace"
 	oteltrace "go.opentelemetry.io/otel/trace"
 	"go.opentelemetry.io/otel/trace/noop"
@@ -26,21 +30,126 @@ import (
 type Config struct {
 	// 是否开启debug模式，未开启debug模式，仅记录错误
 	Debug bool `yaml:"debug" mapstructure:"debug"`
+	// 日志等级，debug/info/warn/error/dpanic/panic/fatal
+	// 配置后优先于Debug
+	Level string `yaml:"level" mapstructure:"level"`
 	// 日志输出的方式
 	// none为不输出日志，file 为文件方式输出，console为控制台。默认为none
+	// none且未开启追踪、Loki等时完全关闭，参考Disable
+	// kafka 将json格式的日志发送到KafkaTopic
+	// elasticsearch 通过bulk接口批量写入ESServer，兼容OpenSearch
+	// fluent 通过Fluent forward协议发送到FluentAddress，如Fluent Bit
+	// cloudwatch 批量写入AWS CloudWatch Logs的CloudWatchGroup
 	Output string `yaml:"output" mapstructure:"output"`
+	// 控制台输出的编码方式，json/console，默认json
+	// console为便于阅读的文本格式，适用于本地开发。文件输出始终使用json
+	// gcp为Cloud Logging的结构化格式（severity,message,logging.googleapis.com/trace等），适用于所有输出
+	// ecs为Elastic Common Schema格式（@timestamp,log.level,trace.id,error.stack_trace等），适用于所有输出
+	// logfmt为key=value格式，如level=info msg="hello world"，适用于所有输出
+	Encoder string `yaml:"encoder" mapstructure:"encoder"`
+	// console编码时，日志等级是否使用彩色输出
+	Color bool `yaml:"color" mapstructure:"color"`
+	// json的key，默认为time,level,msg,caller，"-"为不输出该key
+	// Encoder为gcp,ecs时使用其规定的key，不生效
+	TimeKey    string `yaml:"time_key" mapstructure:"time_key"`
+	LevelKey   string `yaml:"level_key" mapstructure:"level_key"`
+	MessageKey string `yaml:"message_key" mapstructure:"message_key"`
+	CallerKey  string `yaml:"caller_key" mapstructure:"caller_key"`
+	// 时间格式，默认2006-01-02 15:04:05
+	// 支持epoch,epochmillis,epochnanos（数字），rfc3339,rfc3339nano，或自定义的go时间格式
+	TimeFormat string `yaml:"time_format" mapstructure:"time_format"`
+	// 时区，如UTC,Asia/Shanghai，默认为本地时区
+	TimeZone string `yaml:"time_zone" mapstructure:"time_zone"`
+	// 调用位置额外跳过的层数，用于在logx之上再封装的日志函数
+	CallerSkip int `yaml:"caller_skip" mapstructure:"caller_skip"`
+	// 不记录调用位置，减少热点路径上的开销
+	DisableCaller bool `yaml:"disable_caller" mapstructure:"disable_caller"`
+	// 该等级及以上的日志附带调用堆栈，如error，默认不附带
+	StacktraceLevel string `yaml:"stacktrace_level" mapstructure:"stacktrace_level"`
+	// 日志采样，相同等级及msg的日志在窗口内超过Initial条后按Thereafter采样，默认不采样
+	LogSampling LogSampling `yaml:"log_sampling" mapstructure:"log_sampling"`
 	// 日志文件路径
+	// 包含{field}时按该字段的值写入不同的文件，如./logs/{tenant}/run.log
+	// 缺少该字段的日志写入default，各文件共用切割的配置
+	// 支持{service}及日期模板，如./logs/{service}-{2006-01-02}.log，日期变化后写入新的文件（按字段分区时不切换）
 	File string `yaml:"file" mapstructure:"file"` // 日志文件路径
+	// error及以上等级的日志文件，配置后File只包含低于error的日志，两者各自切割，如./logs/error.log
+	// 同样支持{service}及日期模板，按字段分区时不生效
+	ErrorFile string `yaml:"error_file" mapstructure:"error_file"`
+	// 指向当前日志文件的符号链接，如./logs/run.log，便于tail等外部工具跟随
+	FileLink string `yaml:"file_link" mapstructure:"file_link"`
+	// 按字段分区时同时打开的文件数量上限，超过时关闭最久未使用的，默认64
+	PartitionMaxFiles int `yaml:"partition_max_files" mapstructure:"partition_max_files"`
 	// 日志文件大小限制，默认最大100MB,超过将触发文件切割
 	MaxSize int `yaml:"max_size" mapstructure:"max_size"`
 	// 日志文件的分割文件的数量，超过的将会被删除
 	MaxBackups int `yaml:"max_backups" mapstructure:"max_backups"`
 	// 日志文件的保留时间，超过的将会被删除
 	MaxAge int `yaml:"max_age" mapstructure:"max_age"`
+	// 当前日志文件及备份文件的总大小上限(MB)，超过时从最早的备份文件开始删除，默认不限制
+	MaxTotalSize int `yaml:"max_total_size" mapstructure:"max_total_size"`
 	// 是否启用日志文件的压缩功能
 	Compress bool `yaml:"compress" mapstructure:"compress"`
-	// 是否启用日志的切割功能
+	// 压缩方式，gzip(默认)或zstd
+	CompressType string `yaml:"compress_type" mapstructure:"compress_type"`
+	// 压缩级别，gzip为1-9，zstd为1-22，默认为各自的默认级别
+	CompressLevel int `yaml:"compress_level" mapstructure:"compress_level"`
+	// 日志切割的时间，cron表达式（精确到秒），如0 0 0 * * *
+	// 或daily(每天0点),hourly(每小时整点)，此时备份文件默认以日期命名，如run-2024-05-01.log
 	Rotate string `yaml:"rotate" mapstructure:"rotate"`
+	// 切割后备份文件的命名模板，默认为lumberjack的{name}-{time}{ext}
+	// 支持{name},{ext},{time},{host},{service}，如{service}-{time}-{host}{ext}
+	// 相对路径以日志文件所在目录为准，开启Compress且未配置CompressType,CompressLevel时不生效
+	RotateFilename string `yaml:"rotate_filename" mapstructure:"rotate_filename"`
+	// 备份文件名中{time}的格式，默认2006-01-02T15-04-05.000
+	RotateTimeFormat string `yaml:"rotate_time_format" mapstructure:"rotate_time_format"`
+	// 收到SIGHUP时重新打开日志文件，与logrotate的postrotate配合使用，默认不处理SIGHUP
+	ReopenOnSIGHUP bool `yaml:"reopen_on_sighup" mapstructure:"reopen_on_sighup"`
+	// 单条日志编码后的最大字节数，超过时msg将被拆分为多条日志，默认不限制
+	// 各部分带有相同的log.split_id及序号log.part,log.parts，fields仅在第一部分输出
+	// 适用于udp syslog等有长度限制的输出
+	MaxEntryBytes int `yaml:"max_entry_bytes" mapstructure:"max_entry_bytes"`
+	// kafka输出的配置
+	// KafkaCompression 压缩方式，none/gzip/snappy/lz4/zstd，默认none
+	// KafkaBatchSize 批量发送的日志数量，默认100
+	// KafkaBatchTimeout 批量发送的间隔，默认1秒
+	KafkaBrokers      []string      `yaml:"kafka_brokers" mapstructure:"kafka_brokers"`
+	KafkaTopic        string        `yaml:"kafka_topic" mapstructure:"kafka_topic"`
+	KafkaCompression  string        `yaml:"kafka_compression" mapstructure:"kafka_compression"`
+	KafkaBatchSize    int           `yaml:"kafka_batch_size" mapstructure:"kafka_batch_size"`
+	KafkaBatchTimeout time.Duration `yaml:"kafka_batch_timeout" mapstructure:"kafka_batch_timeout"`
+	// elasticsearch输出的配置
+	// ESIndex 索引名称的前缀，按天创建索引，如logs-service-2024.05.01，默认logs-{serviceName}
+	// ESBatchSize 批量写入的日志数量，默认500
+	// ESFlushInterval 批量写入的间隔，默认1秒
+	// ESRetry 写入失败时的最大尝试次数，按指数退避重试，默认3
+	// ESInsecureSkipVerify 是否跳过https证书的校验
+	ESServer             string        `yaml:"es_server" mapstructure:"es_server"`
+	ESIndex              string        `yaml:"es_index" mapstructure:"es_index"`
+	ESUsername           string        `yaml:"es_username" mapstructure:"es_username"`
+	ESPassword           string        `yaml:"es_password" mapstructure:"es_password"`
+	ESBatchSize          int           `yaml:"es_batch_size" mapstructure:"es_batch_size"`
+	ESFlushInterval      time.Duration `yaml:"es_flush_interval" mapstructure:"es_flush_interval"`
+	ESRetry              int           `yaml:"es_retry" mapstructure:"es_retry"`
+	ESInsecureSkipVerify bool          `yaml:"es_insecure_skip_verify" mapstructure:"es_insecure_skip_verify"`
+	// fluent输出的地址，默认127.0.0.1:24224
+	FluentAddress string `yaml:"fluent_address" mapstructure:"fluent_address"`
+	// fluent输出的tag模板，支持{service},{level},{host}，默认{service}.{level}
+	FluentTag string `yaml:"fluent_tag" mapstructure:"fluent_tag"`
+	// cloudwatch输出的配置，凭证依次从环境变量、~/.aws/credentials、ECS容器凭证、EC2实例角色获取
+	// CloudWatchStream 日志流，默认为{serviceName}/{hostname}，不存在时自动创建
+	// CloudWatchRegion 默认为环境变量AWS_REGION
+	// CloudWatchEndpoint 默认为https://logs.{region}.amazonaws.com
+	// CloudWatchFlushInterval 批量写入的间隔，默认5秒
+	CloudWatchGroup         string        `yaml:"cloudwatch_group" mapstructure:"cloudwatch_group"`
+	CloudWatchStream        string        `yaml:"cloudwatch_stream" mapstructure:"cloudwatch_stream"`
+	CloudWatchRegion        string        `yaml:"cloudwatch_region" mapstructure:"cloudwatch_region"`
+	CloudWatchEndpoint      string        `yaml:"cloudwatch_endpoint" mapstructure:"cloudwatch_endpoint"`
+	CloudWatchFlushInterval time.Duration `yaml:"cloudwatch_flush_interval" mapstructure:"cloudwatch_flush_interval"`
+	// Instrument时是否安装GinRecovery
+	InstrumentRecovery bool `yaml:"instrument_recovery" mapstructure:"instrument_recovery"`
+	// Instrument时是否将标准库log重定向为info日志
+	InstrumentStdLog bool `yaml:"instrument_std_log" mapstructure:"instrument_std_log"`
 	// Loki配置
 	// 一种是直接配置
 	// 一种是在docker中安装插件，并配置容器的log loki选项，由插件自动完成推送
@@ -49,12 +158,42 @@ type Config struct {
 	LokiPassword string `yaml:"loki_password" mapstructure:"loki_password"`
 	// 追踪使能
 	EnableTrace bool `yaml:"enable_trace" mapstructure:"enable_trace"`
+	// span名称后附加的时间格式，默认15:04:05，即spanName | 15:04:05
+	// none为不附加，避免span名称的基数过大
+	SpanNameTimeFormat string `yaml:"span_name_time_format" mapstructure:"span_name_time_format"`
 	// 日志追踪的类型，file/oltp，默认oltp
 	TracerProviderType string `yaml:"tracer_provider_type" mapstructure:"tracer_provider_type"`
 	// 日志追踪采样的比率, 0.0-1
 	// 0,never trace
 	// 1,always trace
 	TraceSampleRatio float64 `yaml:"trace_sample_ratio" mapstructure:"trace_sample_ratio"`
+	// 采样策略，默认oltp按TraceSampleRatio采样，file全部采样
+	// always 全部采样
+	// never 不采样
+	// ratio 按TraceSampleRatio采样
+	// parentbased_ratio 有上级span时跟随上级的采样结果，否则按TraceSampleRatio采样
+	// ratelimit 每秒最多采样SampleRateLimit个span
+	// parentbased_ratelimit 有上级span时跟随上级的采样结果，否则按SampleRateLimit限流采样
+	Sampler string `yaml:"sampler" mapstructure:"sampler"`
+	// ratelimit采样时每秒最多采样的span数量
+	SampleRateLimit float64 `yaml:"sample_rate_limit" mapstructure:"sample_rate_limit"`
+	// 按span名称前缀或http路由指定采样比率，使用第一条匹配的规则，均不匹配时使用Sampler
+	// 如/healthz为0，/checkout为1
+	SampleRules []SampleRule `yaml:"sample_rules" mapstructure:"sample_rules"`
+	// 根据baggage强制采样，格式为key或key=value
+	// 如canary=true，debug-session（存在即可）
+	// 匹配的请求将不受采样比率的限制，用于在入口处发起定向调试
+	TraceSampleBaggage []string `yaml:"trace_sample_baggage" mapstructure:"trace_sample_baggage"`
+	// 保存最近结束的span的数量（包括未采样的），用于FlushTrace按traceID补充导出
+	// 开启后未采样的span也会被记录，会增加一定的开销。仅对oltp类型生效
+	RecentSpans int `yaml:"recent_spans" mapstructure:"recent_spans"`
+	// file类型的追踪文件路径，默认为当前目录的trace.txt
+	TraceFile string `yaml:"trace_file" mapstructure:"trace_file"`
+	// 追踪文件的切割，单位为MB及天，均为0时不切割，每次启动清空文件
+	// TraceFileMaxBackups 保留的切割文件数量，0为不限制
+	TraceFileMaxSize    int `yaml:"trace_file_max_size" mapstructure:"trace_file_max_size"`
+	TraceFileMaxAge     int `yaml:"trace_file_max_age" mapstructure:"trace_file_max_age"`
+	TraceFileMaxBackups int `yaml:"trace_file_max_backups" mapstructure:"trace_file_max_backups"`
 	// 默认使用https，为false时，使用http
 	OLTPInsecure bool `yaml:"oltp_insecure" mapstructure:"oltp_insecure"`
 	// oltp endpoint 将trace data发送到该地址
@@ -62,16 +201,96 @@ type Config struct {
 	OTLPEndpointURLPath string `yaml:"oltp_endpoint_url_path" mapstructure:"oltp_endpoint_url_path"`
 	// 用户basic auth
 	OTLPToken string `yaml:"oltp_token" mapstructure:"oltp_token"`
+	// InitMetrics导出指标的路径，默认/v1/metrics，与trace共用OTLPEndpoint,OLTPInsecure,OTLPToken
+	OTLPMetricsURLPath string `yaml:"oltp_metrics_url_path" mapstructure:"oltp_metrics_url_path"`
+	// InitMetrics导出指标的间隔，默认1分钟
+	MetricsInterval time.Duration `yaml:"metrics_interval" mapstructure:"metrics_interval"`
+	// 额外输出的traceID格式，支持xray,datadog
+	// 配置后会同时写入日志字段，并在HttpInject时注入对应的header
+	TraceIDFormats []string `yaml:"trace_id_formats" mapstructure:"trace_id_formats"`
+	// 需要透传的header，如X-Request-ID,X-Tenant
+	// GinMiddleware会将请求中的这些header保存到context，HttpInject时一并转发
+	PropagationHeaders []string `yaml:"propagation_headers" mapstructure:"propagation_headers"`
+	// Encoder为gcp时logging.googleapis.com/trace中的项目ID，默认为环境变量GOOGLE_CLOUD_PROJECT
+	GCPProject string `yaml:"gcp_project" mapstructure:"gcp_project"`
+	// 自定义traceID和spanID的生成，如sonyflake,ULID等
+	// 同时用于GenTraceID,GenSpanID及追踪的span，默认使用crypto/rand生成
+	IDGenerator IDGenerator `yaml:"-" mapstructure:"-"`
+	// 应用属性的命名空间，如app
+	// 配置后日志及span中自定义的字段都会添加该前缀，如app.user_id，避免与otel语义约定的key冲突
+	AttributeNamespace string `yaml:"attribute_namespace" mapstructure:"attribute_namespace"`
+	// 脱敏的字段key，不区分大小写，如password,token,authorization，日志、span、Loki及Sentry中输出为******
+	RedactKeys []string `yaml:"redact_keys" mapstructure:"redact_keys"`
+	// 脱敏的正则，字符串类型的值中匹配的部分替换为******，可使用内置规则email,phone,credit_card
+	RedactPatterns []string `yaml:"redact_patterns" mapstructure:"redact_patterns"`
+	// 字段值的最大长度（字节），超过时截断并添加...，Any,Object按json的长度计算，同时作为span属性值的长度限制
+	MaxFieldLength int `yaml:"max_field_length" mapstructure:"max_field_length"`
+	// 每条日志、span事件最多的字段数，超过的字段被丢弃，同时作为span属性数量的限制
+	// 发生截断时附带字段truncated=true
+	MaxFields int `yaml:"max_fields" mapstructure:"max_fields"`
+	// span的instrumentation scope名称及版本
+	// 默认为github.com/itmisx/logx及其版本，可通过WithScope为单个context设置
+	ScopeName    string `yaml:"scope_name" mapstructure:"scope_name"`
+	ScopeVersion string `yaml:"scope_version" mapstructure:"scope_version"`
+	// Shutdown时是否记录一条统计日志，包括各等级的日志数量，span数量，导出失败次数等
+	ShutdownSummary bool `yaml:"shutdown_summary" mapstructure:"shutdown_summary"`
+	// trace中发生Error后，该trace之后的Debug,Info日志不受日志等级限制的条数，0为不开启
+	// 用于在不开启全局debug的情况下，记录错误发生后的详细日志
+	ErrorBoost int `yaml:"error_boost" mapstructure:"error_boost"`
+	// trace中未达到日志等级的Debug,Info日志，保留最近的条数，0为不开启
+	// 该trace中发生Error,Fatal时先写入保留的日志，用于在只记录错误日志时查看错误发生前的详细日志
+	ErrorBuffer int `yaml:"error_buffer" mapstructure:"error_buffer"`
+	// Fatal时结束当前span并立即导出，避免进程退出后丢失
+	FlushOnFatal bool `yaml:"flush_on_fatal" mapstructure:"flush_on_fatal"`
+	// FlushErrorWindow内错误状态的span达到FlushErrorSpans时，立即导出，0为不开启
+	// FlushErrorWindow默认1分钟
+	FlushErrorSpans  int           `yaml:"flush_error_spans" mapstructure:"flush_error_spans"`
+	FlushErrorWindow time.Duration `yaml:"flush_error_window" mapstructure:"flush_error_window"`
+	// 尾部采样，按trace缓存span，本地的根span结束时仅导出满足条件的trace，默认不开启
+	// error 导出包含错误的trace（span状态为错误或记录过Error日志）
+	// warn 导出包含错误或Warn日志的trace
+	// 仅对采样的span生效，建议配合Sampler: always使用
+	TailSampling string `yaml:"tail_sampling" mapstructure:"tail_sampling"`
+	// 尾部采样等待trace结束的最长时间，默认30秒
+	TailSamplingTimeout time.Duration `yaml:"tail_sampling_timeout" mapstructure:"tail_sampling_timeout"`
+	// 批量导出span的配置，0为使用otel的默认值
+	// BatchTimeout 批量导出的间隔，默认5秒
+	// ExportTimeout 单次导出的超时时间，默认30秒
+	// MaxQueueSize 等待导出的span的队列长度，队列满时丢弃新的span，默认2048
+	// MaxExportBatchSize 单次导出的span数量上限，默认512
+	BatchTimeout       time.Duration `yaml:"batch_timeout" mapstructure:"batch_timeout"`
+	ExportTimeout      time.Duration `yaml:"export_timeout" mapstructure:"export_timeout"`
+	MaxQueueSize       int           `yaml:"max_queue_size" mapstructure:"max_queue_size"`
+	MaxExportBatchSize int           `yaml:"max_export_batch_size" mapstructure:"max_export_batch_size"`
+	// oltp导出失败时重试的最长时间，0为使用otel的默认值1分钟
+	ExportRetry time.Duration `yaml:"export_retry" mapstructure:"export_retry"`
+	// oltp导出失败（重试后）时，将span保存到该目录，之后导出成功时重新导出，默认不开启
+	// SpoolMaxBytes 保存的总大小上限，超过时删除最早的，默认100MB
+	// SpoolMaxAge 保存的最长时间，超过的不再导出，默认24小时
+	SpoolDir      string        `yaml:"spool_dir" mapstructure:"spool_dir"`
+	SpoolMaxBytes int64         `yaml:"spool_max_bytes" mapstructure:"spool_max_bytes"`
+	SpoolMaxAge   time.Duration `yaml:"spool_max_age" mapstructure:"spool_max_age"`
+	// 是否将Stats发布为expvar变量logx，可通过/debug/vars查看
+	Expvar bool `yaml:"expvar" mapstructure:"expvar"`
+	// Sentry DSN，配置后Error及以上等级的日志会同时发送到Sentry
+	// 包括调用堆栈，trace_id，fields作为tags或extra
+	SentryDSN string `yaml:"sentry_dsn" mapstructure:"sentry_dsn"`
+	// Sentry事件的environment，如prod
+	SentryEnvironment string `yaml:"sentry_environment" mapstructure:"sentry_environment"`
+	// 审计日志文件，Audit写入该文件，不切割、不采样且不受日志等级限制，未配置时Audit返回错误
+	AuditFile string `yaml:"audit_file" mapstructure:"audit_file"`
+	// 错误告警的webhook地址，AlertWindow内的错误日志达到AlertThreshold时发送告警，每个窗口最多一次
+	// 告警包含服务名称、应用属性及最近的错误日志
+	AlertWebhook string `yaml:"alert_webhook" mapstructure:"alert_webhook"`
+	// webhook的类型，webhook/slack/dingtalk/wecom，默认根据地址判断，其他地址为webhook（通用json）
+	AlertWebhookType string `yaml:"alert_webhook_type" mapstructure:"alert_webhook_type"`
+	// 告警的错误日志数量，默认10
+	AlertThreshold int `yaml:"alert_threshold" mapstructure:"alert_threshold"`
+	// 统计错误日志的窗口，默认1分钟
+	AlertWindow time.Duration `yaml:"alert_window" mapstructure:"alert_window"`
 }
 
-var (
-	enable_log bool
-	logger     *zap.Logger
-	config     Config
-	provider   *trace.TracerProvider
-	reqClient  *req.Client
-)
-
+// LokiLabel 推送到loki的label，Init时添加service_name及应用属性
 var LokiLabel = map[string]string{}
 
 // save context span
@@ -83,6 +302,10 @@ type LoggerContextKey int
 
 const (
 	loggerSpanContextKey LoggerContextKey = iota
+	loggerHeaderContextKey
+	loggerFieldsContextKey
+	loggerWorkerContextKey
+	loggerScopeContextKey
 )
 
 // LoggerInit logger初始化
@@ -94,53 +317,181 @@ const (
 //
 // 日志的label不支持*.*格式，会被过滤掉
 //
+// 支持otel标准的环境变量OTEL_SERVICE_NAME,OTEL_EXPORTER_OTLP_ENDPOINT,OTEL_TRACES_SAMPLER,
+// OTEL_RESOURCE_ATTRIBUTES等，仅在参数及conf中未配置时生效
+//
 // example:
-// Init(conf,String("sevice.name",service1))
+// Init(conf,"service1",String("service.version","v1"))
 func Init(conf Config, serviceName string, applicationAttributes ...Field) {
-	otel.SetTextMapPropagator(b3.New())
-	config = conf
+	InitWithOptions(conf, serviceName, WithResource(applicationAttributes...))
+}
+
+// InitWithOptions 使用可选项初始化，其他同Init
+// options 可选项，参考WithResource,WithZapCore,WithSampler,WithOTelSampler,WithPropagator,WithTracerProvider
+// Field作为应用属性，等同于WithResource
+//
+// example:
+// InitWithOptions(conf,"service1",WithResource(String("service.version","v1")),WithZapCore(core))
+func InitWithOptions(conf Config, serviceName string, options ...Option) {
+	initMu.Lock()
+	defer initMu.Unlock()
+	initLocked(conf, serviceName, options...)
+}
+
+// initLocked Init的实现，调用方需持有initMu
+// 先创建完整的logState再整体替换，之后刷新并关闭之前的日志文件及输出，返回之前的logState
+func initLocked(conf Config, serviceName string, options ...Option) *logState {
+	initArgs.serviceName, initArgs.options = serviceName, options
+	previous := loadState()
+	s := &logState{fields: previous.fields}
+	for _, opt := range options {
+		opt.apply(&s.opts)
+	}
+	s.applied = conf
+	serviceName = applyEnv(&conf, serviceName, &s.opts)
+	// 按补充环境变量后的配置判断
+	off := isDisabled(conf, s.opts)
+	applicationAttributes := s.opts.resource
+	resetStats()
+	if conf.Expvar {
+		publishExpvar()
+	}
+	// WithPropagator指定的或默认的b3+baggage，HttpInject,Inject等都使用该propagator
+	s.propagator = s.opts.propagator
+	if s.propagator == nil {
+		s.propagator = propagation.NewCompositeTextMapPropagator(b3.New(), propagation.Baggage{})
+	}
+	// 关闭日志及追踪时不修改otel的全局设置
+	if !off {
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(handleOTelError))
+	}
+	if !off || s.opts.propagator != nil {
+		otel.SetTextMapPropagator(s.propagator)
+	}
+	s.config = conf
 	// 设置loki的label
 	var reg = regexp.MustCompile(`^[0-9A-Za-z_]+$`)
-	LokiLabel["service_name"] = serviceName
+	// 复制后再修改，之前的logState仍在使用原来的map
+	s.lokiLabel = maps.Clone(LokiLabel)
+	s.lokiLabel["service_name"] = serviceName
 	for _, attr := range applicationAttributes {
 		if attr.Type == stringType && reg.MatchString(attr.Key) {
-			LokiLabel[attr.Key] = attr.String
+			s.lokiLabel[attr.Key] = attr.String
 		}
 	}
-	if config.LokiServer != "" {
-		reqClient = req.C().SetCommonBasicAuth(config.LokiUsername, config.LokiPassword)
+	LokiLabel = s.lokiLabel
+	if s.config.LokiServer != "" {
+		s.reqClient = req.C().SetCommonBasicAuth(s.config.LokiUsername, s.config.LokiPassword)
 	}
-	if config.EnableTrace {
+	s.redactor = newRedactor(s.config.RedactKeys, s.config.RedactPatterns)
+	if s.config.SentryDSN != "" {
+		client, err := newSentryClient(s.config.SentryDSN, serviceName)
+		if err != nil {
+			log.Printf("logx: create sentry client failed: %v", err)
+		}
+		s.sentry = client
+	}
+	if previous.audit != nil {
+		// 同一个文件只能由一个auditWriter写入，需先关闭之前的
+		previous.audit.close()
+	}
+	if s.config.AuditFile != "" {
+		w, err := newAuditWriter(s.config.AuditFile, serviceName)
+		if err != nil {
+			log.Printf("logx: open audit file failed: %v", err)
+		}
+		s.audit = w
+	}
+	if s.config.AlertWebhook != "" {
+		s.alert = newErrorAlert(s.config, serviceName, applicationAttributes)
+	}
+	if s.config.EnableTrace {
 		var pd *trace.TracerProvider
+		var err error
 		if conf.TracerProviderType == "" {
 			conf.TracerProviderType = "oltp"
 		}
-		switch conf.TracerProviderType {
-		case "oltp":
-			pd, _ = Trace{}.NewOLTPProvider(context.Background(), conf, serviceName, applicationAttributes...)
-		case "file":
-			pd, _ = Trace{}.NewFileProvider(conf, serviceName, applicationAttributes...)
+		switch {
+		case s.opts.provider != nil:
+			pd = s.opts.provider
+		case conf.TracerProviderType == "oltp":
+			pd, err = Trace{state: s}.NewOLTPProvider(context.Background(), conf, serviceName, applicationAttributes...)
+		case conf.TracerProviderType == "file":
+			pd, err = Trace{state: s}.NewFileProvider(conf, serviceName, applicationAttributes...)
 		default:
 			log.Fatal("Unsupported tracerProvider type")
 		}
 
 		if pd != nil {
-			provider = pd
+			s.provider = pd
+		} else {
+			// 创建失败时关闭追踪，Start返回noop的span
+			log.Printf("logx: create tracer provider failed: %v", err)
+			s.config.EnableTrace = false
 		}
 	}
+	if s.provider == nil {
+		// 未开启追踪时保留之前的tracerProvider，与之前的行为一致
+		s.provider = previous.provider
+	}
 	// 默认不输出日志
-	if config.Output == "" {
-		config.Output = "none"
+	if s.config.Output == "" {
+		s.config.Output = "none"
 	}
-	if config.Output != "none" {
-		enable_log = true
-		if config.Output != "file" && config.Output != "console" {
-			config.Output = "console"
+	if s.config.Output != "none" {
+		s.enabled = true
+		if s.config.Output != "file" && s.config.Output != "console" && !isSinkOutput(s.config.Output) {
+			s.config.Output = "console"
 		}
-		zapLogger := newZapLogger(conf)
-		logger = zapLogger.Logger
+		zapLogger := newZapLogger(conf, serviceName, s.opts)
+		s.logger = zapLogger.Logger
+		s.boost = zapLogger.Boost
+		s.rotator = zapLogger.rotator
+		s.closers = zapLogger.closers
 		zapLogger.rotateCrond(conf)
+	} else if len(s.opts.zapCores) > 0 {
+		// 仅输出到自定义的zap core
+		s.enabled = true
+		cores := splitCores(s.config.MaxEntryBytes, nil, s.opts.zapCores...)
+		s.logger = zap.New(zapcore.NewTee(cores...), zapOptions(s.config)...)
+		s.boost = s.logger
+	} else {
+		s.logger = zap.NewNop()
+	}
+	if !s.enabled || (s.config.ErrorBoost <= 0 && s.config.ErrorBuffer <= 0) {
+		s.boost = nil
+	}
+	errorBuffers.reset()
+	watchReopenSignal(s.config.ReopenOnSIGHUP)
+	if s.enabled {
+		s.logger = s.logger.WithOptions(zap.Hooks(countEntry))
+		if s.boost != nil {
+			s.boost = s.boost.WithOptions(zap.Hooks(countEntry))
+		}
+	}
+	if s.enabled && len(s.fields) > 0 {
+		s.logger = s.logger.With(s.zapFields(context.Background(), s.fields...)...)
+		if s.boost != nil {
+			s.boost = s.boost.With(s.zapFields(context.Background(), s.fields...)...)
+		}
+	}
+	storeState(s)
+	disabled.Store(off)
+	if err := previous.release(s); err != nil {
+		log.Printf("logx: close previous log output failed: %v", err)
 	}
+	return previous
+}
+
+// With 设置全局默认字段，如host,pid,env,version等
+// 之后记录的所有日志都会附带这些字段，需在程序启动时调用
+//
+// example:
+// With(String("env", "prod"), Int("pid", os.Getpid()))
+func With(fields ...Field) {
+	initMu.Lock()
+	defer initMu.Unlock()
+	storeState(loadState().with(fields))
 }
 
 // Start 启动一个span追踪
@@ -148,19 +499,60 @@ func Init(conf Config, serviceName string, applicationAttributes ...Field) {
 // spanName span名字
 // spanStartOption span附带属性
 func Start(ctx context.Context, spanName string, spanStartOption ...Field) context.Context {
+	return startSpan(ctx, spanName, oteltrace.SpanKindInternal, spanStartOption)
+}
+
+// StartServer 启动一个server类型的span，用于处理外部请求，如http,grpc服务端
+func StartServer(ctx context.Context, spanName string, spanStartOption ...Field) context.Context {
+	return startSpan(ctx, spanName, oteltrace.SpanKindServer, spanStartOption)
+}
+
+// StartClient 启动一个client类型的span，用于请求外部服务，如http,grpc客户端,数据库
+func StartClient(ctx context.Context, spanName string, spanStartOption ...Field) context.Context {
+	return startSpan(ctx, spanName, oteltrace.SpanKindClient, spanStartOption)
+}
+
+// StartProducer 启动一个producer类型的span，用于发送消息
+func StartProducer(ctx context.Context, spanName string, spanStartOption ...Field) context.Context {
+	return startSpan(ctx, spanName, oteltrace.SpanKindProducer, spanStartOption)
+}
+
+// StartConsumer 启动一个consumer类型的span，用于消费消息
+func StartConsumer(ctx context.Context, spanName string, spanStartOption ...Field) context.Context {
+	return startSpan(ctx, spanName, oteltrace.SpanKindConsumer, spanStartOption)
+}
+
+// startSpan 启动指定类型的span
+func startSpan(ctx context.Context, spanName string, kind oteltrace.SpanKind, spanStartOption []Field) context.Context {
+	s := loadState()
+	// 关闭时不创建noop的span
+	if disabled.Load() {
+		return ctx
+	}
 	var loggerSpanContext LoggerSpanContext
 	var spanContext context.Context
 	var enableTrace bool
 	var span oteltrace.Span
 	// 根据配置开启日志追踪
-	if config.EnableTrace {
+	if s.config.EnableTrace {
 		enableTrace = true
 	}
-	spanName = spanName + " | " + time.Now().Format("15:04:05")
+	switch s.config.SpanNameTimeFormat {
+	case "none":
+	case "":
+		spanName = spanName + " | " + time.Now().Format("15:04:05")
+	default:
+		spanName = spanName + " | " + time.Now().Format(s.config.SpanNameTimeFormat)
+	}
 	// 根据条件
 	// 如果未开启追踪，则返回一个nooptreace，意味着将不再追踪
 	if enableTrace {
-		spanContext, span = provider.Tracer("").Start(ctx, spanName, oteltrace.WithAttributes(FieldsToKeyValues(spanStartOption...)...))
+		attrs := FieldsToKeyValues(hookSpanFields(ctx, spanName, spanStartOption)...)
+		if workerID := WorkerID(ctx); workerID != "" {
+			attrs = append(attrs, attribute.String("worker.id", workerID))
+		}
+		spanContext, span = tracerOf(ctx).Start(ctx, spanName, oteltrace.WithAttributes(attrs...), oteltrace.WithSpanKind(kind))
+		setGRPCSpanStatus(span, spanStartOption)
 		loggerSpanContext.span = span
 	} else {
 		// 如果trace失能，将会创建一个noop traceProvider
@@ -173,101 +565,343 @@ func Start(ctx context.Context, spanName string, spanStartOption ...Field) conte
 
 // SetSpanAttr 为当前的span动态设置属性
 func SetSpanAttr(ctx context.Context, attributes ...Field) {
+	s := loadState()
 	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
 	if !ok {
 		return
 	}
-	if config.EnableTrace {
-		loggerSpanContext.span.SetAttributes(FieldsToKeyValues(attributes...)...)
+	if s.config.EnableTrace {
+		loggerSpanContext.span.SetAttributes(FieldsToKeyValues(hookSpanFields(ctx, "", attributes)...)...)
+		setGRPCSpanStatus(loggerSpanContext.span, attributes)
 	}
 }
 
-// Debug record debug
-func Debug(ctx context.Context, msg string, attributes ...Field) {
-	if enable_log {
-		logger.Debug(msg, FieldsToZapFields(ctx, attributes...)...)
-	}
-	if config.LokiServer != "" {
-		lokiPush(ctx, "debug", msg, attributes...)
-	}
+// Event 为当前的span添加一个指定时间的事件，用于记录之前发生的事件，如设备上报数据中的时间
+// ts为零值时使用当前时间
+func Event(ctx context.Context, name string, ts time.Time, attributes ...Field) {
+	s := loadState()
 	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
 	if !ok {
 		return
 	}
-	if config.EnableTrace {
-		loggerSpanContext.span.AddEvent(msg, oteltrace.WithAttributes(FieldsToKeyValues(attributes...)...))
+	if s.config.EnableTrace {
+		options := []oteltrace.EventOption{oteltrace.WithAttributes(FieldsToKeyValues(hookSpanFields(ctx, name, attributes)...)...)}
+		if !ts.IsZero() {
+			options = append(options, oteltrace.WithTimestamp(ts))
+		}
+		loggerSpanContext.span.AddEvent(name, options...)
 	}
 }
 
-// Info record info
-func Info(ctx context.Context, msg string, attributes ...Field) {
-	if enable_log {
-		logger.Info(msg, FieldsToZapFields(ctx, attributes...)...)
-	}
-	if config.LokiServer != "" {
-		lokiPush(ctx, "info", msg, attributes...)
-	}
-	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
-	if !ok {
-		return
+// WithFields 将fields保存到context，之后使用该context记录的日志都会附带这些fields
+// 适用于user_id,tenant,request_id等请求级别的属性
+func WithFields(ctx context.Context, fields ...Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
 	}
-	if config.EnableTrace {
-		loggerSpanContext.span.AddEvent(msg, oteltrace.WithAttributes(FieldsToKeyValues(attributes...)...))
+	saved := contextFields(ctx)
+	return context.WithValue(ctx, loggerFieldsContextKey, append(saved[:len(saved):len(saved)], fields...))
+}
+
+// contextFields 获取context中保存的fields
+func contextFields(ctx context.Context) []Field {
+	fields, _ := ctx.Value(loggerFieldsContextKey).([]Field)
+	return fields
+}
+
+// withContextFields 合并context中保存的fields
+func withContextFields(ctx context.Context, attributes []Field) []Field {
+	saved := contextFields(ctx)
+	if len(saved) == 0 {
+		return attributes
 	}
+	return append(saved[:len(saved):len(saved)], attributes...)
 }
 
 // Warn record warn
 func Warn(ctx context.Context, msg string, attributes ...Field) {
-	if enable_log {
-		logger.Warn(msg, FieldsToZapFields(ctx, attributes...)...)
+	s := loadState()
+	if disabled.Load() {
+		return
+	}
+	attributes = withContextFields(ctx, attributes)
+	msg, attributes, ok := hookEntry(ctx, "warn", msg, attributes)
+	if !ok {
+		return
+	}
+	if s.enabled {
+		if ce := s.logger.Check(zap.WarnLevel, msg); ce != nil {
+			ce.Write(s.zapFields(ctx, attributes...)...)
+		}
 	}
-	if config.LokiServer != "" {
+	if s.config.LokiServer != "" {
 		lokiPush(ctx, "warn", msg, attributes...)
 	}
 	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
 	if !ok {
 		return
 	}
-	if config.EnableTrace {
+	if s.config.EnableTrace {
 		loggerSpanContext.span.AddEvent(msg, oteltrace.WithAttributes(FieldsToKeyValues(attributes...)...))
+		tailKeep(ctx)
 	}
 }
 
 // Error record error
 func Error(ctx context.Context, msg string, attributes ...Field) {
-	if enable_log {
-		logger.Error(msg, FieldsToZapFields(ctx, attributes...)...)
+	s := loadState()
+	if disabled.Load() {
+		return
+	}
+	attributes = withContextFields(ctx, attributes)
+	msg, attributes, ok := hookEntry(ctx, "error", msg, attributes)
+	if !ok {
+		return
+	}
+	if s.enabled {
+		boostTrace(ctx)
+		if ce := s.logger.Check(zap.ErrorLevel, msg); ce != nil {
+			ce.Write(s.zapFields(ctx, attributes...)...)
+		}
 	}
-	if config.LokiServer != "" {
+	if s.config.LokiServer != "" {
 		lokiPush(ctx, "error", msg, attributes...)
 	}
+	sentryCapture(ctx, "error", msg, nil, false, attributes...)
+	alertError(msg)
+	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
+	if !ok {
+		return
+	}
+	if s.config.EnableTrace {
+		spanRecordError(loggerSpanContext.span, nil, msg, attributes)
+	}
+}
+
+// DPanic record dpanic
+func DPanic(ctx context.Context, msg string, attributes ...Field) {
+	s := loadState()
+	if disabled.Load() {
+		return
+	}
+	attributes = withContextFields(ctx, attributes)
+	msg, attributes, ok := hookEntry(ctx, "dpanic", msg, attributes)
+	if !ok {
+		return
+	}
+	if s.enabled {
+		boostTrace(ctx)
+		s.logger.DPanic(msg, s.zapFields(ctx, attributes...)...)
+	}
+	if s.config.LokiServer != "" {
+		lokiPush(ctx, "dpanic", msg, attributes...)
+	}
+	sentryCapture(ctx, "error", msg, nil, false, attributes...)
+	alertError(msg)
 	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
 	if !ok {
 		return
 	}
-	if config.EnableTrace {
-		loggerSpanContext.span.RecordError(errors.New(msg), oteltrace.WithAttributes(FieldsToKeyValues(attributes...)...))
+	if s.config.EnableTrace {
+		spanRecordError(loggerSpanContext.span, nil, msg, attributes)
+	}
+}
+
+// Panic record panic, then panic
+func Panic(ctx context.Context, msg string, attributes ...Field) {
+	s := loadState()
+	attributes = withContextFields(ctx, attributes)
+	msg, attributes, _ = hookEntry(ctx, "panic", msg, attributes)
+	if s.config.LokiServer != "" {
+		lokiPush(ctx, "panic", msg, attributes...)
+	}
+	sentryCapture(ctx, "fatal", msg, nil, true, attributes...)
+	alertError(msg)
+	if loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext); ok && s.config.EnableTrace {
+		spanRecordError(loggerSpanContext.span, nil, msg, attributes)
 	}
+	if s.enabled {
+		boostTrace(ctx)
+		s.logger.Panic(msg, s.zapFields(ctx, attributes...)...)
+	}
+	panic(msg)
 }
 
 // Fatal record fatal
 func Fatal(ctx context.Context, msg string, attributes ...Field) {
-	defer func() {
-		loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
-		if !ok {
-			return
+	s := loadState()
+	attributes = withContextFields(ctx, attributes)
+	msg, attributes, _ = hookEntry(ctx, "fatal", msg, attributes)
+	// logger.Fatal会退出进程，需先推送到loki并记录到span
+	if s.config.LokiServer != "" {
+		lokiPushSync(ctx, "fatal", msg, attributes...)
+	}
+	if loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext); ok && s.config.EnableTrace {
+		spanRecordError(loggerSpanContext.span, nil, msg, attributes)
+	}
+	sentryCapture(ctx, "fatal", msg, nil, true, attributes...)
+	alertError(msg)
+	flushOnFatal(ctx)
+	if s.enabled {
+		boostTrace(ctx)
+		s.logger.Fatal(msg, s.zapFields(ctx, attributes...)...)
+	}
+}
+
+// Warnf record warn with format
+func Warnf(ctx context.Context, format string, args ...interface{}) {
+	s := loadState()
+	if disabled.Load() {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	attributes := contextFields(ctx)
+	msg, attributes, ok := hookEntry(ctx, "warn", msg, attributes)
+	if !ok {
+		return
+	}
+	if s.enabled {
+		if ce := s.logger.Check(zap.WarnLevel, msg); ce != nil {
+			ce.Write(s.zapFields(ctx, attributes...)...)
 		}
-		if config.EnableTrace {
-			// add error logs
-			loggerSpanContext.span.RecordError(errors.New(msg), oteltrace.WithAttributes(FieldsToKeyValues(attributes...)...))
+	}
+	if s.config.LokiServer != "" {
+		lokiPush(ctx, "warn", msg, attributes...)
+	}
+	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
+	if !ok {
+		return
+	}
+	if s.config.EnableTrace {
+		loggerSpanContext.span.AddEvent(msg, oteltrace.WithAttributes(FieldsToKeyValues(attributes...)...))
+		tailKeep(ctx)
+	}
+}
+
+// Errorf record error with format
+func Errorf(ctx context.Context, format string, args ...interface{}) {
+	s := loadState()
+	if disabled.Load() {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	attributes := contextFields(ctx)
+	msg, attributes, ok := hookEntry(ctx, "error", msg, attributes)
+	if !ok {
+		return
+	}
+	if s.enabled {
+		boostTrace(ctx)
+		if ce := s.logger.Check(zap.ErrorLevel, msg); ce != nil {
+			ce.Write(s.zapFields(ctx, attributes...)...)
 		}
-	}()
-	if enable_log {
-		logger.Fatal(msg, FieldsToZapFields(ctx, attributes...)...)
 	}
-	if config.LokiServer != "" {
-		lokiPush(ctx, "fatal", msg, attributes...)
+	if s.config.LokiServer != "" {
+		lokiPush(ctx, "error", msg, attributes...)
+	}
+	sentryCapture(ctx, "error", msg, nil, false, attributes...)
+	alertError(msg)
+	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
+	if !ok {
+		return
+	}
+	if s.config.EnableTrace {
+		spanRecordError(loggerSpanContext.span, nil, msg, attributes)
+	}
+}
+
+// Fatalf record fatal with format
+func Fatalf(ctx context.Context, format string, args ...interface{}) {
+	s := loadState()
+	msg := fmt.Sprintf(format, args...)
+	attributes := contextFields(ctx)
+	msg, attributes, _ = hookEntry(ctx, "fatal", msg, attributes)
+	// logger.Fatal会退出进程，需先推送到loki并记录到span
+	if s.config.LokiServer != "" {
+		lokiPushSync(ctx, "fatal", msg, attributes...)
+	}
+	if loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext); ok && s.config.EnableTrace {
+		spanRecordError(loggerSpanContext.span, nil, msg, attributes)
+	}
+	sentryCapture(ctx, "fatal", msg, nil, true, attributes...)
+	alertError(msg)
+	flushOnFatal(ctx)
+	if s.enabled {
+		boostTrace(ctx)
+		s.logger.Fatal(msg, s.zapFields(ctx, attributes...)...)
+	}
+}
+
+// FatalCode record fatal, then exit with code
+func FatalCode(ctx context.Context, code int, msg string, attributes ...Field) {
+	s := loadState()
+	attributes = withContextFields(ctx, attributes)
+	msg, attributes, _ = hookEntry(ctx, "fatal", msg, attributes)
+	if s.config.LokiServer != "" {
+		lokiPushSync(ctx, "fatal", msg, attributes...)
+	}
+	if loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext); ok && s.config.EnableTrace {
+		spanRecordError(loggerSpanContext.span, nil, msg, attributes)
+	}
+	sentryCapture(ctx, "fatal", msg, nil, true, attributes...)
+	alertError(msg)
+	flushOnFatal(ctx)
+	if s.enabled {
+		boostTrace(ctx)
+		s.logger.WithOptions(zap.WithFatalHook(exitHook(code))).Fatal(msg, s.zapFields(ctx, attributes...)...)
+	}
+	os.Exit(code)
+}
+
+// ErrorReturn record error and return it
+// err为nil时不记录，直接返回nil
+//
+// example:
+//
+//	return ErrorReturn(ctx, err, String("key", "value"))
+func ErrorReturn(ctx context.Context, err error, attributes ...Field) error {
+	s := loadState()
+	if disabled.Load() {
+		return err
+	}
+	if err == nil {
+		return nil
+	}
+	attributes = withContextFields(ctx, attributes)
+	msg, attributes, ok := hookEntry(ctx, "error", err.Error(), attributes)
+	if !ok {
+		return err
+	}
+	if s.enabled {
+		boostTrace(ctx)
+		s.logger.Error(msg, s.zapFields(ctx, attributes...)...)
+	}
+	if s.config.LokiServer != "" {
+		lokiPush(ctx, "error", msg, attributes...)
+	}
+	sentryCapture(ctx, "error", msg, err, false, attributes...)
+	alertError(msg)
+	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
+	if !ok {
+		return err
 	}
+	if s.config.EnableTrace {
+		spanRecordError(loggerSpanContext.span, err, msg, attributes)
+	}
+	return err
+}
+
+// WithWorkerID 将workerID保存到context
+// 之后使用该context记录的日志会附带worker_id字段，启动的span会附带worker.id属性
+// 用于区分worker池中交错输出的日志
+func WithWorkerID(ctx context.Context, workerID string) context.Context {
+	return context.WithValue(ctx, loggerWorkerContextKey, workerID)
+}
+
+// WorkerID return workerID
+func WorkerID(ctx context.Context) string {
+	workerID, _ := ctx.Value(loggerWorkerContextKey).(string)
+	return workerID
 }
 
 // TraceID return traceID
@@ -316,91 +950,277 @@ func NewRootContext(traceID string, spanID string) (context.Context, error) {
 	return ctx, nil
 }
 
+// DetachContext 返回仅保留关联信息的context，用于长期保存，如缓存或session中
+// 仅保留traceID,spanID,fields及workerID，不再持有span及上级context的取消和其他值
+// 基于返回的context记录的日志仍附带traceID,spanID，Start启动的span为其子span
+func DetachContext(ctx context.Context) context.Context {
+	detached := context.Background()
+	if fields := contextFields(ctx); len(fields) > 0 {
+		detached = context.WithValue(detached, loggerFieldsContextKey, fields)
+	}
+	if workerID := WorkerID(ctx); workerID != "" {
+		detached = context.WithValue(detached, loggerWorkerContextKey, workerID)
+	}
+	return withRemoteSpanContext(detached, oteltrace.SpanContextFromContext(ctx))
+}
+
+// withOTelSpan 将ctx中otel的span作为logx的span，使日志附带traceID并记录到该span
+// 用于中间件等未通过Start启动span的场景
+func withOTelSpan(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext); ok {
+		return ctx
+	}
+	span := oteltrace.SpanFromContext(ctx)
+	if !span.SpanContext().IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, loggerSpanContextKey, LoggerSpanContext{span: span})
+}
+
+// withRemoteSpanContext 将sc作为上级span保存到ctx，sc无效时返回ctx
+// 使用不可记录的span，仅用于获取traceID,spanID
+func withRemoteSpanContext(ctx context.Context, sc oteltrace.SpanContext) context.Context {
+	if !sc.IsValid() {
+		return ctx
+	}
+	ctx = oteltrace.ContextWithRemoteSpanContext(ctx, sc)
+	return context.WithValue(ctx, loggerSpanContextKey, LoggerSpanContext{
+		span: oteltrace.SpanFromContext(ctx),
+	})
+}
+
 // GenTraceID generate traceID
-// current timestamp with 8 rand bytes
+// 默认使用crypto/rand生成，可通过Config.IDGenerator自定义
 func GenTraceID() string {
-	stime := strconv.FormatInt(time.Now().UnixNano(), 16)
-	paddingLen := 16 - len(stime)
-	for i := 0; i < paddingLen; i++ {
-		stime = stime + "0"
-	}
-	return stime + randString(16)
+	traceID, _ := idGeneratorOf(loadState().config).NewIDs(context.Background())
+	return traceID.String()
 }
 
 // GenSpanID gererate spanID
 func GenSpanID() string {
-	return randString(16)
+	return idGeneratorOf(loadState().config).NewSpanID(context.Background(), oteltrace.TraceID{}).String()
 }
 
 // End end trace
 func End(ctx context.Context) {
+	s := loadState()
 	if err := recover(); err != nil {
-		logger.Error("panic", zap.String("recover", fmt.Sprint(err)), zap.Stack("stack"))
+		s.logger.Error("panic", zap.String("recover", fmt.Sprint(err)), zap.Stack("stack"))
 	}
 	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
 	if !ok {
 		return
 	}
-	if config.EnableTrace {
+	if s.config.EnableTrace {
 		loggerSpanContext.span.End()
 	}
 }
 
+// WithSpan 启动一个span并执行fn，执行完成后自动结束span
+// fn返回的错误会记录到span中，fn发生panic时会被恢复并记录到span中，同时作为错误返回
+//
+// example:
+//
+//	err := WithSpan(ctx, "spanName", func(ctx context.Context) error {
+//		return foo(ctx)
+//	}, String("key", "value"))
+func WithSpan(ctx context.Context, spanName string, fn func(ctx context.Context) error, attributes ...Field) (err error) {
+	s := loadState()
+	spanCtx := Start(ctx, spanName, attributes...)
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			if s.enabled {
+				s.logger.Error("panic", zap.String("recover", fmt.Sprint(r)), zap.Stack("stack"))
+			}
+			recordSpanError(spanCtx, err, oteltrace.WithStackTrace(true))
+		}
+		End(spanCtx)
+	}()
+	if err = fn(spanCtx); err != nil {
+		recordSpanError(spanCtx, err)
+	}
+	return err
+}
+
+// EndWithError end trace
+// err不为nil时，记录错误并将span的状态设置为codes.Error
+//
+// example:
+//
+//	defer func() { EndWithError(ctx, err) }()
+func EndWithError(ctx context.Context, err error) {
+	s := loadState()
+	if r := recover(); r != nil {
+		if s.enabled {
+			s.logger.Error("panic", zap.String("recover", fmt.Sprint(r)), zap.Stack("stack"))
+		}
+		recordSpanError(ctx, fmt.Errorf("panic: %v", r), oteltrace.WithStackTrace(true))
+	}
+	if err != nil {
+		recordSpanError(ctx, err)
+	}
+	End(ctx)
+}
+
+// SetSpanStatus 设置当前span的状态
+func SetSpanStatus(ctx context.Context, code codes.Code, description string) {
+	s := loadState()
+	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
+	if !ok {
+		return
+	}
+	if s.config.EnableTrace {
+		loggerSpanContext.span.SetStatus(code, description)
+	}
+}
+
+// recordSpanError 记录错误到当前span，并将span的状态设置为codes.Error
+func recordSpanError(ctx context.Context, err error, options ...oteltrace.EventOption) {
+	s := loadState()
+	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
+	if !ok {
+		return
+	}
+	if s.config.EnableTrace {
+		loggerSpanContext.span.RecordError(err, options...)
+		loggerSpanContext.span.SetStatus(codes.Error, err.Error())
+	}
+}
+
 // FieldsToZapFields
 func FieldsToZapFields(ctx context.Context, fields ...Field) []zapcore.Field {
-	kvs := []zapcore.Field{}
+	return loadState().zapFields(ctx, fields...)
+}
+
+func (s *logState) zapFields(ctx context.Context, fields ...Field) []zapcore.Field {
+	fields = s.limitFields(fields)
+	// trace_id,span_id,worker_id及trace id格式的字段，err类型的字段额外有_chain,_stack
+	kvs := make([]zapcore.Field, 0, 3+2*len(s.config.TraceIDFormats)+2+fieldsCap(fields, 2))
 	if traceID := TraceID(ctx); traceID != "" {
 		kvs = append(kvs, zap.String("trace_id", traceID))
 	}
 	if spanID := SpanID(ctx); spanID != "" {
 		kvs = append(kvs, zap.String("span_id", spanID))
 	}
+	if workerID := WorkerID(ctx); workerID != "" {
+		kvs = append(kvs, zap.String("worker_id", workerID))
+	}
+	for _, f := range s.traceIDFormatFields(ctx) {
+		kvs = append(kvs, zap.String(f.Key, f.String))
+	}
+	// Namespace之后的字段已嵌套在命名空间的对象中，不再添加前缀
+	nested := false
 	for _, f := range fields {
+		f = s.redactField(f)
+		key := f.Key
+		if !nested {
+			key = namespaceKey(s.config.AttributeNamespace, f.Key)
+		}
 		switch f.Type {
 		case boolType:
-			kvs = append(kvs, zap.Bool(f.Key, f.Bool))
+			kvs = append(kvs, zap.Bool(key, f.Bool))
 		case boolSliceType:
-			kvs = append(kvs, zap.Bools(f.Key, f.Bools))
+			kvs = append(kvs, zap.Bools(key, f.Bools))
 		case intType:
-			kvs = append(kvs, zap.Int(f.Key, f.Integer))
+			kvs = append(kvs, zap.Int(key, f.Integer))
 		case intSliceType:
-			kvs = append(kvs, zap.Ints(f.Key, f.Integers))
+			kvs = append(kvs, zap.Ints(key, f.Integers))
 		case int64Type:
-			kvs = append(kvs, zap.Int64(f.Key, f.Integer64))
+			kvs = append(kvs, zap.Int64(key, f.Integer64))
 		case int64SliceType:
-			kvs = append(kvs, zap.Int64s(f.Key, f.Integer64s))
+			kvs = append(kvs, zap.Int64s(key, f.Integer64s))
 		case float64Type:
-			kvs = append(kvs, zap.Float64(f.Key, f.Float64))
+			kvs = append(kvs, zap.Float64(key, f.Float64))
 		case float64SliceType:
-			kvs = append(kvs, zap.Float64s(f.Key, f.Float64s))
+			kvs = append(kvs, zap.Float64s(key, f.Float64s))
 		case stringType:
-			kvs = append(kvs, zap.String(f.Key, f.String))
+			kvs = append(kvs, zap.String(key, f.String))
 		case stringSliceType:
-			kvs = append(kvs, zap.Strings(f.Key, f.Strings))
+			kvs = append(kvs, zap.Strings(key, f.Strings))
 		case stringerType:
-			kvs = append(kvs, zap.String(f.Key, f.String))
+			kvs = append(kvs, zap.String(key, f.String))
 		case anyType:
-			kvs = append(kvs, zap.Any(f.Key, f.Any))
+			kvs = append(kvs, zap.Any(key, f.Any))
+		case uintType:
+			kvs = append(kvs, zap.Uint(key, f.Uinteger))
+		case uint64Type:
+			kvs = append(kvs, zap.Uint64(key, f.Uinteger64))
+		case float32Type:
+			kvs = append(kvs, zap.Float32(key, f.Float32))
+		case durationType:
+			kvs = append(kvs, zap.Duration(key, f.Duration))
+		case timeType:
+			kvs = append(kvs, zap.Time(key, f.Time))
+		case byteStringType:
+			kvs = append(kvs, zap.ByteString(key, f.Bytes))
+		case binaryType:
+			kvs = append(kvs, zap.Binary(key, f.Bytes))
+		case grpcStatusType:
+			kvs = append(kvs, zap.Int(f.Key, f.Integer))
+		case objectType:
+			kvs = append(kvs, zap.Object(key, f.Any.(ObjectMarshaler)))
+		case arrayType:
+			kvs = append(kvs, zap.Array(key, f.Any.(ArrayMarshaler)))
+		case namespaceType:
+			kvs = append(kvs, zap.Namespace(key))
+			nested = true
+		case errType:
+			kvs = append(kvs, zap.String(key, f.Err.Error()))
+			if chain := errorChain(f.Err); len(chain) > 1 {
+				kvs = append(kvs, zap.Strings(key+"_chain", chain))
+			}
+			if f.Stack != "" {
+				kvs = append(kvs, zap.String(key+"_stack", f.Stack))
+			}
 		}
 	}
 	return kvs
 }
 
-// RandString 生成随机字符串
-func randString(len int) string {
-	bytes := []byte{}
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	seed := "0123456789abcdef"
-	for i := 0; i < len; i++ {
-		bytes = append(bytes, seed[r.Intn(15)])
+// lokiTimeout 推送到loki的超时时间
+const lokiTimeout = 3 * time.Second
+
+// lokiPush 异步推送日志到loki
+func lokiPush(ctx context.Context, level, msg string, attributes ...Field) {
+	jsonBytes := lokiBody(ctx, level, msg, attributes)
+	if jsonBytes == nil {
+		return
 	}
-	return string(bytes)
+	go lokiPost(jsonBytes)
 }
 
-func lokiPush(ctx context.Context, level, msg string, attributes ...Field) {
-	if reqClient == nil {
+// lokiPushSync 同步推送日志到loki，最多等待lokiTimeout
+// 用于Fatal等即将退出进程的场景，避免日志随进程退出而丢失
+func lokiPushSync(ctx context.Context, level, msg string, attributes ...Field) {
+	jsonBytes := lokiBody(ctx, level, msg, attributes)
+	if jsonBytes == nil {
 		return
 	}
+	lokiPost(jsonBytes)
+}
+
+func lokiPost(jsonBytes []byte) {
+	s := loadState()
+	reqCtx, reqCancel := context.WithTimeout(context.Background(), lokiTimeout)
+	defer reqCancel()
+	s.reqClient.
+		R().
+		SetContext(reqCtx).
+		SetHeader("content-type", "application/json").
+		SetBodyJsonBytes(jsonBytes).
+		Post(s.config.LokiServer)
+}
+
+// lokiBody 生成loki push接口的请求体，需由lokiPush或lokiPushSync直接调用以获取正确的caller
+func lokiBody(ctx context.Context, level, msg string, attributes []Field) []byte {
+	s := loadState()
+	if s.reqClient == nil {
+		return nil
+	}
+	if len(s.fields) > 0 {
+		attributes = append(s.fields[:len(s.fields):len(s.fields)], attributes...)
+	}
 	var kv = map[string]interface{}{}
 	// 日志等级
 	kv["level"] = level
@@ -413,59 +1233,88 @@ func lokiPush(ctx context.Context, level, msg string, attributes ...Field) {
 	if spanID := SpanID(ctx); spanID != "" {
 		kv["span_id"] = spanID
 	}
+	if workerID := WorkerID(ctx); workerID != "" {
+		kv["worker_id"] = workerID
+	}
+	for _, f := range s.traceIDFormatFields(ctx) {
+		kv[f.Key] = f.String
+	}
+	attributes = s.limitFields(attributes)
+	group := ""
 	for _, attr := range attributes {
+		attr = s.redactField(attr)
+		key := namespaceKey(s.config.AttributeNamespace, group+attr.Key)
 		switch attr.Type {
+		case namespaceType:
+			group += attr.Key + "."
 		case boolType:
-			kv[attr.Key] = attr.Bool
+			kv[key] = attr.Bool
 		case boolSliceType:
-			kv[attr.Key] = attr.Bools
+			kv[key] = attr.Bools
 		case intType:
-			kv[attr.Key] = attr.Integer
+			kv[key] = attr.Integer
 		case intSliceType:
-			kv[attr.Key] = attr.Integers
+			kv[key] = attr.Integers
 		case int64Type:
-			kv[attr.Key] = attr.Integer64
+			kv[key] = attr.Integer64
 		case int64SliceType:
-			kv[attr.Key] = attr.Integer64s
+			kv[key] = attr.Integer64s
 		case float64Type:
-			kv[attr.Key] = attr.Float64
+			kv[key] = attr.Float64
 		case float64SliceType:
-			kv[attr.Key] = attr.Float64s
+			kv[key] = attr.Float64s
 		case stringType:
-			kv[attr.Key] = attr.String
+			kv[key] = attr.String
 		case stringSliceType:
-			kv[attr.Key] = attr.Strings
+			kv[key] = attr.Strings
 		case stringerType:
-			kv[attr.Key] = attr.String
+			kv[key] = attr.String
 		case anyType:
-			kv[attr.Key] = attr.Any
+			kv[key] = attr.Any
+		case uintType:
+			kv[key] = attr.Uinteger
+		case uint64Type:
+			kv[key] = attr.Uinteger64
+		case float32Type:
+			kv[key] = attr.Float32
+		case durationType:
+			kv[key] = attr.Duration.String()
+		case timeType:
+			kv[key] = attr.Time
+		case byteStringType:
+			kv[key] = string(attr.Bytes)
+		case binaryType:
+			kv[key] = attr.Bytes
+		case grpcStatusType:
+			kv[attr.Key] = attr.Integer
+		case objectType, arrayType:
+			kv[key] = marshalerValue(attr)
+		case errType:
+			kv[key] = attr.Err.Error()
+			if chain := errorChain(attr.Err); len(chain) > 1 {
+				kv[key+"_chain"] = chain
+			}
+			if attr.Stack != "" {
+				kv[key+"_stack"] = attr.Stack
+			}
 		}
 	}
 	// 获取调用堆栈信息
-	_, file, line, ok := runtime.Caller(2)
+	_, file, line, ok := runtime.Caller(3)
 	if !ok {
 		fmt.Println("无法获取调用信息")
-		return
+		return nil
 	}
 	kv["caller"] = fmt.Sprintf("%s:%d", file, line)
 	kvJson, _ := json.Marshal(kv)
 	var data = map[string][]map[string]interface{}{
 		"streams": {
 			{
-				"stream": LokiLabel,
+				"stream": s.lokiLabel,
 				"values": [][]interface{}{{strconv.FormatInt(time.Now().UnixNano(), 10), string(kvJson)}},
 			},
 		},
 	}
 	jsonBytes, _ := json.Marshal(data)
-	go func() {
-		reqCtx, reqCancel := context.WithTimeout(context.Background(), time.Second*3)
-		defer reqCancel()
-		reqClient.
-			R().
-			SetContext(reqCtx).
-			SetHeader("content-type", "application/json").
-			SetBodyJsonBytes(jsonBytes).
-			Post(config.LokiServer)
-	}()
+	return jsonBytes
 }
@@ -0,0 +1,146 @@
+//go:build !logx_disable
+
+package logx
+
+import (
+	"context"
+	"fmt"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// DebugEnabled Debug日志是否编译，使用logx_disable构建标签时为false
+// 可以用于包裹构建开销较大的日志，由编译器直接消除
+//
+// example:
+//
+//	if DebugEnabled {
+//		Debug(ctx, "msg", Any("payload", payload))
+//	}
+const DebugEnabled = true
+
+// InfoEnabled Info日志是否编译，使用logx_disable构建标签时为false
+const InfoEnabled = true
+
+// Debug record debug
+func Debug(ctx context.Context, msg string, attributes ...Field) {
+	s := loadState()
+	if !enabled(ctx, zap.DebugLevel) {
+		return
+	}
+	attributes = withContextFields(ctx, attributes)
+	msg, attributes, ok := hookEntry(ctx, "debug", msg, attributes)
+	if !ok {
+		return
+	}
+	if s.enabled {
+		if ce := levelLogger(ctx, zap.DebugLevel).Check(zap.DebugLevel, msg); ce != nil {
+			ce.Write(s.zapFields(ctx, attributes...)...)
+		} else if s.config.ErrorBuffer > 0 {
+			bufferEntry(ctx, zap.DebugLevel, msg, attributes)
+		}
+	}
+	if s.config.LokiServer != "" {
+		lokiPush(ctx, "debug", msg, attributes...)
+	}
+	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
+	if !ok {
+		return
+	}
+	if s.config.EnableTrace {
+		loggerSpanContext.span.AddEvent(msg, oteltrace.WithAttributes(FieldsToKeyValues(attributes...)...))
+	}
+}
+
+// Info record info
+func Info(ctx context.Context, msg string, attributes ...Field) {
+	s := loadState()
+	if !enabled(ctx, zap.InfoLevel) {
+		return
+	}
+	attributes = withContextFields(ctx, attributes)
+	msg, attributes, ok := hookEntry(ctx, "info", msg, attributes)
+	if !ok {
+		return
+	}
+	if s.enabled {
+		if ce := levelLogger(ctx, zap.InfoLevel).Check(zap.InfoLevel, msg); ce != nil {
+			ce.Write(s.zapFields(ctx, attributes...)...)
+		} else if s.config.ErrorBuffer > 0 {
+			bufferEntry(ctx, zap.InfoLevel, msg, attributes)
+		}
+	}
+	if s.config.LokiServer != "" {
+		lokiPush(ctx, "info", msg, attributes...)
+	}
+	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
+	if !ok {
+		return
+	}
+	if s.config.EnableTrace {
+		loggerSpanContext.span.AddEvent(msg, oteltrace.WithAttributes(FieldsToKeyValues(attributes...)...))
+	}
+}
+
+// Debugf record debug with format
+func Debugf(ctx context.Context, format string, args ...interface{}) {
+	s := loadState()
+	if !enabled(ctx, zap.DebugLevel) {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	attributes := contextFields(ctx)
+	msg, attributes, ok := hookEntry(ctx, "debug", msg, attributes)
+	if !ok {
+		return
+	}
+	if s.enabled {
+		if ce := levelLogger(ctx, zap.DebugLevel).Check(zap.DebugLevel, msg); ce != nil {
+			ce.Write(s.zapFields(ctx, attributes...)...)
+		} else if s.config.ErrorBuffer > 0 {
+			bufferEntry(ctx, zap.DebugLevel, msg, attributes)
+		}
+	}
+	if s.config.LokiServer != "" {
+		lokiPush(ctx, "debug", msg, attributes...)
+	}
+	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
+	if !ok {
+		return
+	}
+	if s.config.EnableTrace {
+		loggerSpanContext.span.AddEvent(msg, oteltrace.WithAttributes(FieldsToKeyValues(attributes...)...))
+	}
+}
+
+// Infof record info with format
+func Infof(ctx context.Context, format string, args ...interface{}) {
+	s := loadState()
+	if !enabled(ctx, zap.InfoLevel) {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	attributes := contextFields(ctx)
+	msg, attributes, ok := hookEntry(ctx, "info", msg, attributes)
+	if !ok {
+		return
+	}
+	if s.enabled {
+		if ce := levelLogger(ctx, zap.InfoLevel).Check(zap.InfoLevel, msg); ce != nil {
+			ce.Write(s.zapFields(ctx, attributes...)...)
+		} else if s.config.ErrorBuffer > 0 {
+			bufferEntry(ctx, zap.InfoLevel, msg, attributes)
+		}
+	}
+	if s.config.LokiServer != "" {
+		lokiPush(ctx, "info", msg, attributes...)
+	}
+	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
+	if !ok {
+		return
+	}
+	if s.config.EnableTrace {
+		loggerSpanContext.span.AddEvent(msg, oteltrace.WithAttributes(FieldsToKeyValues(attributes...)...))
+	}
+}
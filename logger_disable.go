@@ -0,0 +1,25 @@
+//go:build logx_disable
+
+package logx
+
+import "context"
+
+// 使用logx_disable构建标签时，Debug和Info为空实现，仅保留Warn,Error,Fatal
+
+// DebugEnabled Debug日志是否编译，使用logx_disable构建标签时为false
+const DebugEnabled = false
+
+// InfoEnabled Info日志是否编译，使用logx_disable构建标签时为false
+const InfoEnabled = false
+
+// Debug record debug, no-op under logx_disable
+func Debug(ctx context.Context, msg string, attributes ...Field) {}
+
+// Info record info, no-op under logx_disable
+func Info(ctx context.Context, msg string, attributes ...Field) {}
+
+// Debugf record debug with format, no-op under logx_disable
+func Debugf(ctx context.Context, format string, args ...interface{}) {}
+
+// Infof record info with format, no-op under logx_disable
+func Infof(ctx context.Context, format string, args ...interface{}) {}
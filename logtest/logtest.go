@@ -0,0 +1,116 @@
+// Package logtest 在单元测试中记录logx的日志及span，用于断言日志及追踪的行为
+//
+// logx的配置为全局的，使用logtest的测试不能并行执行
+//
+// example:
+//
+//	func TestCreateUser(t *testing.T) {
+//		obs := logtest.New(t)
+//		CreateUser(ctx, "alice")
+//		assert.False(t, obs.HasError("create user failed"))
+//		assert.Len(t, obs.SpansNamed("CreateUser"), 1)
+//	}
+package logtest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/itmisx/logx"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// Entry 记录的日志
+type Entry struct {
+	Level   string
+	Message string
+	// 日志的字段，包括trace_id,span_id及WithFields保存的字段
+	Fields map[string]interface{}
+}
+
+// Observer 记录的日志及span
+type Observer struct {
+	logs  *observer.ObservedLogs
+	spans *tracetest.SpanRecorder
+}
+
+// New 以debug等级初始化logx，日志及span记录在内存中，全部采样，不写入文件，span名称不添加时间
+// 测试结束时关闭tracerProvider，并将logx恢复为不输出
+func New(t testing.TB, options ...logx.Option) *Observer {
+	t.Helper()
+	core, logs := observer.New(zap.DebugLevel)
+	spans := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sdktrace.AlwaysSample()),
+		sdktrace.WithSpanProcessor(spans),
+	)
+	options = append(options, logx.WithZapCore(core), logx.WithTracerProvider(provider))
+	// span名称不添加时间，便于SpansNamed匹配
+	logx.InitWithOptions(logx.Config{Level: "debug", EnableTrace: true, SpanNameTimeFormat: "none"}, t.Name(), options...)
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		logx.Init(logx.Config{}, "")
+	})
+	return &Observer{logs: logs, spans: spans}
+}
+
+// Entries 记录的所有日志
+func (o *Observer) Entries() []Entry {
+	logged := o.logs.All()
+	entries := make([]Entry, 0, len(logged))
+	for _, e := range logged {
+		entries = append(entries, Entry{Level: e.Level.String(), Message: e.Message, Fields: e.ContextMap()})
+	}
+	return entries
+}
+
+// EntriesAt level等级的日志，level为debug,info,warn,error等
+func (o *Observer) EntriesAt(level string) []Entry {
+	var entries []Entry
+	for _, e := range o.Entries() {
+		if e.Level == level {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Has 是否记录了level等级且内容为msg的日志
+func (o *Observer) Has(level, msg string) bool {
+	for _, e := range o.EntriesAt(level) {
+		if e.Message == msg {
+			return true
+		}
+	}
+	return false
+}
+
+// HasError 是否记录了内容为msg的error日志
+func (o *Observer) HasError(msg string) bool {
+	return o.Has("error", msg)
+}
+
+// Spans 已结束的span
+func (o *Observer) Spans() []sdktrace.ReadOnlySpan {
+	return o.spans.Ended()
+}
+
+// SpansNamed 名称为name的已结束的span
+func (o *Observer) SpansNamed(name string) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range o.spans.Ended() {
+		if span.Name() == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// Reset 清除记录的日志及span
+func (o *Observer) Reset() {
+	o.logs.TakeAll()
+	o.spans.Reset()
+}
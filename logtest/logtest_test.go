@@ -0,0 +1,37 @@
+package logtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObserver(t *testing.T) {
+	obs := New(t)
+	ctx := logx.Start(context.Background(), "create user")
+	logx.Debug(ctx, "validate user", logx.String("name", "alice"))
+	logx.Error(ctx, "create user failed", logx.Err(errors.New("duplicate")))
+	logx.End(ctx)
+
+	entries := obs.Entries()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, "debug", entries[0].Level)
+	assert.Equal(t, "alice", entries[0].Fields["name"])
+	assert.Equal(t, logx.TraceID(ctx), entries[0].Fields["trace_id"])
+	assert.True(t, obs.HasError("create user failed"))
+	assert.False(t, obs.HasError("validate user"))
+	assert.True(t, obs.Has("debug", "validate user"))
+	assert.Len(t, obs.EntriesAt("error"), 1)
+
+	spans := obs.SpansNamed("create user")
+	assert.Len(t, spans, 1)
+	assert.Len(t, spans[0].Events(), 2)
+	assert.Len(t, obs.SpansNamed("other"), 0)
+
+	obs.Reset()
+	assert.Len(t, obs.Entries(), 0)
+	assert.Len(t, obs.Spans(), 0)
+}
@@ -0,0 +1,174 @@
+package logx
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.uber.org/zap/zapcore"
+)
+
+// ObjectMarshaler 与zap相同，自行编码为对象的类型，无需反射或json序列化
+//
+// example:
+//
+//	func (u User) MarshalLogObject(enc logx.ObjectEncoder) error {
+//		enc.AddString("name", u.Name)
+//		enc.AddInt("age", u.Age)
+//		return nil
+//	}
+type ObjectMarshaler = zapcore.ObjectMarshaler
+
+// ArrayMarshaler 与zap相同，自行编码为数组的类型
+type ArrayMarshaler = zapcore.ArrayMarshaler
+
+// ObjectEncoder ObjectMarshaler使用的编码器
+type ObjectEncoder = zapcore.ObjectEncoder
+
+// ArrayEncoder ArrayMarshaler使用的编码器
+type ArrayEncoder = zapcore.ArrayEncoder
+
+// Object 日志中输出为嵌套的对象，span中展开为key.字段名的属性，如user.name,user.age
+func Object(key string, val ObjectMarshaler) Field {
+	return Field{Key: key, Type: objectType, Any: val}
+}
+
+// Array 日志中输出为数组，span中元素类型相同时为对应类型的数组属性，否则为json字符串
+func Array(key string, val ArrayMarshaler) Field {
+	return Field{Key: key, Type: arrayType, Any: val}
+}
+
+// marshalerValue 将Object,Array编码为map[string]interface{}或[]interface{}
+func marshalerValue(f Field) interface{} {
+	enc := zapcore.NewMapObjectEncoder()
+	var err error
+	switch f.Type {
+	case objectType:
+		err = enc.AddObject(f.Key, f.Any.(ObjectMarshaler))
+	case arrayType:
+		err = enc.AddArray(f.Key, f.Any.(ArrayMarshaler))
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return enc.Fields[f.Key]
+}
+
+// marshalerKeyValues Object展开为key.字段名的属性，Array转换为数组属性
+func marshalerKeyValues(key string, value interface{}, kvs []attribute.KeyValue) []attribute.KeyValue {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			kvs = marshalerKeyValues(key+"."+k, v[k], kvs)
+		}
+		return kvs
+	case []interface{}:
+		return append(kvs, arrayAttribute(key, v))
+	}
+	return append(kvs, valueAttribute(key, value))
+}
+
+// valueAttribute MapObjectEncoder编码后的基本类型转换为属性
+func valueAttribute(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case bool:
+		return attribute.Bool(key, v)
+	case string:
+		return attribute.String(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int8:
+		return attribute.Int64(key, int64(v))
+	case int16:
+		return attribute.Int64(key, int64(v))
+	case int32:
+		return attribute.Int64(key, int64(v))
+	case int64:
+		return attribute.Int64(key, v)
+	case uint:
+		return uint64Attribute(key, uint64(v))
+	case uint8:
+		return attribute.Int64(key, int64(v))
+	case uint16:
+		return attribute.Int64(key, int64(v))
+	case uint32:
+		return attribute.Int64(key, int64(v))
+	case uint64:
+		return uint64Attribute(key, v)
+	case uintptr:
+		return uint64Attribute(key, uint64(v))
+	case float32:
+		return attribute.Float64(key, float64(v))
+	case float64:
+		return attribute.Float64(key, v)
+	case time.Duration:
+		return attribute.String(key, v.String())
+	case time.Time:
+		return attribute.String(key, v.Format(time.RFC3339Nano))
+	case []byte:
+		return attribute.String(key, base64.StdEncoding.EncodeToString(v))
+	case fmt.Stringer:
+		return attribute.String(key, v.String())
+	}
+	if str, err := json.Marshal(value); err == nil {
+		return attribute.String(key, string(str))
+	}
+	return attribute.String(key, fmt.Sprint(value))
+}
+
+// arrayAttribute 元素均为string,bool,整数或浮点数时转换为对应的数组属性，否则为json字符串
+func arrayAttribute(key string, values []interface{}) attribute.KeyValue {
+	if len(values) > 0 {
+		switch values[0].(type) {
+		case string:
+			strs := make([]string, 0, len(values))
+			for _, v := range values {
+				if s, ok := v.(string); ok {
+					strs = append(strs, s)
+				}
+			}
+			if len(strs) == len(values) {
+				return attribute.StringSlice(key, strs)
+			}
+		case bool:
+			bools := make([]bool, 0, len(values))
+			for _, v := range values {
+				if b, ok := v.(bool); ok {
+					bools = append(bools, b)
+				}
+			}
+			if len(bools) == len(values) {
+				return attribute.BoolSlice(key, bools)
+			}
+		case float32, float64:
+			floats := make([]float64, 0, len(values))
+			for _, v := range values {
+				if kv := valueAttribute(key, v); kv.Value.Type() == attribute.FLOAT64 {
+					floats = append(floats, kv.Value.AsFloat64())
+				}
+			}
+			if len(floats) == len(values) {
+				return attribute.Float64Slice(key, floats)
+			}
+		default:
+			ints := make([]int64, 0, len(values))
+			for _, v := range values {
+				if kv := valueAttribute(key, v); kv.Value.Type() == attribute.INT64 {
+					ints = append(ints, kv.Value.AsInt64())
+				}
+			}
+			if len(ints) == len(values) {
+				return attribute.Int64Slice(key, ints)
+			}
+		}
+	}
+	return valueAttribute(key, values)
+}
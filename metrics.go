@@ -0,0 +1,181 @@
+package logx
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.uber.org/zap/zapcore"
+)
+
+// meter logx的meter，InitMetrics之前使用全局的MeterProvider
+var meter = otel.Meter(scopeName)
+
+// instruments Counter,Histogram创建的instrument，按名称缓存
+var instruments sync.Map
+
+// metricsRegistration 内置指标的回调，重复InitMetrics时注销
+var metricsRegistration metric.Registration
+
+// meterProvider InitMetrics创建的MeterProvider，重复InitMetrics及Shutdown时关闭
+var meterProvider *sdkmetric.MeterProvider
+
+// InitMetrics 创建OTLP的指标exporter，设置为otel全局的MeterProvider并注册内置的指标
+// 使用conf中的OTLPEndpoint,OLTPInsecure,OTLPToken,ExportRetry,OTLPMetricsURLPath,MetricsInterval
+// 资源属性为Init的serviceName及options中WithResource的属性，WithMeterProvider指定时不创建exporter
+//
+// 内置的指标：
+//
+//	logx.log.entries 各等级的日志数量，属性level
+//	logx.spans.started,logx.spans.ended,logx.spans.dropped 启动、结束并采样、未采样的span数量
+//	logx.spans.exported,logx.spans.failed 导出成功、失败的span数量
+//
+// example:
+//
+//	logx.Init(conf, "order-service")
+//	logx.InitMetrics(conf)
+func InitMetrics(conf Config, options ...Option) error {
+	var o initOptions
+	for _, opt := range options {
+		opt.apply(&o)
+	}
+	provider := o.meter
+	var created *sdkmetric.MeterProvider
+	if provider == nil {
+		mp, err := newOTLPMeterProvider(conf, initArgs.serviceName, o.resource...)
+		if err != nil {
+			return err
+		}
+		provider, created = mp, mp
+	}
+	if meterProvider != nil {
+		meterProvider.Shutdown(context.Background())
+	}
+	meterProvider = created
+	otel.SetMeterProvider(provider)
+	meter = provider.Meter(scopeName)
+	instruments.Clear()
+	if metricsRegistration != nil {
+		metricsRegistration.Unregister()
+		metricsRegistration = nil
+	}
+	entries, err := meter.Int64ObservableCounter("logx.log.entries", metric.WithDescription("log entries per level"))
+	if err != nil {
+		return err
+	}
+	counters := map[string]func() int64{
+		"logx.spans.started":  stats.spansStarted.Load,
+		"logx.spans.ended":    stats.spansEnded.Load,
+		"logx.spans.dropped":  stats.spansDropped.Load,
+		"logx.spans.exported": stats.spansExported.Load,
+		"logx.spans.failed":   stats.spansFailed.Load,
+	}
+	observables := []metric.Observable{entries}
+	spans := map[metric.Int64ObservableCounter]func() int64{}
+	for name, load := range counters {
+		counter, err := meter.Int64ObservableCounter(name)
+		if err != nil {
+			return err
+		}
+		observables = append(observables, counter)
+		spans[counter] = load
+	}
+	metricsRegistration, err = meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		for i := range stats.entries {
+			level := (zapcore.DebugLevel + zapcore.Level(i)).String()
+			o.ObserveInt64(entries, stats.entries[i].Load(), metric.WithAttributes(attribute.String("level", level)))
+		}
+		for counter, load := range spans {
+			o.ObserveInt64(counter, load())
+		}
+		return nil
+	}, observables...)
+	return err
+}
+
+// newOTLPMeterProvider 按间隔通过OTLP/HTTP导出指标的MeterProvider
+func newOTLPMeterProvider(conf Config, serviceName string, attributes ...Field) (*sdkmetric.MeterProvider, error) {
+	var options []otlpmetrichttp.Option
+	if conf.OTLPEndpoint != "" {
+		options = append(options, otlpmetrichttp.WithEndpoint(conf.OTLPEndpoint))
+	}
+	if conf.OTLPMetricsURLPath != "" {
+		options = append(options, otlpmetrichttp.WithURLPath(conf.OTLPMetricsURLPath))
+	}
+	if conf.OLTPInsecure {
+		options = append(options, otlpmetrichttp.WithInsecure())
+	}
+	if conf.ExportRetry > 0 {
+		options = append(options, otlpmetrichttp.WithRetry(otlpmetrichttp.RetryConfig{
+			Enabled:         true,
+			InitialInterval: 5 * time.Second,
+			MaxInterval:     30 * time.Second,
+			MaxElapsedTime:  conf.ExportRetry,
+		}))
+	}
+	if conf.OTLPToken != "" {
+		options = append(options, otlpmetrichttp.WithHeaders(map[string]string{
+			"Authorization": "Basic " + conf.OTLPToken,
+		}))
+	}
+	exporter, err := otlpmetrichttp.New(context.Background(), options...)
+	if err != nil {
+		return nil, err
+	}
+	interval := conf.MetricsInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	if serviceName != "" {
+		attributes = append(attributes, String("service.name", serviceName))
+	}
+	return sdkmetric.NewMeterProvider(
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter, sdkmetric.WithInterval(interval))),
+		sdkmetric.WithResource(resource.NewWithAttributes(semconv.SchemaURL, loadState().fieldsToKeyValues("", attributes...)...)),
+	), nil
+}
+
+// Counter 累加计数器，ctx中的span可作为exemplar关联到trace
+//
+// example:
+// logx.Counter(ctx, "order.created", 1, logx.String("channel", "app"))
+func Counter(ctx context.Context, name string, incr int64, attributes ...Field) {
+	instrument, ok := instruments.Load(name)
+	if !ok {
+		counter, err := meter.Int64Counter(name)
+		if err != nil {
+			otel.Handle(err)
+			return
+		}
+		instrument, _ = instruments.LoadOrStore(name, counter)
+	}
+	if counter, ok := instrument.(metric.Int64Counter); ok {
+		counter.Add(ctx, incr, metric.WithAttributes(FieldsToKeyValues(attributes...)...))
+	}
+}
+
+// Histogram 记录分布，如耗时、大小等，ctx中的span可作为exemplar关联到trace
+//
+// example:
+// logx.Histogram(ctx, "order.amount", 99.5, logx.String("currency", "CNY"))
+func Histogram(ctx context.Context, name string, value float64, attributes ...Field) {
+	instrument, ok := instruments.Load(name)
+	if !ok {
+		histogram, err := meter.Float64Histogram(name)
+		if err != nil {
+			otel.Handle(err)
+			return
+		}
+		instrument, _ = instruments.LoadOrStore(name, histogram)
+	}
+	if histogram, ok := instrument.(metric.Float64Histogram); ok {
+		histogram.Record(ctx, value, metric.WithAttributes(FieldsToKeyValues(attributes...)...))
+	}
+}
@@ -0,0 +1,32 @@
+// Package chi traces the requests of chi, with the options of
+// github.com/itmisx/logx/propagation/extract.
+package chi
+
+import (
+	"net/http"
+
+	chiv5 "github.com/go-chi/chi/v5"
+	"github.com/itmisx/logx/propagation/extract"
+)
+
+// Middleware returns chi middleware that will trace incoming requests.
+// The service parameter should describe the name of the (virtual)
+// server handling the request.
+//
+// example:
+//
+//	router.Use(chi.Middleware("service", extract.WithSkipPaths("/health")))
+func Middleware(service string, opts ...extract.Option) func(http.Handler) http.Handler {
+	return extract.NewServer(service, opts...).HTTPMiddleware(route)
+}
+
+// route returns the route matched by chi, or the pattern of http.ServeMux
+// when chi did not route the request.
+func route(r *http.Request) string {
+	if rctx := chiv5.RouteContext(r.Context()); rctx != nil {
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			return pattern
+		}
+	}
+	return r.Pattern
+}
@@ -0,0 +1,53 @@
+package chi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	chiv5 "github.com/go-chi/chi/v5"
+	"github.com/itmisx/logx/propagation/extract"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	var accessRoute string
+	var accessStatus int
+	router := chiv5.NewRouter()
+	router.Use(Middleware("foobar",
+		extract.WithTracerProvider(provider),
+		extract.WithSkipPaths("/health"),
+		extract.WithRequestAccessHandler(func(r *http.Request, route string, status int, latency time.Duration) {
+			accessRoute, accessStatus = route, status
+		}),
+	))
+	router.Get("/user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, oteltrace.SpanFromContext(r.Context()).SpanContext().IsValid())
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	router.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		assert.False(t, oteltrace.SpanFromContext(r.Context()).SpanContext().IsValid())
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/1", nil))
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "/user/{id}", spans[0].Name())
+	assert.Equal(t, oteltrace.SpanKindServer, spans[0].SpanKind())
+	assert.Contains(t, spans[0].Attributes(), attribute.Int("http.status_code", http.StatusServiceUnavailable))
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "/user/{id}", accessRoute)
+	assert.Equal(t, http.StatusServiceUnavailable, accessStatus)
+
+	// 跳过的路径不记录span
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	assert.Len(t, recorder.Ended(), 1)
+}
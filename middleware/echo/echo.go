@@ -0,0 +1,50 @@
+// Package echo traces the requests of echo, with the options of
+// github.com/itmisx/logx/propagation/extract.
+package echo
+
+import (
+	"time"
+
+	"github.com/itmisx/logx/propagation/extract"
+	echov4 "github.com/labstack/echo/v4"
+)
+
+// Middleware returns echo middleware that will trace incoming requests.
+// The service parameter should describe the name of the (virtual)
+// server handling the request.
+//
+// example:
+//
+//	e.Use(echo.Middleware("service", extract.WithSkipPaths("/health")))
+func Middleware(service string, opts ...extract.Option) echov4.MiddlewareFunc {
+	server := extract.NewServer(service, opts...)
+	return func(next echov4.HandlerFunc) echov4.HandlerFunc {
+		return func(c echov4.Context) error {
+			request := c.Request()
+			if server.Skip(request.URL.Path) {
+				return next(c)
+			}
+			savedCtx := request.Context()
+			defer func() {
+				c.SetRequest(c.Request().WithContext(savedCtx))
+			}()
+			ctx, span := server.Start(savedCtx, request, c.Path())
+			// pass the span through the request context
+			c.SetRequest(request.WithContext(ctx))
+			if span == nil {
+				return next(c)
+			}
+
+			// serve the request to the next middleware
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				span.RecordError(err)
+				// let the error handler write the response, so the status is known
+				c.Error(err)
+			}
+			server.End(span, c.Request(), c.Path(), c.Response().Status, time.Since(start))
+			return nil
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package echo
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/itmisx/logx/propagation/extract"
+	echov4 "github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	var accessRoute string
+	var accessStatus int
+	e := echov4.New()
+	e.Use(Middleware("foobar",
+		extract.WithTracerProvider(provider),
+		extract.WithSkipPaths("/health"),
+		extract.WithRequestAccessHandler(func(r *http.Request, route string, status int, latency time.Duration) {
+			accessRoute, accessStatus = route, status
+		}),
+	))
+	e.GET("/user/:id", func(c echov4.Context) error {
+		assert.True(t, oteltrace.SpanFromContext(c.Request().Context()).SpanContext().IsValid())
+		return echov4.NewHTTPError(http.StatusServiceUnavailable)
+	})
+	e.GET("/health", func(c echov4.Context) error {
+		assert.False(t, oteltrace.SpanFromContext(c.Request().Context()).SpanContext().IsValid())
+		return c.NoContent(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/user/1", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "/user/:id", spans[0].Name())
+	assert.Equal(t, oteltrace.SpanKindServer, spans[0].SpanKind())
+	assert.Contains(t, spans[0].Attributes(), attribute.Int("http.status_code", http.StatusServiceUnavailable))
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "/user/:id", accessRoute)
+	assert.Equal(t, http.StatusServiceUnavailable, accessStatus)
+
+	// 跳过的路径不记录span
+	e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	assert.Len(t, recorder.Ended(), 1)
+}
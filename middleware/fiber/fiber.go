@@ -0,0 +1,57 @@
+// Package fiber traces the requests of fiber, with the options of
+// github.com/itmisx/logx/propagation/extract.
+package fiber
+
+import (
+	"net/http"
+	"time"
+
+	fiberv2 "github.com/gofiber/fiber/v2"
+	"github.com/itmisx/logx/propagation/extract"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+)
+
+// Middleware returns fiber middleware that will trace incoming requests.
+// The service parameter should describe the name of the (virtual)
+// server handling the request.
+//
+// The span is passed through c.UserContext(). The request passed to the
+// handlers of the options is converted from fasthttp.
+//
+// example:
+//
+//	app.Use(fiber.Middleware("service", extract.WithSkipPaths("/health")))
+func Middleware(service string, opts ...extract.Option) fiberv2.Handler {
+	server := extract.NewServer(service, opts...)
+	return func(c *fiberv2.Ctx) error {
+		if server.Skip(c.Path()) {
+			return c.Next()
+		}
+		var request http.Request
+		if err := fasthttpadaptor.ConvertRequest(c.Context(), &request, true); err != nil {
+			return c.Next()
+		}
+		savedCtx := c.UserContext()
+		defer c.SetUserContext(savedCtx)
+		// the route is matched in c.Next, the span is renamed afterwards
+		ctx, span := server.Start(savedCtx, &request, "")
+		// pass the span through the user context
+		c.SetUserContext(ctx)
+		if span == nil {
+			return c.Next()
+		}
+
+		// serve the request to the next middleware
+		start := time.Now()
+		err := c.Next()
+		if err != nil {
+			span.RecordError(err)
+			// let the error handler write the response, so the status is known
+			if handlerErr := c.App().ErrorHandler(c, err); handlerErr != nil {
+				_ = c.SendStatus(fiberv2.StatusInternalServerError)
+			}
+		}
+		server.End(span, request.WithContext(ctx), c.Route().Path, c.Response().StatusCode(), time.Since(start))
+		return nil
+	}
+}
@@ -0,0 +1,58 @@
+package fiber
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	fiberv2 "github.com/gofiber/fiber/v2"
+	"github.com/itmisx/logx/propagation/extract"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	var accessRoute string
+	var accessStatus int
+	app := fiberv2.New()
+	app.Use(Middleware("foobar",
+		extract.WithTracerProvider(provider),
+		extract.WithSkipPaths("/health"),
+		extract.WithRequestAccessHandler(func(r *http.Request, route string, status int, latency time.Duration) {
+			accessRoute, accessStatus = route, status
+		}),
+	))
+	app.Get("/user/:id", func(c *fiberv2.Ctx) error {
+		assert.True(t, oteltrace.SpanFromContext(c.UserContext()).SpanContext().IsValid())
+		return fiberv2.ErrServiceUnavailable
+	})
+	app.Get("/health", func(c *fiberv2.Ctx) error {
+		assert.False(t, oteltrace.SpanFromContext(c.UserContext()).SpanContext().IsValid())
+		return c.SendStatus(http.StatusOK)
+	})
+	response, err := app.Test(httptest.NewRequest("GET", "/user/1", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "/user/:id", spans[0].Name())
+	assert.Equal(t, oteltrace.SpanKindServer, spans[0].SpanKind())
+	assert.Contains(t, spans[0].Attributes(), attribute.Int("http.status_code", http.StatusServiceUnavailable))
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "/user/:id", accessRoute)
+	assert.Equal(t, http.StatusServiceUnavailable, accessStatus)
+
+	// 跳过的路径不记录span
+	response, err = app.Test(httptest.NewRequest("GET", "/health", nil))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Len(t, recorder.Ended(), 1)
+}
@@ -0,0 +1,116 @@
+package logx
+
+import (
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap/zapcore"
+)
+
+// initOptions Init的可选项
+type initOptions struct {
+	resource   []Field
+	zapCores   []zapcore.Core
+	sampler    sdktrace.Sampler
+	propagator propagation.TextMapPropagator
+	provider   *sdktrace.TracerProvider
+	meter      metric.MeterProvider
+	kafka      KafkaWriter
+}
+
+// Option InitWithOptions的可选项
+//
+// Field也实现了Option，作为应用属性，等同于WithResource
+type Option interface {
+	apply(*initOptions)
+}
+
+type optionFunc func(*initOptions)
+
+func (o optionFunc) apply(o2 *initOptions) {
+	o(o2)
+}
+
+func (f Field) apply(o *initOptions) {
+	o.resource = append(o.resource, f)
+}
+
+// WithResource 设置应用属性，如service.version等，用于追踪的resource及loki的label
+func WithResource(attributes ...Field) Option {
+	return optionFunc(func(o *initOptions) {
+		o.resource = append(o.resource, attributes...)
+	})
+}
+
+// WithZapCore 添加自定义的zap core，与默认的输出同时生效
+// Output为none时，仅输出到自定义的core
+func WithZapCore(core zapcore.Core) Option {
+	return optionFunc(func(o *initOptions) {
+		if core != nil {
+			o.zapCores = append(o.zapCores, core)
+		}
+	})
+}
+
+// WithSampler 使用自定义的采样策略替代TraceSampleRatio
+//
+// example:
+//
+//	WithSampler(SamplerFunc(func(p SamplingParameters) bool {
+//		return p.ParentSampled || time.Now().Hour() < 8
+//	}))
+func WithSampler(sampler Sampler) Option {
+	return optionFunc(func(o *initOptions) {
+		if sampler != nil {
+			o.sampler = samplerAdapter{sampler: sampler}
+		}
+	})
+}
+
+// WithOTelSampler 使用otel的采样器替代TraceSampleRatio，如sdktrace.ParentBased
+func WithOTelSampler(sampler sdktrace.Sampler) Option {
+	return optionFunc(func(o *initOptions) {
+		if sampler != nil {
+			o.sampler = sampler
+		}
+	})
+}
+
+// WithTracerProvider 开启EnableTrace时使用该tracerProvider，不再按TracerProviderType创建
+// 用于测试时通过tracetest.SpanRecorder记录span，或使用自行配置的exporter
+func WithTracerProvider(provider *sdktrace.TracerProvider) Option {
+	return optionFunc(func(o *initOptions) {
+		if provider != nil {
+			o.provider = provider
+		}
+	})
+}
+
+// WithMeterProvider InitMetrics使用指定的MeterProvider，不创建OTLP的exporter
+// 用于测试或应用已自行配置的MeterProvider，如prometheus exporter
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return optionFunc(func(o *initOptions) {
+		if provider != nil {
+			o.meter = provider
+		}
+	})
+}
+
+// WithKafkaWriter Output为kafka时使用指定的writer发送，忽略KafkaBrokers,KafkaTopic,KafkaCompression
+// 用于需要SASL,TLS等自行配置的*kafka.Writer
+func WithKafkaWriter(writer KafkaWriter) Option {
+	return optionFunc(func(o *initOptions) {
+		if writer != nil {
+			o.kafka = writer
+		}
+	})
+}
+
+// WithPropagator 使用自定义的propagator替代默认的b3
+func WithPropagator(propagator propagation.TextMapPropagator) Option {
+	return optionFunc(func(o *initOptions) {
+		if propagator != nil {
+			o.propagator = propagator
+		}
+	})
+}
@@ -0,0 +1,208 @@
+package logx
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// partitionPattern 日志文件路径中的分区字段，如./logs/{tenant}/run.log
+var partitionPattern = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// 缺少分区字段时使用的值
+const defaultPartition = "default"
+
+// partitionKey 返回日志文件路径中的分区字段，不分区时返回空
+// {service}及日期模板不是分区字段
+func partitionKey(file string) string {
+	for _, m := range partitionPattern.FindAllStringSubmatch(file, -1) {
+		if !isFileTemplate(m[0]) {
+			return m[1]
+		}
+	}
+	return ""
+}
+
+// partitionFiles 按分区字段的值打开的日志文件，超过max时关闭最久未使用的
+type partitionFiles struct {
+	mu      sync.Mutex
+	conf    Config
+	service string
+	max     int
+	files   map[string]*list.Element
+	lru     *list.List
+}
+
+type partitionFile struct {
+	value  string
+	writer *rotateWriter
+}
+
+// newPartitionFiles max默认为64
+func newPartitionFiles(conf Config, service string) *partitionFiles {
+	max := conf.PartitionMaxFiles
+	if max <= 0 {
+		max = 64
+	}
+	return &partitionFiles{
+		conf:    conf,
+		service: service,
+		max:     max,
+		files:   map[string]*list.Element{},
+		lru:     list.New(),
+	}
+}
+
+// get 获取分区对应的日志文件，共用切割的配置
+func (p *partitionFiles) get(value string) *rotateWriter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if e, ok := p.files[value]; ok {
+		p.lru.MoveToFront(e)
+		return e.Value.(*partitionFile).writer
+	}
+	if p.lru.Len() >= p.max {
+		oldest := p.lru.Back()
+		file := oldest.Value.(*partitionFile)
+		file.writer.Close()
+		p.lru.Remove(oldest)
+		delete(p.files, file.value)
+	}
+	conf := p.conf
+	conf.File = partitionPattern.ReplaceAllLiteralString(conf.File, value)
+	writer := newRotateWriter(&lumberjack.Logger{
+		Filename:   conf.File,
+		MaxSize:    conf.MaxSize,
+		MaxBackups: conf.MaxBackups,
+		MaxAge:     conf.MaxAge,
+		LocalTime:  true,
+		Compress:   lumberjackCompress(conf),
+	}, conf, p.service)
+	p.files[value] = p.lru.PushFront(&partitionFile{value: value, writer: writer})
+	return writer
+}
+
+// Rotate 切割所有打开的日志文件
+func (p *partitionFiles) Rotate() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var err error
+	for e := p.lru.Front(); e != nil; e = e.Next() {
+		if e2 := e.Value.(*partitionFile).writer.Rotate(); e2 != nil {
+			err = e2
+		}
+	}
+	return err
+}
+
+// Reopen 重新打开所有打开的日志文件
+func (p *partitionFiles) Reopen() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var err error
+	for e := p.lru.Front(); e != nil; e = e.Next() {
+		if e2 := e.Value.(*partitionFile).writer.Reopen(); e2 != nil {
+			err = e2
+		}
+	}
+	return err
+}
+
+// Close 关闭所有打开的日志文件
+func (p *partitionFiles) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var errs []error
+	for e := p.lru.Front(); e != nil; e = e.Next() {
+		errs = append(errs, e.Value.(*partitionFile).writer.Close())
+	}
+	p.files = map[string]*list.Element{}
+	p.lru.Init()
+	return errors.Join(errs...)
+}
+
+// partitionCore 按字段的值将日志写入不同的文件
+type partitionCore struct {
+	zapcore.LevelEnabler
+	enc   zapcore.Encoder
+	key   string
+	value string
+	files *partitionFiles
+}
+
+func newPartitionCore(enc zapcore.Encoder, level zapcore.LevelEnabler, key string, files *partitionFiles) zapcore.Core {
+	return partitionCore{LevelEnabler: level, enc: enc, key: key, files: files}
+}
+
+func (c partitionCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := c
+	clone.enc = c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(clone.enc)
+		if f.Key == c.key {
+			clone.value = partitionValue(f)
+		}
+	}
+	return clone
+}
+
+// Check 直接写入分区文件，没有内层core，与zapcore.NewCore相同，配置ErrorFile时LevelEnabler为levelRange
+func (c partitionCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c partitionCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	value := c.value
+	for _, f := range fields {
+		if f.Key == c.key {
+			value = partitionValue(f)
+		}
+	}
+	if value == "" {
+		value = defaultPartition
+	}
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+	n, err := c.files.get(value).Write(buf.Bytes())
+	stats.bytesWritten.Add(int64(n))
+	if err != nil {
+		stats.writeErrors.Add(1)
+	}
+	return err
+}
+
+func (c partitionCore) Sync() error {
+	return nil
+}
+
+// partitionValue 字段的值，去除路径分隔符避免写入其他目录
+func partitionValue(f zapcore.Field) string {
+	var value string
+	switch f.Type {
+	case zapcore.StringType:
+		value = f.String
+	case zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type:
+		value = strconv.FormatInt(f.Integer, 10)
+	case zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type:
+		value = strconv.FormatUint(uint64(f.Integer), 10)
+	case zapcore.StringerType:
+		value = f.Interface.(fmt.Stringer).String()
+	default:
+		return ""
+	}
+	value = strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(value)
+	return value
+}
@@ -0,0 +1,41 @@
+package logx
+
+// DevelopmentConfig 本地开发的配置
+// 控制台输出便于阅读的彩色文本，debug等级，全部采样，追踪写入当前目录的trace.txt
+//
+// example:
+//
+//	logx.Init(logx.DevelopmentConfig(), "service1")
+func DevelopmentConfig() Config {
+	return Config{
+		Output:             "console",
+		Encoder:            "console",
+		Color:              true,
+		Level:              "debug",
+		StacktraceLevel:    "error",
+		EnableTrace:        true,
+		TracerProviderType: "file",
+		Sampler:            "always",
+	}
+}
+
+// ProductionConfig 生产环境的配置
+// 控制台输出json，info等级，通过OTLP导出追踪，跟随上级span的采样结果，否则按10%采样
+// OTLP的地址默认取环境变量OTEL_EXPORTER_OTLP_ENDPOINT，也可以修改返回的配置
+//
+// example:
+//
+//	conf := logx.ProductionConfig()
+//	conf.OTLPEndpoint = "collector:4318"
+//	logx.Init(conf, "service1")
+func ProductionConfig() Config {
+	return Config{
+		Output:             "console",
+		Encoder:            "json",
+		Level:              "info",
+		EnableTrace:        true,
+		TracerProviderType: "oltp",
+		Sampler:            "parentbased_ratio",
+		TraceSampleRatio:   0.1,
+	}
+}
@@ -0,0 +1,51 @@
+package logx
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector 返回prometheus.Collector，每次采集时读取当前的统计
+//
+//	logx_log_entries_total 各等级的日志数量，标签level
+//	logx_log_dropped_total 丢弃的日志数量，标签reason为write_error(写入失败)或sampled(LogSampling丢弃)
+//	logx_log_rotations_total 日志文件切割的次数
+//	logx_spans_active 已启动未结束的span数量
+//	logx_spans_started_total,logx_spans_exported_total,logx_spans_failed_total span的启动、导出成功、失败数量
+//
+// example:
+// prometheus.MustRegister(logx.Collector())
+func Collector() prometheus.Collector {
+	return logxCollector{}
+}
+
+var (
+	entriesDesc   = prometheus.NewDesc("logx_log_entries_total", "Log entries per level.", []string{"level"}, nil)
+	droppedDesc   = prometheus.NewDesc("logx_log_dropped_total", "Log entries dropped, by reason.", []string{"reason"}, nil)
+	rotationsDesc = prometheus.NewDesc("logx_log_rotations_total", "Log file rotations.", nil, nil)
+	activeDesc    = prometheus.NewDesc("logx_spans_active", "Spans started and not yet ended.", nil, nil)
+	startedDesc   = prometheus.NewDesc("logx_spans_started_total", "Spans started.", nil, nil)
+	exportedDesc  = prometheus.NewDesc("logx_spans_exported_total", "Spans exported.", nil, nil)
+	failedDesc    = prometheus.NewDesc("logx_spans_failed_total", "Spans failed to export.", nil, nil)
+)
+
+type logxCollector struct{}
+
+func (logxCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{entriesDesc, droppedDesc, rotationsDesc, activeDesc, startedDesc, exportedDesc, failedDesc} {
+		ch <- desc
+	}
+}
+
+func (logxCollector) Collect(ch chan<- prometheus.Metric) {
+	summary := currentSummary()
+	for level, n := range summary.Entries {
+		ch <- prometheus.MustNewConstMetric(entriesDesc, prometheus.CounterValue, float64(n), level)
+	}
+	ch <- prometheus.MustNewConstMetric(droppedDesc, prometheus.CounterValue, float64(summary.WriteErrors), "write_error")
+	ch <- prometheus.MustNewConstMetric(droppedDesc, prometheus.CounterValue, float64(summary.EntriesSampled), "sampled")
+	ch <- prometheus.MustNewConstMetric(rotationsDesc, prometheus.CounterValue, float64(summary.Rotations))
+	ch <- prometheus.MustNewConstMetric(activeDesc, prometheus.GaugeValue, float64(summary.ActiveSpans))
+	ch <- prometheus.MustNewConstMetric(startedDesc, prometheus.CounterValue, float64(summary.SpansStarted))
+	ch <- prometheus.MustNewConstMetric(exportedDesc, prometheus.CounterValue, float64(summary.SpansExported))
+	ch <- prometheus.MustNewConstMetric(failedDesc, prometheus.CounterValue, float64(summary.SpansFailed))
+}
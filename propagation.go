@@ -2,19 +2,183 @@ package logx
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/itmisx/logx/propagation/extract"
 	"github.com/itmisx/logx/propagation/inject"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 // HTTPInject inject spanContext
+// 同时转发context中保存的PropagationHeaders
+// 使用Init时选择的propagator，不会修改otel全局的propagator
 func HttpInject(ctx context.Context, request *http.Request) error {
-	return inject.HttpInject(ctx, request)
+	return HttpInjectWithPropagator(ctx, request, propagatorOf())
+}
+
+// HttpInjectWithPropagator 同HttpInject，使用指定的propagator，如只向某个下游传递b3的header
+//
+// example:
+//
+//	HttpInjectWithPropagator(ctx, request, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader)))
+func HttpInjectWithPropagator(ctx context.Context, request *http.Request, propagator propagation.TextMapPropagator) error {
+	if err := inject.HttpInjectWithPropagator(ctx, request, propagator); err != nil {
+		return err
+	}
+	injectTraceIDFormats(ctx, request.Header)
+	if header, ok := ctx.Value(loggerHeaderContextKey).(http.Header); ok {
+		for key, values := range header {
+			if request.Header.Get(key) == "" {
+				request.Header[key] = values
+			}
+		}
+	}
+	return nil
+}
+
+// propagatorOf Init时选择的propagator，未调用Init时为otel全局的propagator
+func propagatorOf() propagation.TextMapPropagator {
+	if p := loadState().propagator; p != nil {
+		return p
+	}
+	return otel.GetTextMapPropagator()
+}
+
+// Inject 返回包含追踪信息的map，用于通过任意方式传递追踪信息，如NATS,RabbitMQ,任务队列等
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	propagatorOf().Inject(ctx, carrier)
+	return carrier
+}
+
+// Extract 从Inject返回的map中解析追踪信息
+// 返回的context记录的日志附带上游的traceID，Start启动的span为上游的子span
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	ctx = propagatorOf().Extract(ctx, propagation.MapCarrier(carrier))
+	return withRemoteSpanContext(ctx, oteltrace.SpanContextFromContext(ctx))
+}
+
+// HttpExtract 从请求的header中解析追踪信息，并保存PropagationHeaders，基于request.Context()
+// 用于net/http、fasthttp适配等非gin的服务，返回的context记录的日志附带上游的traceID，Start启动的span为上游的子span
+//
+// example:
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//		ctx := logx.StartServer(logx.HttpExtract(r), "GET /users")
+//		defer logx.End(ctx)
+//	}
+func HttpExtract(request *http.Request) context.Context {
+	return extractHTTP(request.Context(), request.Header)
+}
+
+// ExtractHTTP 同HttpExtract，从header中解析，基于context.Background()
+func ExtractHTTP(header http.Header) context.Context {
+	return extractHTTP(context.Background(), header)
+}
+
+// extractHTTP 使用与HttpInject相同的propagator解析
+func extractHTTP(ctx context.Context, header http.Header) context.Context {
+	ctx = propagatorOf().Extract(ctx, propagation.HeaderCarrier(header))
+	ctx = ContextWithHeaders(ctx, header)
+	return withRemoteSpanContext(ctx, oteltrace.SpanContextFromContext(ctx))
 }
 
 // GinMiddleware extract spanContext
-func GinMiddleware(service string) gin.HandlerFunc {
-	return extract.GinMiddleware(service)
+// 同时将请求中的PropagationHeaders保存到context
+//
+// 使用extract.WithDeadlineFloor时，请求方建议的超时时间低于floor会记录一条Warn日志
+//
+// 默认不记录access日志，使用GinAccessLog开启
+// 健康检查等路径可以使用extract.WithSkipPaths跳过
+//
+// example:
+// router.Use(logx.GinMiddleware("service", logx.GinAccessLog(), extract.WithSkipPaths("/health")))
+func GinMiddleware(service string, opts ...extract.Option) gin.HandlerFunc {
+	opts = append([]extract.Option{extract.WithPropagators(propagatorOf()), extract.WithDeadlineHandler(func(c *gin.Context, timeout time.Duration) {
+		Warn(withOTelSpan(c.Request.Context()), "request deadline too short",
+			Bool("deadline_too_short", true),
+			Duration("request_timeout", timeout),
+			String("http.route", c.FullPath()),
+		)
+	})}, opts...)
+	middleware := extract.GinMiddleware(service, opts...)
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(ContextWithHeaders(c.Request.Context(), c.Request.Header))
+		middleware(c)
+	}
+}
+
+// GinRecovery 恢复handler中的panic并返回500，替代gin.Recovery
+// panic及调用堆栈记录为Error日志，并将span状态设置为错误，需注册在GinMiddleware之后
+func GinRecovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				ctx := withOTelSpan(c.Request.Context())
+				err := fmt.Errorf("panic: %v", r)
+				Error(ctx, "panic recovered",
+					ErrStack(err),
+					String("http.method", c.Request.Method),
+					String("http.route", c.FullPath()),
+				)
+				SetSpanStatus(ctx, codes.Error, err.Error())
+				c.AbortWithStatus(http.StatusInternalServerError)
+			}
+		}()
+		c.Next()
+	}
+}
+
+// GinAccessLog GinMiddleware的选项，每个请求记录一条access日志
+// 包括方法、路由、状态码、耗时、客户端IP，状态码为5xx时为Error，4xx时为Warn，其他为Info
+func GinAccessLog() extract.Option {
+	return extract.WithAccessHandler(accessLog)
+}
+
+// accessLog 记录access日志
+func accessLog(c *gin.Context, latency time.Duration) {
+	ctx := withOTelSpan(c.Request.Context())
+	status := c.Writer.Status()
+	fields := []Field{
+		String("http.method", c.Request.Method),
+		String("http.route", c.FullPath()),
+		String("http.target", c.Request.URL.RequestURI()),
+		Int("http.status_code", status),
+		Duration("latency", latency),
+		String("client_ip", c.ClientIP()),
+		String("user_agent", c.Request.UserAgent()),
+	}
+	switch {
+	case status >= 500:
+		Error(ctx, "access", fields...)
+	case status >= 400:
+		Warn(ctx, "access", fields...)
+	default:
+		Info(ctx, "access", fields...)
+	}
+}
+
+// ContextWithHeaders 将header中配置的PropagationHeaders保存到context
+// 非gin的服务可以手动调用
+func ContextWithHeaders(ctx context.Context, header http.Header) context.Context {
+	keys := loadState().config.PropagationHeaders
+	if len(keys) == 0 {
+		return ctx
+	}
+	saved := http.Header{}
+	for _, key := range keys {
+		if values := header.Values(key); len(values) > 0 {
+			saved[http.CanonicalHeaderKey(key)] = values
+		}
+	}
+	if len(saved) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, loggerHeaderContextKey, saved)
 }
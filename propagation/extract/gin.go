@@ -1,14 +1,11 @@
 package extract
 
 import (
-	"fmt"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/propagation"
-	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
-	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 const (
@@ -20,53 +17,63 @@ const (
 // The service parameter should describe the name of the (virtual)
 // server handling the request.
 func GinMiddleware(service string, opts ...Option) gin.HandlerFunc {
-	cfg := config{}
-	for _, opt := range opts {
-		opt.apply(&cfg)
-	}
-	if cfg.TracerProvider == nil {
-		cfg.TracerProvider = otel.GetTracerProvider()
-	}
+	cfg := newConfig(opts)
 	tracer := cfg.TracerProvider.Tracer(
 		tracerName,
 		// oteltrace.WithInstrumentationVersion(SemVersion()),
 	)
-	if cfg.Propagators == nil {
-		cfg.Propagators = otel.GetTextMapPropagator()
-	}
 	return func(c *gin.Context) {
+		if cfg.SkipPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
 		c.Set(tracerKey, tracer)
 		savedCtx := c.Request.Context()
 		defer func() {
 			c.Request = c.Request.WithContext(savedCtx)
 		}()
 		ctx := cfg.Propagators.Extract(savedCtx, propagation.HeaderCarrier(c.Request.Header))
-		opts := []oteltrace.SpanStartOption{
-			oteltrace.WithAttributes(semconv.NetAttributesFromHTTPRequest("tcp", c.Request)...),
-			oteltrace.WithAttributes(semconv.EndUserAttributesFromHTTPRequest(c.Request)...),
-			oteltrace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(service, c.FullPath(), c.Request)...),
-			oteltrace.WithSpanKind(oteltrace.SpanKindServer),
-		}
-		spanName := c.FullPath()
-		if spanName == "" {
-			spanName = fmt.Sprintf("HTTP %s route not found", c.Request.Method)
+		if cfg.ExtractOnly {
+			c.Request = c.Request.WithContext(ctx)
+			c.Next()
+			return
 		}
-		ctx, span := tracer.Start(ctx, spanName, opts...)
+		ctx, span := tracer.Start(ctx, spanName(c.FullPath(), c.Request.Method), spanStartOptions(service, c.FullPath(), c.Request)...)
 		defer span.End()
 
 		// pass the span through the request context
 		c.Request = c.Request.WithContext(ctx)
 
+		span.SetAttributes(attribute.String("http.client_ip", c.ClientIP()))
+
+		// record the timeout proposed by the client
+		if timeout, tooShort := recordTimeout(span, c.Request.Header, cfg); tooShort {
+			if cfg.DeadlineHandler != nil {
+				cfg.DeadlineHandler(c, timeout)
+			}
+			if cfg.RequestDeadlineHandler != nil {
+				cfg.RequestDeadlineHandler(c.Request, timeout)
+			}
+		}
+
 		// serve the request to the next middleware
+		start := time.Now()
 		c.Next()
+		latency := time.Since(start)
 
 		status := c.Writer.Status()
-		attrs := semconv.HTTPAttributesFromHTTPStatusCode(status)
-		spanStatus, spanMessage := semconv.SpanStatusFromHTTPStatusCode(status)
-		span.SetAttributes(attrs...)
-		span.SetStatus(spanStatus, spanMessage)
+		setSpanStatus(span, status, cfg)
 		if len(c.Errors) > 0 {
 			span.SetAttributes(attribute.String("gin.errors", c.Errors.String()))
 		}
+		if status >= 400 && cfg.StatusHandler != nil {
+			cfg.StatusHandler(c, status)
+		}
+		if cfg.AccessHandler != nil {
+			cfg.AccessHandler(c, latency)
+		}
+		if cfg.RequestAccessHandler != nil {
+			cfg.RequestAccessHandler(c.Request, c.FullPath(), status, latency)
+		}
 	}
 }
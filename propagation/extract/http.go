@@ -0,0 +1,65 @@
+package extract
+
+import (
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// HTTPMiddleware returns net/http middleware that will trace incoming
+// requests, e.g. for http.ServeMux. The service parameter should describe
+// the name of the (virtual) server handling the request.
+//
+// The route is read from the pattern of http.ServeMux, for chi use
+// github.com/itmisx/logx/middleware/chi.
+func HTTPMiddleware(service string, opts ...Option) func(http.Handler) http.Handler {
+	return NewServer(service, opts...).HTTPMiddleware(func(r *http.Request) string { return r.Pattern })
+}
+
+// HTTPMiddleware returns net/http middleware tracing the requests, route
+// returns the route matched by next.
+func (s *Server) HTTPMiddleware(route func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if s.Skip(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			// the route is matched by next, the span is renamed afterwards
+			ctx, span := s.Start(r.Context(), r, route(r))
+			r = r.WithContext(ctx)
+			if span == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// serve the request to the next handler
+			rw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(rw, r)
+			s.End(span, r, route(r), rw.status, time.Since(start))
+		})
+	}
+}
+
+// semconvRoute returns the http.route attribute.
+func semconvRoute(route string) attribute.KeyValue {
+	return attribute.String("http.route", route)
+}
+
+// statusWriter records the status written by the handler.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap supports http.ResponseController.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
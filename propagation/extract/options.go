@@ -1,13 +1,27 @@
 package extract
 
 import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/otel/propagation"
 	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 type config struct {
-	TracerProvider oteltrace.TracerProvider
-	Propagators    propagation.TextMapPropagator
+	TracerProvider  oteltrace.TracerProvider
+	Propagators     propagation.TextMapPropagator
+	DeadlineFloor   time.Duration
+	DeadlineHandler func(c *gin.Context, timeout time.Duration)
+	ClientErrors    bool
+	StatusHandler   func(c *gin.Context, status int)
+	ExtractOnly     bool
+	SkipPaths       map[string]bool
+	AccessHandler   func(c *gin.Context, latency time.Duration)
+
+	RequestDeadlineHandler func(r *http.Request, timeout time.Duration)
+	RequestAccessHandler   func(r *http.Request, route string, status int, latency time.Duration)
 }
 
 // Option specifies instrumentation configuration options.
@@ -41,3 +55,100 @@ func WithTracerProvider(provider oteltrace.TracerProvider) Option {
 		}
 	})
 }
+
+// WithDeadlineFloor records the timeout proposed by the client through the
+// grpc-timeout or X-Request-Timeout headers. When the timeout is below floor
+// the span is marked with deadline_too_short=true and the deadline handler,
+// if any, is called.
+func WithDeadlineFloor(floor time.Duration) Option {
+	return optionFunc(func(cfg *config) {
+		cfg.DeadlineFloor = floor
+	})
+}
+
+// WithDeadlineHandler specifies the handler called when the timeout proposed
+// by the client is below the floor set by WithDeadlineFloor. It is only
+// called by GinMiddleware, see WithRequestDeadlineHandler.
+func WithDeadlineHandler(handler func(c *gin.Context, timeout time.Duration)) Option {
+	return optionFunc(func(cfg *config) {
+		if handler != nil {
+			cfg.DeadlineHandler = handler
+		}
+	})
+}
+
+// WithClientErrors marks the span status as Error for 4xx responses too.
+// By default only 5xx responses are errors, as 4xx are caused by the client.
+func WithClientErrors() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ClientErrors = true
+	})
+}
+
+// WithStatusHandler specifies the handler called after the request is
+// served when the response status is 4xx or 5xx.
+func WithStatusHandler(handler func(c *gin.Context, status int)) Option {
+	return optionFunc(func(cfg *config) {
+		if handler != nil {
+			cfg.StatusHandler = handler
+		}
+	})
+}
+
+// WithExtractOnly only extracts the span context from the request, without
+// starting a server span. Spans started by the handlers are children of the
+// span of the client.
+func WithExtractOnly() Option {
+	return optionFunc(func(cfg *config) {
+		cfg.ExtractOnly = true
+	})
+}
+
+// WithSkipPaths skips the requests to paths, e.g. health checks. The
+// requests are neither extracted nor traced.
+func WithSkipPaths(paths ...string) Option {
+	return optionFunc(func(cfg *config) {
+		if cfg.SkipPaths == nil {
+			cfg.SkipPaths = map[string]bool{}
+		}
+		for _, path := range paths {
+			cfg.SkipPaths[path] = true
+		}
+	})
+}
+
+// WithAccessHandler specifies the handler called after the request is
+// served, e.g. to write an access log. The request context still carries
+// the server span. It is only called by GinMiddleware, see
+// WithRequestAccessHandler.
+func WithAccessHandler(handler func(c *gin.Context, latency time.Duration)) Option {
+	return optionFunc(func(cfg *config) {
+		if handler != nil {
+			cfg.AccessHandler = handler
+		}
+	})
+}
+
+// WithRequestDeadlineHandler is WithDeadlineHandler for all the middlewares,
+// including the ones of echo, fiber and chi under
+// github.com/itmisx/logx/middleware. The request context carries the server
+// span.
+func WithRequestDeadlineHandler(handler func(r *http.Request, timeout time.Duration)) Option {
+	return optionFunc(func(cfg *config) {
+		if handler != nil {
+			cfg.RequestDeadlineHandler = handler
+		}
+	})
+}
+
+// WithRequestAccessHandler is WithAccessHandler for all the middlewares,
+// including the ones of echo, fiber and chi under
+// github.com/itmisx/logx/middleware. The route is empty when no route
+// matched. The request context carries the server span.
+func WithRequestAccessHandler(handler func(r *http.Request, route string, status int, latency time.Duration)) Option {
+	return optionFunc(func(cfg *config) {
+		if handler != nil {
+			cfg.RequestAccessHandler = handler
+		}
+	})
+}
@@ -0,0 +1,135 @@
+package extract
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// serverTracerName is the tracer used by the middlewares other than gin.
+const serverTracerName = "github.com/itmisx/logx/propagation/extract"
+
+// newConfig applies the options and fills in the global provider and
+// propagators.
+func newConfig(opts []Option) config {
+	cfg := config{}
+	for _, opt := range opts {
+		opt.apply(&cfg)
+	}
+	if cfg.TracerProvider == nil {
+		cfg.TracerProvider = otel.GetTracerProvider()
+	}
+	if cfg.Propagators == nil {
+		cfg.Propagators = otel.GetTextMapPropagator()
+	}
+	return cfg
+}
+
+// Server traces the requests of a framework. It is used by HTTPMiddleware
+// and the middlewares of echo, fiber and chi under
+// github.com/itmisx/logx/middleware, which share the options of
+// GinMiddleware.
+type Server struct {
+	service string
+	cfg     config
+	tracer  oteltrace.Tracer
+}
+
+// NewServer returns the Server of service with the options applied.
+func NewServer(service string, opts ...Option) *Server {
+	cfg := newConfig(opts)
+	return &Server{service: service, cfg: cfg, tracer: cfg.TracerProvider.Tracer(serverTracerName)}
+}
+
+// Skip reports whether the requests to path are skipped by WithSkipPaths.
+// Skipped requests are neither extracted nor traced.
+func (s *Server) Skip(path string) bool {
+	return s.cfg.SkipPaths[path]
+}
+
+// Start extracts the span context from the headers of r into ctx and,
+// unless WithExtractOnly is set, starts the server span. The route may be
+// empty when it is only matched after serving. The returned span is nil
+// when only extracting.
+func (s *Server) Start(ctx context.Context, r *http.Request, route string) (context.Context, oteltrace.Span) {
+	ctx = s.cfg.Propagators.Extract(ctx, propagation.HeaderCarrier(r.Header))
+	if s.cfg.ExtractOnly {
+		return ctx, nil
+	}
+	ctx, span := s.tracer.Start(ctx, spanName(route, r.Method), spanStartOptions(s.service, route, r)...)
+	if timeout, tooShort := recordTimeout(span, r.Header, s.cfg); tooShort && s.cfg.RequestDeadlineHandler != nil {
+		s.cfg.RequestDeadlineHandler(r.WithContext(ctx), timeout)
+	}
+	return ctx, span
+}
+
+// End records the matched route and the response status on the span
+// returned by Start, calls the access handler and ends the span. r should
+// carry the context returned by Start. It does nothing when span is nil.
+func (s *Server) End(span oteltrace.Span, r *http.Request, route string, status int, latency time.Duration) {
+	if span == nil {
+		return
+	}
+	defer span.End()
+	if route != "" {
+		span.SetName(route)
+		span.SetAttributes(semconvRoute(route))
+	}
+	setSpanStatus(span, status, s.cfg)
+	if s.cfg.RequestAccessHandler != nil {
+		s.cfg.RequestAccessHandler(r, route, status, latency)
+	}
+}
+
+// spanStartOptions returns the attributes of a server span for r.
+func spanStartOptions(service, route string, r *http.Request) []oteltrace.SpanStartOption {
+	return []oteltrace.SpanStartOption{
+		oteltrace.WithAttributes(semconv.NetAttributesFromHTTPRequest("tcp", r)...),
+		oteltrace.WithAttributes(semconv.EndUserAttributesFromHTTPRequest(r)...),
+		oteltrace.WithAttributes(semconv.HTTPServerAttributesFromHTTPRequest(service, route, r)...),
+		oteltrace.WithSpanKind(oteltrace.SpanKindServer),
+	}
+}
+
+// spanName returns the route, or a placeholder when no route matched.
+func spanName(route, method string) string {
+	if route == "" {
+		return fmt.Sprintf("HTTP %s route not found", method)
+	}
+	return route
+}
+
+// recordTimeout records the timeout proposed by the client on the span and
+// reports whether it is below the deadline floor.
+func recordTimeout(span oteltrace.Span, header http.Header, cfg config) (timeout time.Duration, tooShort bool) {
+	timeout, ok := requestTimeout(header)
+	if !ok {
+		return 0, false
+	}
+	span.SetAttributes(attribute.Int64("http.request.timeout_ms", timeout.Milliseconds()))
+	if timeout < cfg.DeadlineFloor {
+		span.SetAttributes(attribute.Bool("deadline_too_short", true))
+		return timeout, true
+	}
+	return timeout, false
+}
+
+// setSpanStatus records the response status on the span. 4xx are only
+// errors when WithClientErrors is set.
+func setSpanStatus(span oteltrace.Span, status int, cfg config) {
+	spanStatus, spanMessage := semconv.SpanStatusFromHTTPStatusCode(status)
+	// 4xx are caused by the client, not errors of the server
+	if status >= 400 && status < 500 && !cfg.ClientErrors {
+		spanStatus, spanMessage = codes.Unset, ""
+	}
+	span.SetAttributes(semconv.HTTPAttributesFromHTTPStatusCode(status)...)
+	span.SetStatus(spanStatus, spanMessage)
+}
@@ -0,0 +1,54 @@
+package extract
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// requestTimeout returns the timeout proposed by the client.
+// grpc-timeout uses the gRPC wire format, e.g. 100m, 1S.
+// X-Request-Timeout accepts a Go duration, e.g. 500ms, or plain milliseconds.
+func requestTimeout(header http.Header) (time.Duration, bool) {
+	if value := header.Get("grpc-timeout"); value != "" {
+		return parseGRPCTimeout(value)
+	}
+	if value := header.Get("X-Request-Timeout"); value != "" {
+		if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return time.Duration(ms) * time.Millisecond, true
+		}
+		if timeout, err := time.ParseDuration(value); err == nil {
+			return timeout, true
+		}
+	}
+	return 0, false
+}
+
+// parseGRPCTimeout parses the grpc-timeout header, <digits><unit>
+func parseGRPCTimeout(value string) (time.Duration, bool) {
+	if len(value) < 2 {
+		return 0, false
+	}
+	var unit time.Duration
+	switch value[len(value)-1] {
+	case 'H':
+		unit = time.Hour
+	case 'M':
+		unit = time.Minute
+	case 'S':
+		unit = time.Second
+	case 'm':
+		unit = time.Millisecond
+	case 'u':
+		unit = time.Microsecond
+	case 'n':
+		unit = time.Nanosecond
+	default:
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/itmisx/logx/propagation/extract"
 	"github.com/stretchr/testify/assert"
@@ -12,7 +14,10 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.opentelemetry.io/contrib/propagators/b3"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/trace"
 	oteltrace "go.opentelemetry.io/otel/trace"
 )
@@ -65,3 +70,52 @@ func TestPropagationWithGlobalPropagators(t *testing.T) {
 
 	router.ServeHTTP(w, r)
 }
+
+func TestDeadlineFloor(t *testing.T) {
+	var timeouts []time.Duration
+	router := gin.New()
+	router.Use(extract.GinMiddleware("foobar",
+		extract.WithDeadlineFloor(time.Second),
+		extract.WithDeadlineHandler(func(c *gin.Context, timeout time.Duration) {
+			timeouts = append(timeouts, timeout)
+		}),
+	))
+	router.GET("/ping", func(c *gin.Context) {})
+
+	for _, header := range [][2]string{
+		{"grpc-timeout", "100m"},
+		{"grpc-timeout", "2S"},
+		{"X-Request-Timeout", "500"},
+		{"X-Request-Timeout", "1500ms"},
+	} {
+		r := httptest.NewRequest("GET", "/ping", nil)
+		r.Header.Set(header[0], header[1])
+		router.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	assert.Equal(t, []time.Duration{100 * time.Millisecond, 500 * time.Millisecond}, timeouts)
+}
+
+func TestSpanStatus(t *testing.T) {
+	var statuses []int
+	handler := extract.WithStatusHandler(func(c *gin.Context, status int) {
+		statuses = append(statuses, status)
+		span := oteltrace.SpanFromContext(c.Request.Context()).(sdktrace.ReadOnlySpan)
+		assert.Contains(t, span.Attributes(), attribute.Int("http.status_code", status))
+		if status >= 500 {
+			assert.Equal(t, codes.Error, span.Status().Code)
+		} else {
+			assert.Equal(t, codes.Unset, span.Status().Code)
+		}
+	})
+	router := gin.New()
+	router.Use(extract.GinMiddleware("foobar", extract.WithTracerProvider(sdktrace.NewTracerProvider()), handler))
+	router.GET("/status/:code", func(c *gin.Context) {
+		code, _ := strconv.Atoi(c.Param("code"))
+		c.Status(code)
+	})
+
+	for _, path := range []string{"/status/200", "/status/404", "/status/503"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	assert.Equal(t, []int{404, 503}, statuses)
+}
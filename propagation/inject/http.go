@@ -4,13 +4,17 @@ import (
 	"context"
 	"net/http"
 
-	b3prop "go.opentelemetry.io/contrib/propagators/b3"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
 )
 
+// HttpInject 使用otel全局的propagator将追踪信息注入到请求的header，不修改全局的propagator
 func HttpInject(ctx context.Context, request *http.Request) error {
-	otel.SetTextMapPropagator(b3prop.New())
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
+	return HttpInjectWithPropagator(ctx, request, otel.GetTextMapPropagator())
+}
+
+// HttpInjectWithPropagator 使用指定的propagator将追踪信息注入到请求的header
+func HttpInjectWithPropagator(ctx context.Context, request *http.Request, propagator propagation.TextMapPropagator) error {
+	propagator.Inject(ctx, propagation.HeaderCarrier(request.Header))
 	return nil
 }
@@ -0,0 +1,68 @@
+package propagation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/itmisx/logx/propagation/extract"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func assertServerSpan(t *testing.T, recorder *tracetest.SpanRecorder, route string, status int) {
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	span := spans[0]
+	assert.Equal(t, route, span.Name())
+	assert.Equal(t, oteltrace.SpanKindServer, span.SpanKind())
+	assert.Contains(t, span.Attributes(), attribute.Int("http.status_code", status))
+	assert.Contains(t, span.Attributes(), attribute.String("http.method", "GET"))
+	assert.Equal(t, codes.Error, span.Status().Code)
+}
+
+func TestHTTPMiddleware(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /user/{id}", func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, oteltrace.SpanFromContext(r.Context()).SpanContext().IsValid())
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	var accessRoute string
+	var accessStatus int
+	handler := extract.HTTPMiddleware("foobar",
+		extract.WithTracerProvider(provider),
+		extract.WithSkipPaths("/health"),
+		extract.WithRequestAccessHandler(func(r *http.Request, route string, status int, latency time.Duration) {
+			assert.True(t, oteltrace.SpanFromContext(r.Context()).SpanContext().IsValid())
+			accessRoute, accessStatus = route, status
+		}),
+	)(mux)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/user/1", nil))
+	assertServerSpan(t, recorder, "GET /user/{id}", http.StatusServiceUnavailable)
+	assert.Equal(t, "GET /user/{id}", accessRoute)
+	assert.Equal(t, http.StatusServiceUnavailable, accessStatus)
+
+	// 跳过的路径不记录span
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+	assert.Len(t, recorder.Ended(), 1)
+}
+
+func TestHTTPMiddlewareDeadline(t *testing.T) {
+	var timeout time.Duration
+	handler := extract.HTTPMiddleware("foobar",
+		extract.WithTracerProvider(sdktrace.NewTracerProvider()),
+		extract.WithDeadlineFloor(time.Second),
+		extract.WithRequestDeadlineHandler(func(r *http.Request, t time.Duration) { timeout = t }),
+	)(http.NotFoundHandler())
+	request := httptest.NewRequest("GET", "/user/1", nil)
+	request.Header.Set("X-Request-Timeout", "100")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	assert.Equal(t, 100*time.Millisecond, timeout)
+}
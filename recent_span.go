@@ -0,0 +1,99 @@
+package logx
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// recentSpans 最近结束的span，未开启RecentSpans时为nil
+var recentSpans *recentSpanProcessor
+
+// recentSpanProcessor 使用环形缓冲保存最近结束的span（包括未采样的）
+type recentSpanProcessor struct {
+	mu       sync.Mutex
+	spans    []sdktrace.ReadOnlySpan
+	next     int
+	exporter sdktrace.SpanExporter
+}
+
+func newRecentSpanProcessor(size int, exporter sdktrace.SpanExporter) *recentSpanProcessor {
+	return &recentSpanProcessor{
+		spans:    make([]sdktrace.ReadOnlySpan, size),
+		exporter: exporter,
+	}
+}
+
+func (p *recentSpanProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {}
+
+func (p *recentSpanProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.spans[p.next] = s
+	p.next = (p.next + 1) % len(p.spans)
+}
+
+func (p *recentSpanProcessor) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func (p *recentSpanProcessor) ForceFlush(ctx context.Context) error {
+	return nil
+}
+
+// take 取出traceID对应的未采样的span
+func (p *recentSpanProcessor) take(traceID oteltrace.TraceID) []sdktrace.ReadOnlySpan {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var spans []sdktrace.ReadOnlySpan
+	for i, s := range p.spans {
+		if s == nil || s.SpanContext().TraceID() != traceID {
+			continue
+		}
+		if !s.SpanContext().IsSampled() {
+			spans = append(spans, s)
+		}
+		p.spans[i] = nil
+	}
+	return spans
+}
+
+// recordOnlySampler 将base不采样的span改为仅记录，以便保存到recentSpans
+type recordOnlySampler struct {
+	base sdktrace.Sampler
+}
+
+func (s recordOnlySampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.base.ShouldSample(p)
+	if result.Decision == sdktrace.Drop {
+		result.Decision = sdktrace.RecordOnly
+	}
+	return result
+}
+
+func (s recordOnlySampler) Description() string {
+	return "RecordOnlySampler{" + s.base.Description() + "}"
+}
+
+// FlushTrace 导出最近结束的span中属于traceID的未采样的span
+// 需配置RecentSpans，用于在请求结束后补充采集该请求的追踪信息
+func FlushTrace(traceID string) error {
+	if recentSpans == nil {
+		return errors.New("recent spans not enabled")
+	}
+	tID, err := oteltrace.TraceIDFromHex(traceID)
+	if err != nil {
+		return errors.New("invalid traceID")
+	}
+	spans := recentSpans.take(tID)
+	if len(spans) == 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	return recentSpans.exporter.ExportSpans(ctx, spans)
+}
@@ -0,0 +1,37 @@
+package logx
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// initMu 保证Init,Reconfigure依次执行
+var initMu sync.Mutex
+
+// Reconfigure 运行时以新的配置重新初始化，serviceName及Option与最近一次Init相同
+// 配置校验失败时返回错误且不做修改；成功时替换logger及tracerProvider，并刷新之前的日志，之前的tracerProvider在后台导出剩余的span后关闭
+//
+// example:
+//
+//	conf.Output = "file"
+//	if err := logx.Reconfigure(conf); err != nil {
+//		logx.Error(ctx, "reconfigure log failed", logx.Err(err))
+//	}
+func Reconfigure(conf Config) error {
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+	initMu.Lock()
+	previous := initLocked(conf, initArgs.serviceName, initArgs.options...)
+	initMu.Unlock()
+	// 关闭之前的tracerProvider，导出剩余的span
+	if previousProvider := previous.provider; previousProvider != nil && previousProvider != loadState().provider {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			previousProvider.Shutdown(ctx)
+		}()
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package logx
+
+import (
+	"log"
+	"regexp"
+	"strings"
+)
+
+// redactedValue 脱敏后的值
+const redactedValue = "******"
+
+// redactPatterns RedactPatterns中可直接使用的内置规则
+var redactPatterns = map[string]string{
+	"email":       `[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`,
+	"phone":       `(?:\+\d{1,3}[ \-]?)?\b1[3-9]\d{9}\b`,
+	"credit_card": `\b\d{4}[ \-]?\d{4}[ \-]?\d{4}[ \-]?\d{1,7}\b`,
+}
+
+type fieldRedactor struct {
+	keys     map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// newRedactor keys不区分大小写，patterns为正则或内置规则的名称，无效的正则会被忽略
+func newRedactor(keys, patterns []string) *fieldRedactor {
+	if len(keys) == 0 && len(patterns) == 0 {
+		return nil
+	}
+	r := &fieldRedactor{keys: map[string]bool{}}
+	for _, key := range keys {
+		r.keys[strings.ToLower(key)] = true
+	}
+	for _, pattern := range patterns {
+		if builtin, ok := redactPatterns[pattern]; ok {
+			pattern = builtin
+		}
+		reg, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Printf("logx: invalid redact pattern %q: %v", pattern, err)
+			continue
+		}
+		r.patterns = append(r.patterns, reg)
+	}
+	return r
+}
+
+// Secret 始终脱敏的字段，日志、span、Loki及Sentry中均输出为******
+func Secret(key string, val string) Field {
+	return Field{Key: key, Type: secretType, String: val}
+}
+
+// redactField 返回脱敏后的字段
+// key在RedactKeys中（包括命名空间前缀后的部分，如http.authorization）时整个值替换为******
+// 字符串类型的值中匹配RedactPatterns的部分替换为******
+func (s *logState) redactField(f Field) Field {
+	if f.Type == secretType {
+		return String(f.Key, redactedValue)
+	}
+	r := s.redactor
+	if r == nil {
+		return f
+	}
+	key := strings.ToLower(f.Key)
+	if i := strings.LastIndexByte(key, '.'); i >= 0 {
+		key = key[i+1:]
+	}
+	if r.keys[key] && f.Type != namespaceType {
+		return String(f.Key, redactedValue)
+	}
+	if len(r.patterns) == 0 {
+		return f
+	}
+	switch f.Type {
+	case stringType, stringerType:
+		f.String = r.replace(f.String)
+	case byteStringType:
+		f.Bytes = []byte(r.replace(string(f.Bytes)))
+	case stringSliceType:
+		strs := make([]string, len(f.Strings))
+		for i, s := range f.Strings {
+			strs[i] = r.replace(s)
+		}
+		f.Strings = strs
+	}
+	return f
+}
+
+func (r *fieldRedactor) replace(s string) string {
+	for _, reg := range r.patterns {
+		s = reg.ReplaceAllLiteralString(s, redactedValue)
+	}
+	return s
+}
@@ -0,0 +1,120 @@
+// Package redistrace 实现go-redis的redis.Hook
+//
+// 为每个命令创建子span，记录命令名称及key的数量，并通过logx记录错误
+//
+// example:
+// rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+// rdb.AddHook(redistrace.NewHook())
+package redistrace
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/itmisx/logx"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/itmisx/logx/redistrace"
+
+type hook struct{}
+
+// NewHook 创建redis的hook
+func NewHook() redis.Hook {
+	return hook{}
+}
+
+func (hook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		spanCtx, span := otel.Tracer(tracerName).Start(ctx, "redis."+cmd.Name(),
+			oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+			oteltrace.WithAttributes(
+				semconv.DBSystemRedis,
+				semconv.DBOperationKey.String(cmd.Name()),
+				attribute.Int("db.redis.key_count", keyCount(cmd)),
+			),
+		)
+		defer span.End()
+		err := next(spanCtx, cmd)
+		recordError(ctx, span, err, logx.String("db.operation", cmd.Name()))
+		return err
+	}
+}
+
+func (hook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		names := make([]string, 0, len(cmds))
+		keys := 0
+		for _, cmd := range cmds {
+			names = append(names, cmd.Name())
+			keys += keyCount(cmd)
+		}
+		spanCtx, span := otel.Tracer(tracerName).Start(ctx, "redis.pipeline",
+			oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+			oteltrace.WithAttributes(
+				semconv.DBSystemRedis,
+				semconv.DBOperationKey.String(strings.Join(names, " ")),
+				attribute.Int("db.redis.num_cmd", len(cmds)),
+				attribute.Int("db.redis.key_count", keys),
+			),
+		)
+		defer span.End()
+		err := next(spanCtx, cmds)
+		recordError(ctx, span, err, logx.StringSlice("db.operation", names))
+		return err
+	}
+}
+
+// recordError 记录错误，redis.Nil表示key不存在，不视为错误
+func recordError(ctx context.Context, span oteltrace.Span, err error, fields ...logx.Field) {
+	if err == nil || errors.Is(err, redis.Nil) {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		fields = append(fields, logx.Bool("timeout", true))
+	}
+	logx.Error(ctx, "redis command error", append(fields, logx.Err(err))...)
+}
+
+// noKeyCommands 不包含key的命令
+var noKeyCommands = map[string]bool{
+	"ping": true, "echo": true, "auth": true, "select": true, "info": true,
+	"time": true, "dbsize": true, "flushdb": true, "flushall": true,
+	"publish": true, "script": true, "client": true, "config": true, "hello": true,
+	"scan": true, "keys": true, "multi": true, "exec": true, "discard": true,
+}
+
+// allKeyCommands 参数全部为key的命令
+var allKeyCommands = map[string]bool{
+	"del": true, "unlink": true, "exists": true, "mget": true, "touch": true, "watch": true,
+}
+
+// keyCount 命令中key的数量
+func keyCount(cmd redis.Cmder) int {
+	name := cmd.Name()
+	args := len(cmd.Args()) - 1
+	switch {
+	case args <= 0 || noKeyCommands[name]:
+		return 0
+	case allKeyCommands[name]:
+		return args
+	case name == "mset" || name == "msetnx":
+		return args / 2
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,43 @@
+package redistrace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestProcessHook(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	core, logs := observer.New(zap.ErrorLevel)
+	logx.InitWithOptions(logx.Config{}, "local-test", logx.WithZapCore(core))
+
+	ctx := context.Background()
+	process := NewHook().ProcessHook(func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "del" {
+			return errors.New("foo")
+		}
+		return redis.Nil
+	})
+	assert.Equal(t, redis.Nil, process(ctx, redis.NewStringCmd(ctx, "get", "k1")))
+	assert.EqualError(t, process(ctx, redis.NewIntCmd(ctx, "del", "k1", "k2")), "foo")
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, "redis.get", spans[0].Name())
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Contains(t, spans[1].Attributes(), attribute.Int("db.redis.key_count", 2))
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, 1, logs.Len())
+}
@@ -0,0 +1,36 @@
+package logx
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// reopenSignal 接收SIGHUP的channel，未配置ReopenOnSIGHUP时为nil
+var reopenSignal chan os.Signal
+
+// watchReopenSignal 收到SIGHUP时重新打开日志文件，重复Init时先停止之前的监听
+func watchReopenSignal(enable bool) {
+	if reopenSignal != nil {
+		signal.Stop(reopenSignal)
+		close(reopenSignal)
+		reopenSignal = nil
+	}
+	if !enable {
+		return
+	}
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	reopenSignal = ch
+	go func() {
+		for range ch {
+			if loadState().rotator == nil {
+				continue
+			}
+			if err := Reopen(); err != nil {
+				log.Printf("logx: reopen log file failed: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,363 @@
+package logx
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// lumberjack默认的备份文件时间格式
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// rotateShortcuts Rotate的快捷配置，对应的cron表达式及备份文件名中{time}的格式
+var rotateShortcuts = map[string]struct {
+	spec       string
+	timeFormat string
+}{
+	"daily":  {spec: "0 0 0 * * *", timeFormat: "2006-01-02"},
+	"hourly": {spec: "0 0 * * * *", timeFormat: "2006-01-02-15"},
+}
+
+// rotateSpec Rotate对应的cron表达式
+func rotateSpec(rotate string) string {
+	if shortcut, ok := rotateShortcuts[rotate]; ok {
+		return shortcut.spec
+	}
+	return rotate
+}
+
+// fileRotator 可切割的日志文件
+type fileRotator interface {
+	Rotate() error
+	Reopen() error
+}
+
+// multiRotator 同时切割File及ErrorFile
+type multiRotator []fileRotator
+
+func (m multiRotator) Rotate() error {
+	var errs []error
+	for _, r := range m {
+		errs = append(errs, r.Rotate())
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiRotator) Reopen() error {
+	var errs []error
+	for _, r := range m {
+		errs = append(errs, r.Reopen())
+	}
+	return errors.Join(errs...)
+}
+
+// rotateWriter 包装lumberjack，由logx判断并执行切割
+// 以便按RotateFilename重命名备份文件，并记录切割日志
+type rotateWriter struct {
+	mu      sync.Mutex
+	lum     *lumberjack.Logger
+	conf    Config
+	service string
+	size    int64
+	// 编码切割日志，与写入该文件的日志格式相同
+	encoder zapcore.Encoder
+	// 文件名包含日期模板时，之前日期的日志文件，用于MaxTotalSize
+	datedPattern string
+}
+
+func newRotateWriter(lum *lumberjack.Logger, conf Config, service string) *rotateWriter {
+	w := &rotateWriter{lum: lum, conf: conf, service: service, encoder: newEncoder(conf)}
+	if info, err := os.Stat(lum.Filename); err == nil {
+		w.size = info.Size()
+	}
+	return w
+}
+
+func (w *rotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.size > 0 && w.size+int64(len(p)) > w.maxBytes() {
+		w.rotate()
+	}
+	n, err := w.lum.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rotateWriter) Sync() error {
+	return nil
+}
+
+// Rotate 切割日志文件
+func (w *rotateWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.rotate()
+}
+
+// Reopen 关闭日志文件，下次写入时重新打开，用于logrotate等外部工具移动文件后写入新的文件
+func (w *rotateWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.lum.Close()
+	w.size = 0
+	if info, e := os.Stat(w.lum.Filename); e == nil {
+		w.size = info.Size()
+	}
+	return err
+}
+
+// Close 关闭日志文件，之后写入时重新打开
+func (w *rotateWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lum.Close()
+}
+
+func (w *rotateWriter) maxBytes() int64 {
+	if w.lum.MaxSize == 0 {
+		return 100 * 1024 * 1024
+	}
+	return int64(w.lum.MaxSize) * 1024 * 1024
+}
+
+func (w *rotateWriter) rotate() error {
+	oldSize := w.size
+	if err := w.lum.Rotate(); err != nil {
+		return err
+	}
+	w.size = 0
+	stats.rotations.Add(1)
+	backup := w.latestBackup()
+	if backup == "" {
+		return nil
+	}
+	// lumberjack的压缩异步完成，重命名会与之冲突
+	if w.template() != "" && !lumberjackCompress(w.conf) {
+		name := w.backupName(time.Now())
+		if err := os.Rename(backup, name); err == nil {
+			backup = name
+			w.removeBackups(w.expand("*"))
+		}
+	}
+	if selfCompress(w.conf) {
+		go w.compressBackup(backup)
+	}
+	w.pruneTotalSize()
+	w.logRotated(backup, oldSize)
+	return nil
+}
+
+// logRotated 在新的日志文件开头写入warn等级的切割日志
+// 不经过logger，以免受日志等级限制及再次进入Write
+func (w *rotateWriter) logRotated(backup string, oldSize int64) {
+	entry := zapcore.Entry{Level: zapcore.WarnLevel, Time: time.Now(), Message: "log file rotated"}
+	buf, err := w.encoder.EncodeEntry(entry, []zapcore.Field{
+		zap.String("log.file", w.lum.Filename),
+		zap.String("log.backup", backup),
+		zap.Int64("log.old_size", oldSize),
+	})
+	if err != nil {
+		return
+	}
+	defer buf.Free()
+	n, _ := w.lum.Write(buf.Bytes())
+	w.size += int64(n)
+}
+
+// latestBackup lumberjack刚生成的备份文件
+func (w *rotateWriter) latestBackup() string {
+	prefix, ext := splitFilename(w.lum.Filename)
+	matches, _ := filepath.Glob(prefix + "-*" + ext)
+	var latest string
+	var latestTime time.Time
+	for _, match := range matches {
+		ts := strings.TrimSuffix(strings.TrimPrefix(match, prefix+"-"), ext)
+		t, err := time.ParseInLocation(backupTimeFormat, ts, time.Local)
+		if err != nil {
+			continue
+		}
+		if t.After(latestTime) {
+			latest, latestTime = match, t
+		}
+	}
+	return latest
+}
+
+// template 备份文件的命名模板，Rotate为daily,hourly时默认为{name}-{time}{ext}
+func (w *rotateWriter) template() string {
+	if w.conf.RotateFilename == "" {
+		if _, ok := rotateShortcuts[w.conf.Rotate]; ok {
+			return "{name}-{time}{ext}"
+		}
+	}
+	return w.conf.RotateFilename
+}
+
+// backupName 按RotateFilename生成备份文件名，相对路径以日志文件所在目录为准
+// Rotate为daily,hourly时{time}为切割前的日期或小时，如run-2024-05-01.log
+// 同一时间段内因MaxSize等多次切割时，依次添加.1,.2等序号
+func (w *rotateWriter) backupName(t time.Time) string {
+	format := w.conf.RotateTimeFormat
+	if shortcut, ok := rotateShortcuts[w.conf.Rotate]; ok {
+		// 整点切割时属于上一个时间段
+		t = t.Add(-time.Second)
+		if format == "" {
+			format = shortcut.timeFormat
+		}
+	}
+	if format == "" {
+		format = backupTimeFormat
+	}
+	name := w.expand(t.Format(format))
+	prefix, ext := splitFilename(name)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(name); errors.Is(err, os.ErrNotExist) {
+			return name
+		}
+		name = prefix + "." + strconv.Itoa(i) + ext
+	}
+}
+
+// expand 替换RotateFilename中的占位符
+func (w *rotateWriter) expand(ts string) string {
+	prefix, ext := splitFilename(w.lum.Filename)
+	host, _ := os.Hostname()
+	name := strings.NewReplacer(
+		"{name}", filepath.Base(prefix),
+		"{ext}", ext,
+		"{time}", ts,
+		"{host}", host,
+		"{service}", w.service,
+	).Replace(w.template())
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(filepath.Dir(w.lum.Filename), name)
+}
+
+// compressBackup 压缩备份文件，并按MaxBackups,MaxAge清理压缩后的备份文件
+func (w *rotateWriter) compressBackup(backup string) {
+	if _, err := compressFile(backup, w.conf); err != nil {
+		log.Printf("logx: compress log file failed: %v", err)
+		return
+	}
+	pattern := w.expand("*")
+	if w.template() == "" {
+		prefix, ext := splitFilename(w.lum.Filename)
+		pattern = prefix + "-*" + ext
+	}
+	w.removeBackups(pattern + compressExt(w.conf))
+}
+
+// removeBackups 按MaxBackups,MaxAge清理重命名或由logx压缩后的备份文件
+// lumberjack只识别自身格式的备份文件，无法清理这些文件
+func (w *rotateWriter) removeBackups(pattern string) {
+	matches, _ := filepath.Glob(pattern)
+	var files []os.FileInfo
+	var paths = map[os.FileInfo]string{}
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		files = append(files, info)
+		paths[info] = match
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().After(files[j].ModTime())
+	})
+	cutoff := time.Now().Add(-time.Duration(w.lum.MaxAge) * 24 * time.Hour)
+	for i, info := range files {
+		if (w.lum.MaxBackups > 0 && i >= w.lum.MaxBackups) ||
+			(w.lum.MaxAge > 0 && info.ModTime().Before(cutoff)) {
+			os.Remove(paths[info])
+		}
+	}
+}
+
+// pruneTotalSize 当前文件及备份文件的总大小超过MaxTotalSize时，从最早的备份文件开始删除
+func (w *rotateWriter) pruneTotalSize() {
+	if w.conf.MaxTotalSize <= 0 {
+		return
+	}
+	prefix, ext := splitFilename(w.lum.Filename)
+	patterns := []string{prefix + "-*" + ext}
+	if w.template() != "" {
+		patterns = append(patterns, w.expand("*"))
+	}
+	if w.datedPattern != "" {
+		patterns = append(patterns, w.datedPattern)
+	}
+	for _, pattern := range patterns {
+		patterns = append(patterns, pattern+".gz", pattern+".zst")
+	}
+	var files []os.FileInfo
+	var paths = map[os.FileInfo]string{}
+	var seen = map[string]bool{w.lum.Filename: true}
+	var total int64
+	if info, err := os.Stat(w.lum.Filename); err == nil {
+		total = info.Size()
+	}
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, match := range matches {
+			// 跳过FileLink等符号链接
+			info, err := os.Lstat(match)
+			if err != nil || !info.Mode().IsRegular() || seen[match] {
+				continue
+			}
+			seen[match] = true
+			files = append(files, info)
+			paths[info] = match
+			total += info.Size()
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().Before(files[j].ModTime())
+	})
+	budget := int64(w.conf.MaxTotalSize) * 1024 * 1024
+	for _, info := range files {
+		if total <= budget {
+			return
+		}
+		if os.Remove(paths[info]) == nil {
+			total -= info.Size()
+		}
+	}
+}
+
+// splitFilename 拆分日志文件的路径（不含扩展名）及扩展名
+func splitFilename(filename string) (string, string) {
+	ext := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, ext), ext
+}
+
+// Rotate 立即切割日志文件，仅output为file时有效
+func Rotate() error {
+	rotator := loadState().rotator
+	if rotator == nil {
+		return errors.New("log file is not enabled")
+	}
+	return rotator.Rotate()
+}
+
+// Reopen 重新打开日志文件，仅output为file时有效
+// 使用logrotate等外部工具切割时，在移动文件后调用(或配置ReopenOnSIGHUP后发送SIGHUP)，之后的日志写入新的文件
+func Reopen() error {
+	rotator := loadState().rotator
+	if rotator == nil {
+		return errors.New("log file is not enabled")
+	}
+	return rotator.Reopen()
+}
@@ -0,0 +1,43 @@
+package logx
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// Traceparent 返回当前span的w3c traceparent，如00-{traceID}-{spanID}-01
+// 最后一段为采样标记，无span时返回空
+func Traceparent(ctx context.Context) string {
+	sc := spanContext(ctx)
+	if !sc.IsValid() {
+		sc = oteltrace.SpanContextFromContext(ctx)
+	}
+	if !sc.IsValid() {
+		return ""
+	}
+	return "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-" + sc.TraceFlags().String()
+}
+
+// TraceparentMeta 返回traceparent的meta标签，用于在渲染的html中关联前端RUM与后端的追踪
+//
+// example:
+// <head>{{ .TraceparentMeta }}</head>
+func TraceparentMeta(ctx context.Context) template.HTML {
+	traceparent := Traceparent(ctx)
+	if traceparent == "" {
+		return ""
+	}
+	return template.HTML(`<meta name="traceparent" content="` + traceparent + `">`)
+}
+
+// SetServerTiming 添加Server-Timing响应头 traceparent;desc="..."
+// 浏览器的RUM agent可以通过PerformanceResourceTiming读取，关联前后端的追踪
+// 跨域请求需同时设置Timing-Allow-Origin
+func SetServerTiming(ctx context.Context, header http.Header) {
+	if traceparent := Traceparent(ctx); traceparent != "" {
+		header.Add("Server-Timing", `traceparent;desc="`+traceparent+`"`)
+	}
+}
@@ -0,0 +1,203 @@
+package logx
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// SamplingParameters 采样时span的信息
+type SamplingParameters struct {
+	// 上级context，可以获取baggage,WorkerID等
+	ParentContext context.Context
+	// 上级span是否已采样，无上级span时为false
+	ParentSampled bool
+	TraceID       string
+	Name          string
+	Kind          oteltrace.SpanKind
+	// Start时附带的属性
+	Attributes Fields
+}
+
+// Sampler 自定义的采样策略，如按客户的配额，按时间段等
+// 返回true时采样该span
+type Sampler interface {
+	ShouldSample(p SamplingParameters) bool
+}
+
+// SamplerFunc 函数形式的Sampler
+type SamplerFunc func(p SamplingParameters) bool
+
+// ShouldSample
+func (f SamplerFunc) ShouldSample(p SamplingParameters) bool {
+	return f(p)
+}
+
+// samplerAdapter 将Sampler转换为sdktrace.Sampler
+type samplerAdapter struct {
+	sampler Sampler
+}
+
+func (s samplerAdapter) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	parent := oteltrace.SpanContextFromContext(p.ParentContext)
+	params := SamplingParameters{
+		ParentContext: p.ParentContext,
+		ParentSampled: parent.IsSampled(),
+		TraceID:       p.TraceID.String(),
+		Name:          p.Name,
+		Kind:          p.Kind,
+		Attributes:    keyValuesToFields(p.Attributes),
+	}
+	result := sdktrace.SamplingResult{Decision: sdktrace.Drop, Tracestate: parent.TraceState()}
+	if s.sampler.ShouldSample(params) {
+		result.Decision = sdktrace.RecordAndSample
+	}
+	return result
+}
+
+func (s samplerAdapter) Description() string {
+	return "LogxSampler"
+}
+
+// keyValuesToFields attribute转换为Field
+func keyValuesToFields(kvs []attribute.KeyValue) Fields {
+	fields := make(Fields, 0, len(kvs))
+	for _, kv := range kvs {
+		key := string(kv.Key)
+		switch kv.Value.Type() {
+		case attribute.BOOL:
+			fields = append(fields, Bool(key, kv.Value.AsBool()))
+		case attribute.BOOLSLICE:
+			fields = append(fields, BoolSlice(key, kv.Value.AsBoolSlice()))
+		case attribute.INT64:
+			fields = append(fields, Int64(key, kv.Value.AsInt64()))
+		case attribute.INT64SLICE:
+			fields = append(fields, Int64Slice(key, kv.Value.AsInt64Slice()))
+		case attribute.FLOAT64:
+			fields = append(fields, Float64(key, kv.Value.AsFloat64()))
+		case attribute.FLOAT64SLICE:
+			fields = append(fields, Float64Slice(key, kv.Value.AsFloat64Slice()))
+		case attribute.STRING:
+			fields = append(fields, String(key, kv.Value.AsString()))
+		case attribute.STRINGSLICE:
+			fields = append(fields, StringSlice(key, kv.Value.AsStringSlice()))
+		}
+	}
+	return fields
+}
+
+// samplerOf 根据Config.Sampler创建采样器，未配置时使用fallback
+func samplerOf(conf Config, fallback sdktrace.Sampler) sdktrace.Sampler {
+	switch conf.Sampler {
+	case "always":
+		return sdktrace.AlwaysSample()
+	case "never":
+		return sdktrace.NeverSample()
+	// 仍使用traceSampler，以支持SetTraceSampleRatio
+	case "ratio":
+		return traceSampler.set(conf.TraceSampleRatio)
+	case "parentbased_ratio":
+		return sdktrace.ParentBased(traceSampler.set(conf.TraceSampleRatio))
+	case "ratelimit":
+		return newRateLimitSampler(conf.SampleRateLimit)
+	case "parentbased_ratelimit":
+		return sdktrace.ParentBased(newRateLimitSampler(conf.SampleRateLimit))
+	}
+	return fallback
+}
+
+// rateLimitSampler 令牌桶限流采样，每秒最多采样limit个span
+type rateLimitSampler struct {
+	mu     sync.Mutex
+	limit  float64
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimitSampler(limit float64) *rateLimitSampler {
+	return &rateLimitSampler{limit: limit, tokens: math.Max(limit, 1), last: time.Now()}
+}
+
+func (s *rateLimitSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := sdktrace.SamplingResult{
+		Decision:   sdktrace.Drop,
+		Tracestate: oteltrace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	// 桶的容量至少为1，以支持每秒小于1个的限制
+	s.tokens = math.Min(math.Max(s.limit, 1), s.tokens+now.Sub(s.last).Seconds()*s.limit)
+	s.last = now
+	if s.tokens >= 1 {
+		s.tokens--
+		result.Decision = sdktrace.RecordAndSample
+	}
+	return result
+}
+
+func (s *rateLimitSampler) Description() string {
+	return fmt.Sprintf("RateLimitingSampler{%g}", s.limit)
+}
+
+// SampleRule 采样规则，按span名称前缀或http路由指定采样比率
+type SampleRule struct {
+	// span名称前缀，为空时不按名称匹配
+	SpanName string `yaml:"span_name" mapstructure:"span_name"`
+	// http路由，如/healthz,/users/:id，与http.route属性完全匹配，为空时不按路由匹配
+	Route string `yaml:"route" mapstructure:"route"`
+	// 采样比率，0.0-1
+	Ratio float64 `yaml:"ratio" mapstructure:"ratio"`
+}
+
+// ruleSampler 按第一条匹配的规则采样，均不匹配时使用base
+type ruleSampler struct {
+	rules    []SampleRule
+	samplers []sdktrace.Sampler
+	base     sdktrace.Sampler
+}
+
+// newRuleSampler rules为空时返回base
+func newRuleSampler(rules []SampleRule, base sdktrace.Sampler) sdktrace.Sampler {
+	if len(rules) == 0 {
+		return base
+	}
+	s := ruleSampler{rules: rules, base: base}
+	for _, rule := range rules {
+		s.samplers = append(s.samplers, sdktrace.TraceIDRatioBased(rule.Ratio))
+	}
+	return s
+}
+
+func (s ruleSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	var route string
+	for _, kv := range p.Attributes {
+		if kv.Key == "http.route" {
+			route = kv.Value.AsString()
+		}
+	}
+	for i, rule := range s.rules {
+		if rule.SpanName == "" && rule.Route == "" {
+			continue
+		}
+		if rule.SpanName != "" && !strings.HasPrefix(p.Name, rule.SpanName) {
+			continue
+		}
+		if rule.Route != "" && rule.Route != route {
+			continue
+		}
+		return s.samplers[i].ShouldSample(p)
+	}
+	return s.base.ShouldSample(p)
+}
+
+func (s ruleSampler) Description() string {
+	return "RuleSampler{" + s.base.Description() + "}"
+}
@@ -0,0 +1,51 @@
+// Package saramatrace 在sarama的消息中传递追踪信息
+//
+// sarama的ProducerInterceptor,ConsumerInterceptor不包含context，
+// 需要在发送前调用Inject，消费时调用Extract
+//
+// example:
+// saramatrace.Inject(ctx, msg)
+// producer.SendMessage(msg)
+//
+// ctx := saramatrace.Extract(context.Background(), msg)
+// ctx = logx.Start(ctx, "consume")
+// defer logx.End(ctx)
+package saramatrace
+
+import (
+	"context"
+
+	"github.com/IBM/sarama"
+	"github.com/itmisx/logx"
+)
+
+// Inject 将追踪信息注入到消息的headers
+func Inject(ctx context.Context, msg *sarama.ProducerMessage) {
+	headers := map[string]string{}
+	logx.KafkaInject(ctx, headers)
+	for key, value := range headers {
+		msg.Headers = setHeader(msg.Headers, key, value)
+	}
+}
+
+// Extract 从消息的headers中解析追踪信息
+func Extract(ctx context.Context, msg *sarama.ConsumerMessage) context.Context {
+	headers := map[string]string{}
+	for _, header := range msg.Headers {
+		if header != nil {
+			headers[string(header.Key)] = string(header.Value)
+		}
+	}
+	return logx.KafkaExtract(ctx, headers)
+}
+
+// setHeader 设置header，已存在的会被覆盖
+func setHeader(headers []sarama.RecordHeader, key, value string) []sarama.RecordHeader {
+	for i := range headers {
+		if string(headers[i].Key) == key {
+			headers[i].Value = []byte(value)
+			return headers
+		}
+	}
+	return append(headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
@@ -0,0 +1,62 @@
+package logx
+
+import (
+	"context"
+	"runtime/debug"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// scopeName 默认的instrumentation scope名称
+const scopeName = "github.com/itmisx/logx"
+
+// instrumentationScope span的instrumentation scope
+type instrumentationScope struct {
+	name    string
+	version string
+}
+
+// WithScope 设置之后创建的span的instrumentation scope
+// 用于区分span由哪个库或模块产生，如github.com/foo/bar/repo
+//
+// example:
+// ctx = WithScope(ctx, "github.com/foo/bar/repo", "v1.2.0")
+func WithScope(ctx context.Context, name, version string) context.Context {
+	return context.WithValue(ctx, loggerScopeContextKey, instrumentationScope{name: name, version: version})
+}
+
+// scopeOf 获取context中的scope，未设置时使用配置的ScopeName,ScopeVersion
+func scopeOf(ctx context.Context) instrumentationScope {
+	if scope, ok := ctx.Value(loggerScopeContextKey).(instrumentationScope); ok {
+		return scope
+	}
+	conf := &loadState().config
+	scope := instrumentationScope{name: conf.ScopeName, version: conf.ScopeVersion}
+	if scope.name == "" {
+		scope.name = scopeName
+		if scope.version == "" {
+			scope.version = moduleVersion()
+		}
+	}
+	return scope
+}
+
+// tracerOf 根据context中的scope获取tracer
+func tracerOf(ctx context.Context) oteltrace.Tracer {
+	scope := scopeOf(ctx)
+	return loadState().provider.Tracer(scope.name, oteltrace.WithInstrumentationVersion(scope.version))
+}
+
+// moduleVersion 从构建信息中获取logx的版本
+func moduleVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == scopeName {
+			return dep.Version
+		}
+	}
+	return ""
+}
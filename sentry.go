@@ -0,0 +1,228 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// sentryClient 通过envelope接口发送事件到Sentry
+type sentryClient struct {
+	dsn      string
+	endpoint string
+	auth     string
+	service  string
+	client   *http.Client
+}
+
+// newSentryClient 解析DSN，格式为{scheme}://{key}@{host}[/{path}]/{project}
+func newSentryClient(dsn, service string) (*sentryClient, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	key := u.User.Username()
+	idx := strings.LastIndex(u.Path, "/")
+	if key == "" || idx < 0 || u.Path[idx+1:] == "" {
+		return nil, errors.New("invalid sentry dsn")
+	}
+	project := u.Path[idx+1:]
+	return &sentryClient{
+		dsn:      dsn,
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/envelope/", u.Scheme, u.Host, u.Path[:idx], project),
+		auth:     "Sentry sentry_version=7, sentry_client=logx/" + moduleVersion() + ", sentry_key=" + key,
+		service:  service,
+		client:   &http.Client{Timeout: 3 * time.Second},
+	}, nil
+}
+
+// sentryEvent Sentry事件，仅包含logx使用的字段
+type sentryEvent struct {
+	EventID     string                 `json:"event_id"`
+	Timestamp   string                 `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Logger      string                 `json:"logger"`
+	Platform    string                 `json:"platform"`
+	ServerName  string                 `json:"server_name,omitempty"`
+	Environment string                 `json:"environment,omitempty"`
+	Message     map[string]string      `json:"message"`
+	Exception   []sentryException      `json:"exception,omitempty"`
+	Tags        map[string]string      `json:"tags,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+	Contexts    map[string]interface{} `json:"contexts,omitempty"`
+}
+
+type sentryException struct {
+	Type       string           `json:"type"`
+	Value      string           `json:"value"`
+	Stacktrace sentryStacktrace `json:"stacktrace"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	AbsPath  string `json:"abs_path"`
+	Lineno   int    `json:"lineno"`
+	InApp    bool   `json:"in_app"`
+}
+
+// sentryCapture 发送Error及以上等级的日志到Sentry
+// 字符串、数字、布尔类型的fields作为tags，其他的作为extra
+// wait为true时等待发送完成，用于Fatal等退出进程的日志
+func sentryCapture(ctx context.Context, level, msg string, err error, wait bool, attributes ...Field) {
+	s := loadState()
+	client := s.sentry
+	if client == nil {
+		return
+	}
+	if len(s.fields) > 0 {
+		attributes = append(s.fields[:len(s.fields):len(s.fields)], attributes...)
+	}
+	host, _ := os.Hostname()
+	event := sentryEvent{
+		EventID:     sentryEventID(),
+		Timestamp:   time.Now().UTC().Format(time.RFC3339Nano),
+		Level:       level,
+		Logger:      "logx",
+		Platform:    "go",
+		ServerName:  host,
+		Environment: s.config.SentryEnvironment,
+		Message:     map[string]string{"formatted": msg},
+		Tags:        map[string]string{"service.name": client.service},
+		Extra:       map[string]interface{}{},
+		Contexts:    map[string]interface{}{},
+	}
+	if traceID := TraceID(ctx); traceID != "" {
+		event.Tags["trace_id"] = traceID
+		event.Contexts["trace"] = map[string]string{"trace_id": traceID, "span_id": SpanID(ctx)}
+	}
+	attributes = s.limitFields(attributes)
+	group := ""
+	for _, attr := range attributes {
+		attr = s.redactField(attr)
+		key := namespaceKey(s.config.AttributeNamespace, group+attr.Key)
+		switch attr.Type {
+		case namespaceType:
+			group += attr.Key + "."
+		case stringType, stringerType:
+			event.Tags[key] = attr.String
+		case boolType:
+			event.Tags[key] = fmt.Sprint(attr.Bool)
+		case intType, grpcStatusType:
+			event.Tags[key] = fmt.Sprint(attr.Integer)
+		case int64Type:
+			event.Tags[key] = fmt.Sprint(attr.Integer64)
+		case uintType:
+			event.Tags[key] = fmt.Sprint(attr.Uinteger)
+		case uint64Type:
+			event.Tags[key] = fmt.Sprint(attr.Uinteger64)
+		case objectType, arrayType:
+			event.Extra[key] = marshalerValue(attr)
+		case errType:
+			if err == nil {
+				err = attr.Err
+			}
+			event.Extra[key] = attr.Err.Error()
+		default:
+			event.Extra[key] = attr.Value()
+		}
+	}
+	exception := sentryException{Type: "error", Value: msg, Stacktrace: sentryStack(3)}
+	if err != nil {
+		exception.Type = fmt.Sprintf("%T", err)
+		exception.Value = err.Error()
+	}
+	event.Exception = []sentryException{exception}
+	send := func() {
+		if err := client.send(event); err != nil {
+			// 不能记录为Error日志，否则会再次发送到Sentry
+			log.Printf("logx: send sentry event failed: %v", err)
+		}
+	}
+	if wait {
+		send()
+		return
+	}
+	go send()
+}
+
+// send 以envelope格式发送事件
+func (c *sentryClient) send(event sentryEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	header, _ := json.Marshal(map[string]string{
+		"event_id": event.EventID,
+		"sent_at":  time.Now().UTC().Format(time.RFC3339Nano),
+		"dsn":      c.dsn,
+	})
+	item, _ := json.Marshal(map[string]interface{}{"type": "event", "length": len(payload)})
+	var body bytes.Buffer
+	body.Write(header)
+	body.WriteByte('\n')
+	body.Write(item)
+	body.WriteByte('\n')
+	body.Write(payload)
+	body.WriteByte('\n')
+	req, err := http.NewRequest(http.MethodPost, c.endpoint, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", c.auth)
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// sentryStack 调用堆栈，Sentry要求按调用顺序排列，即最近的调用在最后
+func sentryStack(skip int) sentryStacktrace {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(skip+1, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var stack []sentryFrame
+	for {
+		frame, more := frames.Next()
+		stack = append(stack, sentryFrame{
+			Function: frame.Function,
+			AbsPath:  frame.File,
+			Lineno:   frame.Line,
+			InApp:    !strings.HasPrefix(frame.Function, "runtime."),
+		})
+		if !more {
+			break
+		}
+	}
+	for i, j := 0, len(stack)-1; i < j; i, j = i+1, j-1 {
+		stack[i], stack[j] = stack[j], stack[i]
+	}
+	return sentryStacktrace{Frames: stack}
+}
+
+// sentryEventID 32位十六进制的事件ID
+func sentryEventID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
@@ -0,0 +1,158 @@
+package logx
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// 拆分字段预留的长度
+const splitFieldsSize = 64
+
+// splitCore 编码后超过limit字节的日志，将msg拆分为多条日志输出
+// 各部分带有相同的log.split_id，及log.part(从1开始),log.parts
+// fields仅在第一部分输出
+type splitCore struct {
+	zapcore.Core
+	enc   zapcore.Encoder
+	limit int
+}
+
+// splitCores limit大于0时为cores添加拆分功能，enc用于计算编码后的长度
+func splitCores(limit int, enc zapcore.Encoder, cores ...zapcore.Core) []zapcore.Core {
+	if limit <= 0 {
+		return cores
+	}
+	if enc == nil {
+		enc = zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig())
+	}
+	wrapped := make([]zapcore.Core, 0, len(cores))
+	for _, core := range cores {
+		wrapped = append(wrapped, splitCore{Core: core, enc: enc, limit: limit})
+	}
+	return wrapped
+}
+
+func (c splitCore) With(fields []zapcore.Field) zapcore.Core {
+	enc := c.enc.Clone()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	return splitCore{Core: c.Core.With(fields), enc: enc, limit: c.limit}
+}
+
+// Check 第一部分写入内层core Check返回的entry，其余部分各自经内层core的Check写入
+func (c splitCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkInner(c.Core, ent, ce, func(checked *zapcore.CheckedEntry, fields []zapcore.Field) {
+		parts := c.split(checked.Entry, fields)
+		if parts == nil {
+			checked.Write(fields...)
+			return
+		}
+		// Write后checked被回收，先保存ErrorOutput
+		errorOutput := checked.ErrorOutput
+		checked.Entry = parts[0].ent
+		checked.Write(parts[0].fields...)
+		for _, part := range parts[1:] {
+			if partCE := c.Core.Check(part.ent, nil); partCE != nil {
+				partCE.ErrorOutput = errorOutput
+				partCE.Write(part.fields...)
+			}
+		}
+	})
+}
+
+func (c splitCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	parts := c.split(ent, fields)
+	if parts == nil {
+		return c.Core.Write(ent, fields)
+	}
+	var err error
+	for _, part := range parts {
+		if e := c.Core.Write(part.ent, part.fields); e != nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// splitPart 拆分后的一条日志
+type splitPart struct {
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}
+
+// split 拆分超过limit的日志，无需拆分时返回nil
+func (c splitCore) split(ent zapcore.Entry, fields []zapcore.Field) []splitPart {
+	size := c.size(ent, fields)
+	if size <= c.limit || ent.Message == "" {
+		return nil
+	}
+	empty := ent
+	empty.Message = ""
+	base := c.size(empty, nil) + splitFieldsSize
+	first := c.size(empty, fields) + splitFieldsSize
+	if base >= c.limit {
+		return nil
+	}
+	// 按转义后的长度折算msg的字节数
+	encoded := size - c.size(empty, fields)
+	ratio := float64(len(ent.Message)) / float64(encoded)
+	var parts []string
+	msg := ent.Message
+	for budget := c.limit - first; msg != ""; budget = c.limit - base {
+		n := splitPoint(msg, int(float64(budget)*ratio))
+		parts = append(parts, msg[:n])
+		msg = msg[n:]
+	}
+	id := splitID()
+	split := make([]splitPart, len(parts))
+	for i, part := range parts {
+		partEnt := ent
+		partEnt.Message = part
+		partFields := []zapcore.Field{
+			zap.String("log.split_id", id),
+			zap.Int("log.part", i+1),
+			zap.Int("log.parts", len(parts)),
+		}
+		if i == 0 {
+			partFields = append(fields[:len(fields):len(fields)], partFields...)
+		}
+		split[i] = splitPart{ent: partEnt, fields: partFields}
+	}
+	return split
+}
+
+// size 日志编码后的字节数
+func (c splitCore) size(ent zapcore.Entry, fields []zapcore.Field) int {
+	buf, err := c.enc.EncodeEntry(ent, fields)
+	if err != nil {
+		return 0
+	}
+	defer buf.Free()
+	return buf.Len()
+}
+
+// splitPoint 不超过n字节且不截断utf8字符的拆分位置，至少拆分出一个字符
+func splitPoint(s string, n int) int {
+	if n >= len(s) {
+		return len(s)
+	}
+	for i := n; i > 0; i-- {
+		if utf8.RuneStart(s[i]) {
+			return i
+		}
+	}
+	_, size := utf8.DecodeRuneInString(s)
+	return size
+}
+
+// splitID 拆分日志的关联id
+func splitID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
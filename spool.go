@@ -0,0 +1,568 @@
+package logx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// spoolReplayTimeout 后台重新导出保存的span的超时时间，超时后在下次导出成功时继续
+const spoolReplayTimeout = 30 * time.Second
+
+// spoolCheckpointFile 保存已确认导出的批次序号的文件，位于SpoolDir中
+const spoolCheckpointFile = "checkpoint"
+
+// SpoolStatus SpoolDir中磁盘队列的状态
+type SpoolStatus struct {
+	// 等待重新导出的批次数及文件大小
+	Pending      int
+	PendingBytes int64
+	// 最后保存的批次序号
+	LastSeq uint64
+	// 已确认的最大批次序号，保存在SpoolDir/checkpoint，重启后不会重复导出
+	AckedSeq uint64
+	// 超过SpoolMaxBytes或SpoolMaxAge被丢弃的批次数
+	Dropped int64
+}
+
+// SpoolState 返回磁盘队列的状态，未开启SpoolDir时返回零值
+func SpoolState() SpoolStatus {
+	e := loadState().spooler
+	if e == nil {
+		return SpoolStatus{}
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.status()
+}
+
+// ReplayPending 立即按顺序重新导出磁盘队列中的批次，等待正在后台执行的重新导出完成
+// 返回nil时故障期间保存的span都已送达，队列为空，可以安全地清理SpoolDir
+//
+// example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//	defer cancel()
+//	if status, err := logx.ReplayPending(ctx); err != nil {
+//		log.Printf("%d batches pending: %v", status.Pending, err)
+//	}
+func ReplayPending(ctx context.Context) (SpoolStatus, error) {
+	e := loadState().spooler
+	if e == nil {
+		return SpoolStatus{}, errors.New("logx: SpoolDir is not configured")
+	}
+	e.replayMu.Lock()
+	err := e.replay(ctx)
+	e.replayMu.Unlock()
+	return SpoolState(), err
+}
+
+// AckSpool 确认序号不超过seq的批次，从队列中删除且不再导出，并更新checkpoint
+// 用于放弃无法送达的数据，seq可以使用SpoolState().LastSeq
+func AckSpool(seq uint64) error {
+	e := loadState().spooler
+	if e == nil {
+		return errors.New("logx: SpoolDir is not configured")
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.ack(seq)
+}
+
+// spoolExporter 导出失败时将span保存到磁盘，之后导出成功时在后台重新导出
+// 每个批次按递增的序号保存为{seq}.json，导出成功后将序号写入checkpoint再删除文件
+// 保存的文件及checkpoint在启动时读取一次，之后只在内存中记录
+type spoolExporter struct {
+	sdktrace.SpanExporter
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+	pending  []spoolFile
+	total    int64
+	lastSeq  uint64
+	acked    uint64
+	dropped  int64
+	// 同时只有一个重新导出在执行
+	replayMu sync.Mutex
+}
+
+// newSpoolExporter maxBytes默认100MB，maxAge默认24小时
+// 序号不超过checkpoint的文件已导出，删除后不再导出
+func newSpoolExporter(exporter sdktrace.SpanExporter, dir string, maxBytes int64, maxAge time.Duration) *spoolExporter {
+	if maxBytes <= 0 {
+		maxBytes = 100 * 1024 * 1024
+	}
+	if maxAge <= 0 {
+		maxAge = 24 * time.Hour
+	}
+	e := &spoolExporter{SpanExporter: exporter, dir: dir, maxBytes: maxBytes, maxAge: maxAge}
+	if data, err := os.ReadFile(filepath.Join(dir, spoolCheckpointFile)); err == nil {
+		e.acked, _ = strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	}
+	e.lastSeq = e.acked
+	for _, file := range spoolFiles(dir) {
+		if file.seq <= e.acked {
+			os.Remove(file.path)
+			continue
+		}
+		e.pending = append(e.pending, file)
+		e.total += file.size
+		e.lastSeq = file.seq
+	}
+	return e
+}
+
+func (e *spoolExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if err := e.SpanExporter.ExportSpans(ctx, spans); err != nil {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if spoolErr := e.spool(spans); spoolErr != nil {
+			return err
+		}
+		// 已保存到磁盘，重新导出时计入SpansExported
+		stats.spansFailed.Add(-int64(len(spans)))
+		return nil
+	}
+	e.startReplay()
+	return nil
+}
+
+// status 调用方需持有mu
+func (e *spoolExporter) status() SpoolStatus {
+	return SpoolStatus{
+		Pending:      len(e.pending),
+		PendingBytes: e.total,
+		LastSeq:      e.lastSeq,
+		AckedSeq:     e.acked,
+		Dropped:      e.dropped,
+	}
+}
+
+// spool 保存span到磁盘，超过maxBytes时丢弃最早的文件，调用方需持有mu
+func (e *spoolExporter) spool(spans []sdktrace.ReadOnlySpan) error {
+	if err := os.MkdirAll(e.dir, 0o755); err != nil {
+		return err
+	}
+	records := make([]spoolSpan, 0, len(spans))
+	for _, s := range spans {
+		records = append(records, newSpoolSpan(s))
+	}
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+	seq := e.lastSeq + 1
+	name := filepath.Join(e.dir, strconv.FormatUint(seq, 10)+".json")
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		return err
+	}
+	e.lastSeq = seq
+	e.pending = append(e.pending, spoolFile{path: name, seq: seq, size: int64(len(data)), modTime: time.Now()})
+	e.total += int64(len(data))
+	for e.total > e.maxBytes && len(e.pending) > 1 {
+		e.drop(e.pending[0])
+	}
+	return nil
+}
+
+// drop 丢弃未导出的文件，调用方需持有mu
+func (e *spoolExporter) drop(file spoolFile) {
+	for i, f := range e.pending {
+		if f.seq == file.seq {
+			e.pending = append(e.pending[:i], e.pending[i+1:]...)
+			e.total -= f.size
+			e.dropped++
+			os.Remove(f.path)
+			return
+		}
+	}
+}
+
+// ack 确认序号不超过seq的文件，先写入checkpoint再删除文件，调用方需持有mu
+func (e *spoolExporter) ack(seq uint64) error {
+	if seq > e.lastSeq {
+		seq = e.lastSeq
+	}
+	if seq <= e.acked {
+		return nil
+	}
+	if err := os.MkdirAll(e.dir, 0o755); err != nil {
+		return err
+	}
+	checkpoint := filepath.Join(e.dir, spoolCheckpointFile)
+	if err := os.WriteFile(checkpoint+".tmp", []byte(strconv.FormatUint(seq, 10)), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(checkpoint+".tmp", checkpoint); err != nil {
+		return err
+	}
+	e.acked = seq
+	for len(e.pending) > 0 && e.pending[0].seq <= seq {
+		e.total -= e.pending[0].size
+		os.Remove(e.pending[0].path)
+		e.pending = e.pending[1:]
+	}
+	return nil
+}
+
+// startReplay 在后台重新导出保存的span，不占用当前批次的导出时间
+func (e *spoolExporter) startReplay() {
+	e.mu.Lock()
+	empty := len(e.pending) == 0
+	e.mu.Unlock()
+	if empty || !e.replayMu.TryLock() {
+		return
+	}
+	go func() {
+		defer e.replayMu.Unlock()
+		ctx, cancel := context.WithTimeout(context.Background(), spoolReplayTimeout)
+		defer cancel()
+		e.replay(ctx)
+	}()
+}
+
+// replay 按顺序重新导出保存的span，丢弃超过maxAge及无法读取的文件，导出失败时停止
+// 导出时不持有mu，不阻塞新的span保存到磁盘，调用方需持有replayMu
+func (e *spoolExporter) replay(ctx context.Context) error {
+	for {
+		e.mu.Lock()
+		if len(e.pending) == 0 {
+			e.mu.Unlock()
+			return nil
+		}
+		file := e.pending[0]
+		e.mu.Unlock()
+		spans, ok := e.load(file)
+		if !ok {
+			e.mu.Lock()
+			e.drop(file)
+			e.mu.Unlock()
+			continue
+		}
+		if err := e.SpanExporter.ExportSpans(ctx, spans); err != nil {
+			return err
+		}
+		e.mu.Lock()
+		err := e.ack(file.seq)
+		e.mu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// load 读取文件中保存的span，过期或无法读取时ok为false
+func (e *spoolExporter) load(file spoolFile) ([]sdktrace.ReadOnlySpan, bool) {
+	if time.Since(file.modTime) > e.maxAge {
+		return nil, false
+	}
+	data, err := os.ReadFile(file.path)
+	if err != nil {
+		return nil, false
+	}
+	var records []spoolSpan
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, false
+	}
+	spans := make([]sdktrace.ReadOnlySpan, 0, len(records))
+	for _, record := range records {
+		spans = append(spans, record.snapshot())
+	}
+	return spans, true
+}
+
+type spoolFile struct {
+	path    string
+	seq     uint64
+	size    int64
+	modTime time.Time
+}
+
+// spoolFiles 目录中保存的文件，按序号从小到大排序
+func spoolFiles(dir string) []spoolFile {
+	matches, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	files := make([]spoolFile, 0, len(matches))
+	for _, match := range matches {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(match), ".json"), 10, 64)
+		if err != nil {
+			continue
+		}
+		if info, err := os.Stat(match); err == nil {
+			files = append(files, spoolFile{path: match, seq: seq, size: info.Size(), modTime: info.ModTime()})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].seq < files[j].seq
+	})
+	return files
+}
+
+// spoolSpan 可序列化的span
+type spoolSpan struct {
+	Name              string
+	TraceID           string
+	SpanID            string
+	TraceFlags        byte
+	TraceState        string
+	ParentSpanID      string
+	ParentRemote      bool
+	Kind              int
+	Start             time.Time
+	End               time.Time
+	Attributes        []spoolAttr
+	Events            []spoolEvent
+	Links             []spoolLink
+	StatusCode        uint32
+	StatusDescription string
+	DroppedAttributes int
+	DroppedEvents     int
+	DroppedLinks      int
+	ChildSpanCount    int
+	Resource          []spoolAttr
+	SchemaURL         string
+	ScopeName         string
+	ScopeVersion      string
+	ScopeSchemaURL    string
+}
+
+type spoolEvent struct {
+	Name       string
+	Time       time.Time
+	Attributes []spoolAttr
+}
+
+type spoolLink struct {
+	TraceID    string
+	SpanID     string
+	TraceFlags byte
+	TraceState string
+	Attributes []spoolAttr
+}
+
+// spoolAttr 保存attribute的类型，以便还原
+type spoolAttr struct {
+	Key   string
+	Type  string
+	Value json.RawMessage
+}
+
+func newSpoolSpan(s sdktrace.ReadOnlySpan) spoolSpan {
+	sc := s.SpanContext()
+	record := spoolSpan{
+		Name:              s.Name(),
+		TraceID:           sc.TraceID().String(),
+		SpanID:            sc.SpanID().String(),
+		TraceFlags:        byte(sc.TraceFlags()),
+		TraceState:        sc.TraceState().String(),
+		ParentRemote:      s.Parent().IsRemote(),
+		Kind:              int(s.SpanKind()),
+		Start:             s.StartTime(),
+		End:               s.EndTime(),
+		Attributes:        newSpoolAttrs(s.Attributes()),
+		StatusCode:        uint32(s.Status().Code),
+		StatusDescription: s.Status().Description,
+		DroppedAttributes: s.DroppedAttributes(),
+		DroppedEvents:     s.DroppedEvents(),
+		DroppedLinks:      s.DroppedLinks(),
+		ChildSpanCount:    s.ChildSpanCount(),
+		ScopeName:         s.InstrumentationScope().Name,
+		ScopeVersion:      s.InstrumentationScope().Version,
+		ScopeSchemaURL:    s.InstrumentationScope().SchemaURL,
+	}
+	if s.Parent().IsValid() {
+		record.ParentSpanID = s.Parent().SpanID().String()
+	}
+	if res := s.Resource(); res != nil {
+		record.Resource = newSpoolAttrs(res.Attributes())
+		record.SchemaURL = res.SchemaURL()
+	}
+	for _, event := range s.Events() {
+		record.Events = append(record.Events, spoolEvent{
+			Name:       event.Name,
+			Time:       event.Time,
+			Attributes: newSpoolAttrs(event.Attributes),
+		})
+	}
+	for _, link := range s.Links() {
+		record.Links = append(record.Links, spoolLink{
+			TraceID:    link.SpanContext.TraceID().String(),
+			SpanID:     link.SpanContext.SpanID().String(),
+			TraceFlags: byte(link.SpanContext.TraceFlags()),
+			TraceState: link.SpanContext.TraceState().String(),
+			Attributes: newSpoolAttrs(link.Attributes),
+		})
+	}
+	return record
+}
+
+// snapshot 还原为ReadOnlySpan
+func (r spoolSpan) snapshot() sdktrace.ReadOnlySpan {
+	span := &spoolSnapshot{
+		name:              r.Name,
+		spanContext:       spoolSpanContext(r.TraceID, r.SpanID, r.TraceFlags, r.TraceState, false),
+		kind:              oteltrace.SpanKind(r.Kind),
+		start:             r.Start,
+		end:               r.End,
+		attributes:        spoolKeyValues(r.Attributes),
+		status:            sdktrace.Status{Code: codes.Code(r.StatusCode), Description: r.StatusDescription},
+		droppedAttributes: r.DroppedAttributes,
+		droppedEvents:     r.DroppedEvents,
+		droppedLinks:      r.DroppedLinks,
+		childSpanCount:    r.ChildSpanCount,
+		resource:          resource.NewWithAttributes(r.SchemaURL, spoolKeyValues(r.Resource)...),
+		scope: instrumentation.Scope{
+			Name:      r.ScopeName,
+			Version:   r.ScopeVersion,
+			SchemaURL: r.ScopeSchemaURL,
+		},
+	}
+	if r.ParentSpanID != "" {
+		span.parent = spoolSpanContext(r.TraceID, r.ParentSpanID, 0, "", r.ParentRemote)
+	}
+	for _, event := range r.Events {
+		span.events = append(span.events, sdktrace.Event{
+			Name:       event.Name,
+			Time:       event.Time,
+			Attributes: spoolKeyValues(event.Attributes),
+		})
+	}
+	for _, link := range r.Links {
+		span.links = append(span.links, sdktrace.Link{
+			SpanContext: spoolSpanContext(link.TraceID, link.SpanID, link.TraceFlags, link.TraceState, false),
+			Attributes:  spoolKeyValues(link.Attributes),
+		})
+	}
+	return span
+}
+
+// spoolSnapshot 从磁盘还原的span
+// sdktrace.ReadOnlySpan包含未导出的方法，通过嵌入接口满足，导出的方法均由spoolSnapshot实现
+type spoolSnapshot struct {
+	sdktrace.ReadOnlySpan
+	name              string
+	spanContext       oteltrace.SpanContext
+	parent            oteltrace.SpanContext
+	kind              oteltrace.SpanKind
+	start             time.Time
+	end               time.Time
+	attributes        []attribute.KeyValue
+	events            []sdktrace.Event
+	links             []sdktrace.Link
+	status            sdktrace.Status
+	droppedAttributes int
+	droppedEvents     int
+	droppedLinks      int
+	childSpanCount    int
+	resource          *resource.Resource
+	scope             instrumentation.Scope
+}
+
+func (s *spoolSnapshot) Name() string                                  { return s.name }
+func (s *spoolSnapshot) SpanContext() oteltrace.SpanContext            { return s.spanContext }
+func (s *spoolSnapshot) Parent() oteltrace.SpanContext                 { return s.parent }
+func (s *spoolSnapshot) SpanKind() oteltrace.SpanKind                  { return s.kind }
+func (s *spoolSnapshot) StartTime() time.Time                          { return s.start }
+func (s *spoolSnapshot) EndTime() time.Time                            { return s.end }
+func (s *spoolSnapshot) Attributes() []attribute.KeyValue              { return s.attributes }
+func (s *spoolSnapshot) Links() []sdktrace.Link                        { return s.links }
+func (s *spoolSnapshot) Events() []sdktrace.Event                      { return s.events }
+func (s *spoolSnapshot) Status() sdktrace.Status                       { return s.status }
+func (s *spoolSnapshot) InstrumentationScope() instrumentation.Scope   { return s.scope }
+func (s *spoolSnapshot) InstrumentationLibrary() instrumentation.Scope { return s.scope }
+func (s *spoolSnapshot) Resource() *resource.Resource                  { return s.resource }
+func (s *spoolSnapshot) DroppedAttributes() int                        { return s.droppedAttributes }
+func (s *spoolSnapshot) DroppedLinks() int                             { return s.droppedLinks }
+func (s *spoolSnapshot) DroppedEvents() int                            { return s.droppedEvents }
+func (s *spoolSnapshot) ChildSpanCount() int                           { return s.childSpanCount }
+
+func spoolSpanContext(traceID, spanID string, flags byte, state string, remote bool) oteltrace.SpanContext {
+	tid, _ := oteltrace.TraceIDFromHex(traceID)
+	sid, _ := oteltrace.SpanIDFromHex(spanID)
+	ts, _ := oteltrace.ParseTraceState(state)
+	return oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: oteltrace.TraceFlags(flags),
+		TraceState: ts,
+		Remote:     remote,
+	})
+}
+
+func newSpoolAttrs(kvs []attribute.KeyValue) []spoolAttr {
+	attrs := make([]spoolAttr, 0, len(kvs))
+	for _, kv := range kvs {
+		value, err := json.Marshal(kv.Value.AsInterface())
+		if err != nil {
+			continue
+		}
+		attrs = append(attrs, spoolAttr{Key: string(kv.Key), Type: kv.Value.Type().String(), Value: value})
+	}
+	return attrs
+}
+
+func spoolKeyValues(attrs []spoolAttr) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		if kv, ok := attr.keyValue(); ok {
+			kvs = append(kvs, kv)
+		}
+	}
+	return kvs
+}
+
+func (a spoolAttr) keyValue() (attribute.KeyValue, bool) {
+	var err error
+	var kv attribute.KeyValue
+	switch a.Type {
+	case attribute.BOOL.String():
+		var v bool
+		err = json.Unmarshal(a.Value, &v)
+		kv = attribute.Bool(a.Key, v)
+	case attribute.INT64.String():
+		var v int64
+		err = json.Unmarshal(a.Value, &v)
+		kv = attribute.Int64(a.Key, v)
+	case attribute.FLOAT64.String():
+		var v float64
+		err = json.Unmarshal(a.Value, &v)
+		kv = attribute.Float64(a.Key, v)
+	case attribute.STRING.String():
+		var v string
+		err = json.Unmarshal(a.Value, &v)
+		kv = attribute.String(a.Key, v)
+	case attribute.BOOLSLICE.String():
+		var v []bool
+		err = json.Unmarshal(a.Value, &v)
+		kv = attribute.BoolSlice(a.Key, v)
+	case attribute.INT64SLICE.String():
+		var v []int64
+		err = json.Unmarshal(a.Value, &v)
+		kv = attribute.Int64Slice(a.Key, v)
+	case attribute.FLOAT64SLICE.String():
+		var v []float64
+		err = json.Unmarshal(a.Value, &v)
+		kv = attribute.Float64Slice(a.Key, v)
+	case attribute.STRINGSLICE.String():
+		var v []string
+		err = json.Unmarshal(a.Value, &v)
+		kv = attribute.StringSlice(a.Key, v)
+	default:
+		return kv, false
+	}
+	return kv, err == nil
+}
@@ -0,0 +1,240 @@
+// Package sqltrace 为database/sql添加追踪
+//
+// 为Query,Exec,Prepare及事务创建子span，记录sql及耗时，并通过logx记录错误
+//
+// example:
+// db, err := sqltrace.Open("mysql", dsn)
+// rows, err := db.QueryContext(ctx, "SELECT * FROM users")
+package sqltrace
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/itmisx/logx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/itmisx/logx/sqltrace"
+
+// Open 打开数据库，driverName为已注册的驱动，如mysql,postgres
+func Open(driverName, dsn string) (*sql.DB, error) {
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	db.Close()
+	if dc, ok := d.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(dsn)
+		if err != nil {
+			return nil, err
+		}
+		return sql.OpenDB(Connector(driverName, c)), nil
+	}
+	return sql.OpenDB(Connector(driverName, dsnConnector{dsn: dsn, driver: d})), nil
+}
+
+// Connector 包装driver.Connector，driverName用于span的db.system属性
+// 适用于通过驱动的Connector创建数据库的场景，如mysql.NewConnector
+func Connector(driverName string, c driver.Connector) driver.Connector {
+	return connector{Connector: c, system: driverName}
+}
+
+// dsnConnector 不支持DriverContext的驱动
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type connector struct {
+	driver.Connector
+	system string
+}
+
+func (c connector) Connect(ctx context.Context) (driver.Conn, error) {
+	cn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: cn, system: c.system}, nil
+}
+
+// trace 创建span执行fn，记录耗时及错误
+// fn返回driver.ErrSkip时，database/sql会改为使用Prepare执行，不记录错误
+func trace(ctx context.Context, system, op, query string, fn func(ctx context.Context) error) error {
+	attrs := []attribute.KeyValue{attribute.String("db.system", system)}
+	if query != "" {
+		attrs = append(attrs, semconv.DBStatementKey.String(query))
+	}
+	spanCtx, span := otel.Tracer(tracerName).Start(ctx, "sql."+op,
+		oteltrace.WithSpanKind(oteltrace.SpanKindClient),
+		oteltrace.WithAttributes(attrs...),
+	)
+	defer span.End()
+	begin := time.Now()
+	err := fn(spanCtx)
+	latency := time.Since(begin)
+	span.SetAttributes(attribute.Int64("db.duration_ms", latency.Milliseconds()))
+	if err == nil || errors.Is(err, driver.ErrSkip) {
+		logx.Debug(ctx, "sql "+op, logx.String("db.statement", query), logx.Duration("latency", latency))
+		return err
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	logx.Error(ctx, "sql "+op+" error", logx.String("db.statement", query), logx.Duration("latency", latency), logx.Err(err))
+	return err
+}
+
+type conn struct {
+	driver.Conn
+	system string
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (result driver.Result, err error) {
+	execer, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	err = trace(ctx, c.system, "exec", query, func(ctx context.Context) error {
+		result, err = execer.ExecContext(ctx, query, args)
+		return err
+	})
+	return result, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (rows driver.Rows, err error) {
+	queryer, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	err = trace(ctx, c.system, "query", query, func(ctx context.Context) error {
+		rows, err = queryer.QueryContext(ctx, query, args)
+		return err
+	})
+	return rows, err
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (s driver.Stmt, err error) {
+	err = trace(ctx, c.system, "prepare", query, func(ctx context.Context) error {
+		if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+			s, err = p.PrepareContext(ctx, query)
+		} else {
+			s, err = c.Conn.Prepare(query)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: s, query: query, system: c.system}, nil
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (tx driver.Tx, err error) {
+	err = trace(ctx, c.system, "begin", "", func(ctx context.Context) error {
+		if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+			tx, err = b.BeginTx(ctx, opts)
+		} else {
+			tx, err = c.Conn.Begin()
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &transaction{Tx: tx, ctx: ctx, system: c.system}, nil
+}
+
+type stmt struct {
+	driver.Stmt
+	query  string
+	system string
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (result driver.Result, err error) {
+	err = trace(ctx, s.system, "exec", s.query, func(ctx context.Context) error {
+		if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+			result, err = e.ExecContext(ctx, args)
+		} else {
+			result, err = s.Stmt.Exec(namedValues(args))
+		}
+		return err
+	})
+	return result, err
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (rows driver.Rows, err error) {
+	err = trace(ctx, s.system, "query", s.query, func(ctx context.Context) error {
+		if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+			rows, err = q.QueryContext(ctx, args)
+		} else {
+			rows, err = s.Stmt.Query(namedValues(args))
+		}
+		return err
+	})
+	return rows, err
+}
+
+// namedValues 转换为旧版接口的参数
+func namedValues(args []driver.NamedValue) []driver.Value {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
+
+// transaction 事务，ctx为BeginTx时的ctx
+type transaction struct {
+	driver.Tx
+	ctx    context.Context
+	system string
+}
+
+func (t *transaction) Commit() error {
+	return trace(t.ctx, t.system, "commit", "", func(context.Context) error {
+		return t.Tx.Commit()
+	})
+}
+
+func (t *transaction) Rollback() error {
+	return trace(t.ctx, t.system, "rollback", "", func(context.Context) error {
+		return t.Tx.Rollback()
+	})
+}
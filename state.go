@@ -0,0 +1,109 @@
+package logx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+
+	"github.com/imroc/req/v3"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+)
+
+// logState Init生成的运行时状态，Init,Reconfigure,With时创建新的logState整体替换
+// 发布后不再修改，日志及追踪的函数通过loadState()读取，不需要加锁
+type logState struct {
+	config Config
+	// Init,Reconfigure传入的配置，未应用环境变量，热加载时与文件的配置比较
+	applied Config
+	opts    initOptions
+	enabled bool
+	logger  *zap.Logger
+	// 不受日志等级限制的logger，未开启ErrorBoost,ErrorBuffer时为nil
+	boost *zap.Logger
+	// With设置的全局默认字段，所有日志都会附带
+	fields []Field
+	// 当前的日志文件，用于Rotate,Reopen
+	rotator fileRotator
+	// 日志文件及kafka等输出，被替换后关闭
+	closers  []io.Closer
+	provider *trace.TracerProvider
+	// 开启SpoolDir时的磁盘队列，用于ReplayPending,SpoolState,AckSpool
+	spooler *spoolExporter
+	// WithPropagator指定的或默认的b3+baggage
+	// 应用或其他库之后修改otel全局的propagator不影响HttpInject,Inject,KafkaInject等的格式
+	propagator propagation.TextMapPropagator
+	reqClient  *req.Client
+	lokiLabel  map[string]string
+	// 以下未配置时为nil
+	redactor *fieldRedactor
+	sentry   *sentryClient
+	alert    *errorAlert
+	audit    *auditWriter
+}
+
+// current 当前生效的logState，Init之前为nil
+var current atomic.Pointer[logState]
+
+// emptyState Init之前使用的状态
+var emptyState = &logState{logger: zap.NewNop()}
+
+// loadState 返回当前生效的logState
+func loadState() *logState {
+	if s := current.Load(); s != nil {
+		return s
+	}
+	return emptyState
+}
+
+// storeState 替换当前的logState，返回之前的
+// 之前的logState需由调用方在刷新后调用release关闭
+func storeState(s *logState) *logState {
+	if previous := current.Swap(s); previous != nil {
+		return previous
+	}
+	return emptyState
+}
+
+// release 刷新被替换的logState的日志，并关闭next不再使用的日志文件及输出
+func (s *logState) release(next *logState) error {
+	if s == next {
+		return nil
+	}
+	var errs []error
+	if s.enabled {
+		// 标准输出不支持Sync，忽略错误
+		_ = s.logger.Sync()
+	}
+	for _, c := range s.closers {
+		if !next.owns(c) {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// owns c是否为s的日志文件或输出
+func (s *logState) owns(c io.Closer) bool {
+	for _, o := range s.closers {
+		if o == c {
+			return true
+		}
+	}
+	return false
+}
+
+// with 返回附带fields的logState副本，其他状态共用
+func (s *logState) with(fields []Field) *logState {
+	next := *s
+	next.fields = append(s.fields[:len(s.fields):len(s.fields)], fields...)
+	if next.enabled {
+		next.logger = s.logger.With(s.zapFields(context.Background(), fields...)...)
+		if next.boost != nil {
+			next.boost = s.boost.With(s.zapFields(context.Background(), fields...)...)
+		}
+	}
+	return &next
+}
@@ -0,0 +1,261 @@
+package logx
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"sync"
+	"sync/atomic"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Summary 日志及追踪的统计
+type Summary struct {
+	// 各等级的日志数量，如info,error
+	Entries map[string]int64
+	// 启动的span数量，等于SpansEnded+SpansDropped+未结束的span数量
+	SpansStarted int64
+	// 结束并采样的span数量
+	SpansEnded int64
+	// 未采样的span数量
+	SpansDropped int64
+	// 导出成功的span数量
+	SpansExported int64
+	// 导出失败的span数量，开启SpoolDir时不包括保存到磁盘的
+	SpansFailed int64
+	// 等待导出的span数量（估算），为SpansEnded减去已导出、失败及尾部采样丢弃的数量
+	QueueDepth int64
+	// 导出span失败的次数
+	ExportErrors int64
+	// otel内部错误的次数，如导出失败，队列已满等
+	OTelErrors int64
+	// 写入文件或控制台的字节数
+	BytesWritten int64
+	// 写入失败而丢失的日志数量
+	WriteErrors int64
+	// LogSampling丢弃的日志数量
+	EntriesSampled int64
+	// 日志文件切割的次数
+	Rotations int64
+	// 已启动未结束的记录中的span数量
+	ActiveSpans int64
+}
+
+// stats 自Init以来的统计
+var stats struct {
+	entries      [zapcore.FatalLevel - zapcore.DebugLevel + 1]atomic.Int64
+	spansStarted atomic.Int64
+	spansEnded   atomic.Int64
+	spansDropped atomic.Int64
+	// 导出成功的span数量
+	spansExported atomic.Int64
+	// 导出失败的span数量
+	spansFailed atomic.Int64
+	// 尾部采样丢弃的span数量
+	spansDiscarded atomic.Int64
+	exportErrors   atomic.Int64
+	otelErrors     atomic.Int64
+	bytesWritten   atomic.Int64
+	writeErrors    atomic.Int64
+	entriesSampled atomic.Int64
+	rotations      atomic.Int64
+	spansActive    atomic.Int64
+}
+
+// resetStats 重置统计
+func resetStats() {
+	for i := range stats.entries {
+		stats.entries[i].Store(0)
+	}
+	stats.spansStarted.Store(0)
+	stats.spansEnded.Store(0)
+	stats.spansDropped.Store(0)
+	stats.spansExported.Store(0)
+	stats.spansFailed.Store(0)
+	stats.spansDiscarded.Store(0)
+	stats.exportErrors.Store(0)
+	stats.otelErrors.Store(0)
+	stats.bytesWritten.Store(0)
+	stats.writeErrors.Store(0)
+	stats.entriesSampled.Store(0)
+	stats.rotations.Store(0)
+	stats.spansActive.Store(0)
+}
+
+// countEntry zap的hook，统计各等级的日志数量
+func countEntry(entry zapcore.Entry) error {
+	if entry.Level >= zapcore.DebugLevel && entry.Level <= zapcore.FatalLevel {
+		stats.entries[entry.Level-zapcore.DebugLevel].Add(1)
+	}
+	return nil
+}
+
+// currentSummary 当前的统计
+func currentSummary() Summary {
+	summary := Summary{
+		Entries:        map[string]int64{},
+		SpansStarted:   stats.spansStarted.Load(),
+		SpansEnded:     stats.spansEnded.Load(),
+		SpansDropped:   stats.spansDropped.Load(),
+		SpansExported:  stats.spansExported.Load(),
+		SpansFailed:    stats.spansFailed.Load(),
+		ExportErrors:   stats.exportErrors.Load(),
+		OTelErrors:     stats.otelErrors.Load(),
+		BytesWritten:   stats.bytesWritten.Load(),
+		WriteErrors:    stats.writeErrors.Load(),
+		EntriesSampled: stats.entriesSampled.Load(),
+		Rotations:      stats.rotations.Load(),
+		ActiveSpans:    stats.spansActive.Load(),
+	}
+	summary.QueueDepth = max(summary.SpansEnded-summary.SpansExported-summary.SpansFailed-stats.spansDiscarded.Load(), 0)
+	for i := range stats.entries {
+		if n := stats.entries[i].Load(); n > 0 {
+			summary.Entries[(zapcore.DebugLevel + zapcore.Level(i)).String()] = n
+		}
+	}
+	return summary
+}
+
+// Stats 返回自Init以来的统计，用于监控日志及追踪数据是否丢失
+func Stats() Summary {
+	return currentSummary()
+}
+
+// Shutdown 导出剩余的span并刷新日志，返回自Init以来的统计
+// 配置ShutdownSummary时，会记录一条info日志logx summary，适用于批处理任务及命令行工具
+func Shutdown(ctx context.Context) (Summary, error) {
+	var errs []error
+	stopWatch()
+	initMu.Lock()
+	defer initMu.Unlock()
+	s := loadState()
+	if s.provider != nil {
+		errs = append(errs, s.provider.Shutdown(ctx))
+	}
+	if meterProvider != nil {
+		errs = append(errs, meterProvider.Shutdown(ctx))
+		meterProvider = nil
+	}
+	summary := currentSummary()
+	if s.enabled {
+		if s.config.ShutdownSummary {
+			fields := []zap.Field{
+				zap.Int64("spans_started", summary.SpansStarted),
+				zap.Int64("spans_ended", summary.SpansEnded),
+				zap.Int64("spans_dropped", summary.SpansDropped),
+				zap.Int64("spans_exported", summary.SpansExported),
+				zap.Int64("spans_failed", summary.SpansFailed),
+				zap.Int64("export_errors", summary.ExportErrors),
+				zap.Int64("otel_errors", summary.OTelErrors),
+				zap.Int64("bytes_written", summary.BytesWritten),
+			}
+			for level, n := range summary.Entries {
+				fields = append(fields, zap.Int64("entries_"+level, n))
+			}
+			s.logger.Info("logx summary", fields...)
+		}
+		// 标准输出不支持Sync，忽略错误
+		_ = s.logger.Sync()
+	}
+	// 关闭日志文件及输出，之后Init时不再重复关闭
+	next := *s
+	next.closers, next.audit = nil, nil
+	storeState(&next)
+	for _, c := range s.closers {
+		errs = append(errs, c.Close())
+	}
+	if s.audit != nil {
+		errs = append(errs, s.audit.close())
+	}
+	return summary, errors.Join(errs...)
+}
+
+// countingSampler 统计启动及未采样的span
+type countingSampler struct {
+	base sdktrace.Sampler
+}
+
+func (s countingSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	result := s.base.ShouldSample(p)
+	stats.spansStarted.Add(1)
+	if result.Decision != sdktrace.RecordAndSample {
+		stats.spansDropped.Add(1)
+	}
+	return result
+}
+
+func (s countingSampler) Description() string {
+	return s.base.Description()
+}
+
+// countingProcessor 统计结束并采样的span及未结束的span
+type countingProcessor struct{}
+
+func (countingProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	stats.spansActive.Add(1)
+}
+
+func (countingProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	stats.spansActive.Add(-1)
+	if s.SpanContext().IsSampled() {
+		stats.spansEnded.Add(1)
+	}
+}
+
+func (countingProcessor) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+func (countingProcessor) ForceFlush(ctx context.Context) error {
+	return nil
+}
+
+// countingExporter 统计导出失败的次数
+type countingExporter struct {
+	sdktrace.SpanExporter
+}
+
+func (e countingExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	if err != nil {
+		stats.exportErrors.Add(1)
+		stats.spansFailed.Add(int64(len(spans)))
+	} else {
+		stats.spansExported.Add(int64(len(spans)))
+	}
+	return err
+}
+
+// countingWriteSyncer 统计写入的字节数
+type countingWriteSyncer struct {
+	zapcore.WriteSyncer
+}
+
+func (w countingWriteSyncer) Write(p []byte) (int, error) {
+	n, err := w.WriteSyncer.Write(p)
+	stats.bytesWritten.Add(int64(n))
+	if err != nil {
+		stats.writeErrors.Add(1)
+	}
+	return n, err
+}
+
+// handleOTelError 记录otel内部的错误，如导出失败，队列已满等
+func handleOTelError(err error) {
+	stats.otelErrors.Add(1)
+	Error(context.Background(), "otel error", Err(err))
+}
+
+var expvarOnce sync.Once
+
+// publishExpvar 发布expvar变量logx
+func publishExpvar() {
+	expvarOnce.Do(func() {
+		expvar.Publish("logx", expvar.Func(func() any {
+			return Stats()
+		}))
+	})
+}
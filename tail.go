@@ -0,0 +1,207 @@
+package logx
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// tailSampling 尾部采样，未开启TailSampling时为nil
+var tailSampling *tailProcessor
+
+// maxTailTraces 缓存的trace数量上限，超过时丢弃最早的
+const maxTailTraces = 10000
+
+// tailTrace 缓存中的trace
+type tailTrace struct {
+	spans []sdktrace.ReadOnlySpan
+	keep  bool
+	start time.Time
+}
+
+// tailProcessor 按trace缓存已结束的span，本地的根span结束时
+// 仅导出包含错误（或warn）的trace
+type tailProcessor struct {
+	mu      sync.Mutex
+	next    sdktrace.SpanProcessor
+	timeout time.Duration
+	traces  map[oteltrace.TraceID]*tailTrace
+	order   []oteltrace.TraceID
+	// 已决定的trace，用于根span之后结束的span
+	decided      map[oteltrace.TraceID]bool
+	decidedOrder []oteltrace.TraceID
+}
+
+// newTailProcessor timeout默认为30秒，超时未结束的trace按当前的span决定
+func newTailProcessor(next sdktrace.SpanProcessor, timeout time.Duration) *tailProcessor {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &tailProcessor{
+		next:    next,
+		timeout: timeout,
+		traces:  map[oteltrace.TraceID]*tailTrace{},
+		decided: map[oteltrace.TraceID]bool{},
+	}
+}
+
+func (p *tailProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	p.next.OnStart(parent, s)
+}
+
+func (p *tailProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if !s.SpanContext().IsSampled() {
+		p.next.OnEnd(s)
+		return
+	}
+	id := s.SpanContext().TraceID()
+	p.mu.Lock()
+	if keep, ok := p.decided[id]; ok {
+		p.mu.Unlock()
+		if keep {
+			p.next.OnEnd(s)
+		}
+		return
+	}
+	t := p.trace(id)
+	t.spans = append(t.spans, s)
+	if s.Status().Code == codes.Error || hasException(s) {
+		t.keep = true
+	}
+	var export []sdktrace.ReadOnlySpan
+	// 本地的根span结束时决定
+	if !s.Parent().IsValid() || s.Parent().IsRemote() {
+		export = append(export, p.decide(id)...)
+	}
+	export = append(export, p.expire()...)
+	p.mu.Unlock()
+	for _, span := range export {
+		p.next.OnEnd(span)
+	}
+}
+
+// keep 标记trace需要导出，用于warn日志
+func (p *tailProcessor) keep(id oteltrace.TraceID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.decided[id]; ok {
+		return
+	}
+	p.trace(id).keep = true
+}
+
+// trace 获取缓存的trace，不存在时创建
+func (p *tailProcessor) trace(id oteltrace.TraceID) *tailTrace {
+	if t, ok := p.traces[id]; ok {
+		return t
+	}
+	if len(p.order) >= maxTailTraces {
+		delete(p.traces, p.order[0])
+		p.order = p.order[1:]
+	}
+	t := &tailTrace{start: time.Now()}
+	p.traces[id] = t
+	p.order = append(p.order, id)
+	return t
+}
+
+// decide 决定trace是否导出，返回需要导出的span
+func (p *tailProcessor) decide(id oteltrace.TraceID) []sdktrace.ReadOnlySpan {
+	t, ok := p.traces[id]
+	if !ok {
+		return nil
+	}
+	delete(p.traces, id)
+	for i, o := range p.order {
+		if o == id {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+	if len(p.decidedOrder) >= maxTailTraces {
+		delete(p.decided, p.decidedOrder[0])
+		p.decidedOrder = p.decidedOrder[1:]
+	}
+	p.decided[id] = t.keep
+	p.decidedOrder = append(p.decidedOrder, id)
+	if t.keep {
+		return t.spans
+	}
+	stats.spansDiscarded.Add(int64(len(t.spans)))
+	return nil
+}
+
+// expire 决定超时的trace
+func (p *tailProcessor) expire() []sdktrace.ReadOnlySpan {
+	var export []sdktrace.ReadOnlySpan
+	for len(p.order) > 0 && time.Since(p.traces[p.order[0]].start) > p.timeout {
+		export = append(export, p.decide(p.order[0])...)
+	}
+	return export
+}
+
+// Shutdown 决定所有缓存的trace后关闭
+func (p *tailProcessor) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	var export []sdktrace.ReadOnlySpan
+	for len(p.order) > 0 {
+		export = append(export, p.decide(p.order[0])...)
+	}
+	p.mu.Unlock()
+	for _, span := range export {
+		p.next.OnEnd(span)
+	}
+	return p.next.Shutdown(ctx)
+}
+
+func (p *tailProcessor) ForceFlush(ctx context.Context) error {
+	return p.next.ForceFlush(ctx)
+}
+
+// hasException span是否记录过错误
+func hasException(s sdktrace.ReadOnlySpan) bool {
+	for _, event := range s.Events() {
+		if event.Name == "exception" {
+			return true
+		}
+	}
+	return false
+}
+
+// tailKeep TailSampling为warn时，标记ctx所在的trace需要导出
+func tailKeep(ctx context.Context) {
+	if tailSampling == nil || loadState().config.TailSampling != "warn" {
+		return
+	}
+	if sc := oteltrace.SpanContextFromContext(ctx); sc.IsValid() {
+		tailSampling.keep(sc.TraceID())
+	}
+}
+
+// batcherOf 返回导出span的processor，开启TailSampling时使用尾部采样
+func batcherOf(conf Config, exporter sdktrace.SpanExporter) sdktrace.SpanProcessor {
+	var options []sdktrace.BatchSpanProcessorOption
+	if conf.BatchTimeout > 0 {
+		options = append(options, sdktrace.WithBatchTimeout(conf.BatchTimeout))
+	}
+	if conf.ExportTimeout > 0 {
+		options = append(options, sdktrace.WithExportTimeout(conf.ExportTimeout))
+	}
+	if conf.MaxQueueSize > 0 {
+		options = append(options, sdktrace.WithMaxQueueSize(conf.MaxQueueSize))
+	}
+	if conf.MaxExportBatchSize > 0 {
+		options = append(options, sdktrace.WithMaxExportBatchSize(conf.MaxExportBatchSize))
+	}
+	batcher := sdktrace.NewBatchSpanProcessor(exporter, options...)
+	tailSampling = nil
+	if conf.TailSampling == "" {
+		return batcher
+	}
+	tailSampling = newTailProcessor(batcher, conf.TailSamplingTimeout)
+	return tailSampling
+}
@@ -0,0 +1,65 @@
+package logx
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlertWebhook(t *testing.T) {
+	payloads := make(chan map[string]interface{}, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		payloads <- payload
+	}))
+	defer server.Close()
+
+	logx.Init(logx.Config{
+		AlertWebhook:   server.URL,
+		AlertThreshold: 3,
+		AlertWindow:    time.Minute,
+	}, "local-test", logx.String("env", "test"))
+	ctx := context.Background()
+	logx.Error(ctx, "error 1")
+	logx.Errorf(ctx, "error %d", 2)
+	logx.Warn(ctx, "not counted")
+	logx.Error(ctx, "error 3")
+	logx.Error(ctx, "error 4")
+
+	select {
+	case payload := <-payloads:
+		assert.Equal(t, "local-test", payload["service"])
+		assert.Equal(t, float64(3), payload["count"])
+		assert.Equal(t, map[string]interface{}{"env": "test"}, payload["attributes"])
+		assert.Equal(t, []interface{}{"error 1", "error 2", "error 3"}, payload["samples"])
+	case <-time.After(time.Second):
+		t.Fatal("alert not received")
+	}
+	// 每个窗口最多告警一次
+	select {
+	case <-payloads:
+		t.Fatal("alert sent twice in one window")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	logx.Init(logx.Config{
+		AlertWebhook:     server.URL,
+		AlertWebhookType: "dingtalk",
+		AlertThreshold:   1,
+	}, "local-test")
+	logx.Error(ctx, "boom")
+	select {
+	case payload := <-payloads:
+		assert.Equal(t, "text", payload["msgtype"])
+		assert.Contains(t, payload["text"].(map[string]interface{})["content"], "boom")
+	case <-time.After(time.Second):
+		t.Fatal("alert not received")
+	}
+}
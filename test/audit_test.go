@@ -0,0 +1,57 @@
+package logx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAudit(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "audit", "audit.log")
+	logx.Init(logx.Config{AuditFile: file, EnableTrace: true, TracerProviderType: "file"}, "local-test")
+
+	ctx := logx.Start(context.Background(), "audit")
+	defer logx.End(ctx)
+	ctx = logx.WithFields(ctx, logx.String("ip", "127.0.0.1"))
+	assert.NoError(t, logx.Audit(ctx, "user.delete",
+		logx.String("actor", "admin"),
+		logx.String("target", "user:1"),
+		logx.String("result", "success"),
+		logx.Secret("password", "123456"),
+		logx.Int("seq", 100),
+	))
+	assert.ErrorContains(t, logx.Audit(ctx, "user.delete", logx.String("actor", "admin")), "target")
+
+	// 重新打开后继续seq及hash链
+	logx.Init(logx.Config{AuditFile: file}, "local-test")
+	assert.NoError(t, logx.Audit(context.Background(), "user.create",
+		logx.String("actor", "admin"),
+		logx.String("target", "user:2"),
+		logx.String("result", "failure"),
+	))
+	logx.Init(logx.Config{}, "local-test")
+	assert.Error(t, logx.Audit(context.Background(), "user.create"))
+
+	content, _ := os.ReadFile(file)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], `"seq":1`)
+	assert.Contains(t, lines[0], `"trace_id":"`+logx.TraceID(ctx)+`"`)
+	assert.Contains(t, lines[0], `"ip":"127.0.0.1"`)
+	assert.Contains(t, lines[0], `"password":"******"`)
+	assert.Contains(t, lines[1], `"seq":2`)
+	assert.NoError(t, logx.VerifyAudit(file))
+
+	// 修改记录后校验失败
+	tampered := strings.Replace(string(content), "user:1", "user:3", 1)
+	assert.NoError(t, os.WriteFile(file, []byte(tampered), 0o640))
+	assert.ErrorContains(t, logx.VerifyAudit(file), "audit record 1: hash mismatch")
+	// 删除记录后校验失败
+	assert.NoError(t, os.WriteFile(file, []byte(lines[1]+"\n"), 0o640))
+	assert.ErrorContains(t, logx.VerifyAudit(file), "audit record 1")
+}
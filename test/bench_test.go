@@ -0,0 +1,71 @@
+package logx
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var benchFields = []logx.Field{
+	logx.String("user", "u1"),
+	logx.Int("n", 1),
+	logx.Bool("ok", true),
+	logx.Float64("amount", 9.9),
+	logx.ErrStack(errors.New("boom")),
+}
+
+// benchInit 输出到io.Discard，level为记录的等级
+func benchInit(level zapcore.Level, enableTrace bool) {
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(io.Discard), level)
+	logx.InitWithOptions(logx.Config{EnableTrace: enableTrace, TracerProviderType: "file", Sampler: "never"}, "bench", logx.WithZapCore(core))
+}
+
+func BenchmarkFieldsToZapFields(b *testing.B) {
+	benchInit(zap.InfoLevel, false)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		logx.FieldsToZapFields(ctx, benchFields...)
+	}
+}
+
+func BenchmarkFieldsToKeyValues(b *testing.B) {
+	benchInit(zap.InfoLevel, false)
+	b.ReportAllocs()
+	for b.Loop() {
+		logx.FieldsToKeyValues(benchFields...)
+	}
+}
+
+func BenchmarkInfo(b *testing.B) {
+	benchInit(zap.InfoLevel, false)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		logx.Info(ctx, "bench", benchFields[:4]...)
+	}
+}
+
+func BenchmarkDebugDisabled(b *testing.B) {
+	benchInit(zap.InfoLevel, false)
+	ctx := context.Background()
+	b.ReportAllocs()
+	for b.Loop() {
+		logx.Debug(ctx, "bench", benchFields[:4]...)
+	}
+}
+
+func BenchmarkInfoWithSpan(b *testing.B) {
+	benchInit(zap.InfoLevel, true)
+	ctx := logx.Start(context.Background(), "bench")
+	defer logx.End(ctx)
+	b.ReportAllocs()
+	for b.Loop() {
+		logx.Info(ctx, "bench", benchFields[:4]...)
+	}
+}
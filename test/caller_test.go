@@ -0,0 +1,38 @@
+package logx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// logWrapper 在logx之上再封装的日志函数
+func logWrapper(msg string) {
+	logx.Error(context.Background(), msg)
+}
+
+func TestCallerOptions(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logx.InitWithOptions(logx.Config{CallerSkip: 1, StacktraceLevel: "error"}, "local-test", logx.WithZapCore(core))
+	logWrapper("wrapped")
+	logx.Warn(context.Background(), "no stack")
+	entries := logs.TakeAll()
+	if assert.Len(t, entries, 2) {
+		assert.Contains(t, entries[0].Caller.Function, "TestCallerOptions")
+		assert.Contains(t, entries[0].Stack, "TestCallerOptions")
+		assert.NotContains(t, entries[0].Stack, "logWrapper")
+		assert.Empty(t, entries[1].Stack)
+	}
+
+	logx.InitWithOptions(logx.Config{DisableCaller: true}, "local-test", logx.WithZapCore(core))
+	logx.Error(context.Background(), "no caller")
+	entries = logs.TakeAll()
+	if assert.Len(t, entries, 1) {
+		assert.False(t, entries[0].Caller.Defined)
+	}
+	logx.Init(logx.Config{}, "local-test")
+}
@@ -0,0 +1,41 @@
+package logx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestCommandContext(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logx.InitWithOptions(logx.Config{EnableTrace: true, TracerProviderType: "file"}, "local-test", logx.WithZapCore(core))
+
+	cmd := logx.CommandContext(context.Background(), "sh", "-c", "echo $B3; echo oops >&2; printf tail; exit 3")
+	assert.NotNil(t, cmd.Run())
+
+	var stdout []string
+	for _, entry := range logs.All() {
+		if entry.ContextMap()["stream"] == "stderr" {
+			assert.Equal(t, zapcore.WarnLevel, entry.Level)
+			assert.Equal(t, "oops", entry.Message)
+		} else {
+			stdout = append(stdout, entry.Message)
+		}
+	}
+	assert.Len(t, stdout, 2)
+	assert.Contains(t, stdout[0], logx.TraceID(cmd.Context()))
+	assert.Equal(t, "tail", stdout[1])
+
+	span := oteltrace.SpanFromContext(cmd.Context()).(sdktrace.ReadOnlySpan)
+	assert.Contains(t, span.Attributes(), attribute.Int("process.exit_code", 3))
+	assert.Equal(t, codes.Error, span.Status().Code)
+}
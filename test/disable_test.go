@@ -0,0 +1,44 @@
+package logx
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestDisable(t *testing.T) {
+	propagator := propagation.TraceContext{}
+	otel.SetTextMapPropagator(propagator)
+	logx.Disable()
+	defer logx.Init(logx.Config{}, "local-test")
+	// 不修改全局的propagator
+	assert.Equal(t, propagator, otel.GetTextMapPropagator())
+
+	ctx := context.Background()
+	assert.False(t, logx.Enabled(ctx, "error"))
+	assert.Equal(t, ctx, logx.Start(ctx, "disabled"))
+	err := errors.New("failed")
+	allocs := testing.AllocsPerRun(100, func() {
+		spanCtx := logx.Start(ctx, "disabled", logx.String("key", "value"))
+		logx.Debug(spanCtx, "debug", logx.String("key", "value"))
+		logx.Info(spanCtx, "info", logx.Int("count", 1))
+		logx.Warn(spanCtx, "warn", logx.String("key", "value"))
+		logx.Error(spanCtx, "error", logx.Err(err))
+		logx.Errorf(spanCtx, "error %d", 1)
+		_ = logx.ErrorReturn(spanCtx, err)
+		logx.End(spanCtx)
+	})
+	assert.Equal(t, float64(0), allocs)
+	assert.False(t, oteltrace.SpanContextFromContext(logx.Start(ctx, "disabled")).IsValid())
+
+	// Output为none且未开启追踪等，与Disable相同
+	logx.Init(logx.Config{Output: "none"}, "local-test")
+	assert.Equal(t, propagator, otel.GetTextMapPropagator())
+	assert.False(t, logx.Enabled(ctx, "error"))
+}
@@ -0,0 +1,195 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+// lastEntry 读取日志文件的最后一条日志
+func lastEntry(t *testing.T, file string) map[string]interface{} {
+	content, _ := os.ReadFile(file)
+	lines := bytes.Split(bytes.TrimSpace(content), []byte("\n"))
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(lines[len(lines)-1], &entry))
+	return entry
+}
+
+func TestGCPEncoder(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "run.log")
+	logx.Init(logx.Config{
+		Output:             "file",
+		File:               file,
+		Encoder:            "gcp",
+		Level:              "info",
+		GCPProject:         "my-project",
+		EnableTrace:        true,
+		TracerProviderType: "file",
+	}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+
+	ctx := logx.Start(context.Background(), "gcp")
+	logx.Warn(ctx, "hello gcp")
+	logx.End(ctx)
+
+	entry := lastEntry(t, file)
+	assert.Equal(t, "WARNING", entry["severity"])
+	assert.Equal(t, "hello gcp", entry["message"])
+	assert.Equal(t, "projects/my-project/traces/"+logx.TraceID(ctx), entry["logging.googleapis.com/trace"])
+	assert.Equal(t, logx.SpanID(ctx), entry["logging.googleapis.com/spanId"])
+	location, _ := entry["logging.googleapis.com/sourceLocation"].(map[string]interface{})
+	assert.Contains(t, location["file"], "encoder_test.go")
+	assert.Contains(t, location["function"], "TestGCPEncoder")
+}
+
+func TestECSEncoder(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "run.log")
+	logx.Init(logx.Config{
+		Output:             "file",
+		File:               file,
+		Encoder:            "ecs",
+		Level:              "info",
+		EnableTrace:        true,
+		TracerProviderType: "file",
+	}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+
+	ctx := logx.Start(context.Background(), "ecs")
+	logx.Error(ctx, "hello ecs", logx.ErrStack(errors.New("boom")))
+	logx.End(ctx)
+
+	entry := lastEntry(t, file)
+	assert.Equal(t, "error", entry["log.level"])
+	assert.Equal(t, "hello ecs", entry["message"])
+	assert.NotEmpty(t, entry["@timestamp"])
+	assert.NotEmpty(t, entry["ecs.version"])
+	assert.Equal(t, logx.TraceID(ctx), entry["trace.id"])
+	assert.Equal(t, logx.SpanID(ctx), entry["span.id"])
+	assert.Equal(t, "boom", entry["error.message"])
+	assert.Contains(t, entry["error.stack_trace"], "TestECSEncoder")
+	assert.NotContains(t, entry, "trace_id")
+}
+
+// TestECSFieldNames 错误链及添加命名空间前缀的字段同样使用ECS的字段名，Namespace嵌套的字段不变
+func TestECSFieldNames(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "run.log")
+	logx.Init(logx.Config{
+		Output:             "file",
+		File:               file,
+		Encoder:            "ecs",
+		Level:              "info",
+		AttributeNamespace: "app",
+	}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+
+	err := fmt.Errorf("query: %w", errors.New("boom"))
+	logx.Error(context.Background(), "hello ecs",
+		logx.ErrStack(err),
+		logx.String("user", "u1"),
+		logx.Namespace("upstream"),
+		logx.String("error", "timeout"),
+	)
+
+	content, _ := os.ReadFile(file)
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(content, &entry))
+	assert.Equal(t, "query: boom", entry["error.message"])
+	assert.Equal(t, []interface{}{"query: boom", "boom"}, entry["error.chain"])
+	assert.Contains(t, entry["error.stack_trace"], "TestECSFieldNames")
+	assert.Equal(t, "u1", entry["app.user"])
+	assert.Equal(t, map[string]interface{}{"error": "timeout"}, entry["app.upstream"])
+	for _, key := range []string{"app.error", "app.error_chain", "app.error_stack", "error_chain"} {
+		assert.NotContains(t, entry, key)
+	}
+}
+
+func TestECSErrorFile(t *testing.T) {
+	dir := t.TempDir()
+	logx.Init(logx.Config{
+		Output:    "file",
+		File:      filepath.Join(dir, "run.log"),
+		ErrorFile: filepath.Join(dir, "error.log"),
+		Encoder:   "ecs",
+		Level:     "info",
+	}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+
+	logx.Info(context.Background(), "ecs info")
+	logx.Error(context.Background(), "ecs error", logx.Err(errors.New("boom")))
+
+	run, _ := os.ReadFile(filepath.Join(dir, "run.log"))
+	errs, _ := os.ReadFile(filepath.Join(dir, "error.log"))
+	assert.Contains(t, string(run), "ecs info")
+	assert.NotContains(t, string(run), "ecs error")
+	assert.Contains(t, string(errs), "ecs error")
+	assert.NotContains(t, string(errs), "ecs info")
+	assert.Equal(t, "boom", lastEntry(t, filepath.Join(dir, "error.log"))["error.message"])
+}
+
+func TestLogfmtEncoder(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "run.log")
+	logx.Init(logx.Config{
+		Output:  "file",
+		File:    file,
+		Encoder: "logfmt",
+		Level:   "info",
+	}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+
+	logx.Info(context.Background(), "hello logfmt",
+		logx.Int("n", 1),
+		logx.String("user", "u1"),
+		logx.String("quote", `a "b"`),
+		logx.StringSlice("tags", []string{"x", "y"}),
+	)
+
+	content, _ := os.ReadFile(file)
+	line := string(content)
+	assert.Contains(t, line, `msg="hello logfmt"`)
+	assert.Contains(t, line, ` n=1 user=u1 quote="a \"b\"" tags="[\"x\",\"y\"]"`)
+	assert.True(t, strings.HasPrefix(line, `level=info time="`))
+	assert.True(t, strings.HasSuffix(line, "\n"))
+}
+
+func TestEncoderKeys(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "run.log")
+	logx.Init(logx.Config{
+		Output:     "file",
+		File:       file,
+		Level:      "info",
+		TimeKey:    "ts",
+		LevelKey:   "lvl",
+		MessageKey: "message",
+		CallerKey:  "-",
+		TimeFormat: "epochmillis",
+	}, "local-test")
+	logx.Info(context.Background(), "hello keys")
+	entry := lastEntry(t, file)
+	assert.Equal(t, "info", entry["lvl"])
+	assert.Equal(t, "hello keys", entry["message"])
+	assert.InDelta(t, time.Now().UnixMilli(), entry["ts"], 5000)
+	assert.NotContains(t, entry, "caller")
+	assert.NotContains(t, entry, "time")
+
+	logx.Init(logx.Config{
+		Output:     "file",
+		File:       file,
+		Level:      "info",
+		TimeFormat: "rfc3339",
+		TimeZone:   "UTC",
+	}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	logx.Info(context.Background(), "hello utc")
+	entry = lastEntry(t, file)
+	assert.True(t, strings.HasSuffix(entry["time"].(string), "Z"))
+}
@@ -0,0 +1,76 @@
+package logx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestOTelEnv(t *testing.T) {
+	t.Setenv("OTEL_SERVICE_NAME", "env-service")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://127.0.0.1:4318")
+	t.Setenv("OTEL_TRACES_SAMPLER", "always_off")
+	t.Setenv("OTEL_RESOURCE_ATTRIBUTES", "deployment.environment=prod,service.version=v2,team=a%20b")
+
+	logx.Init(logx.Config{EnableTrace: true}, "", logx.String("service.version", "v1"))
+	ctx := logx.Start(context.Background(), "test")
+	defer logx.End(ctx)
+	assert.False(t, oteltrace.SpanContextFromContext(ctx).IsSampled())
+
+	logx.Init(logx.Config{EnableTrace: true, TraceSampleRatio: 1}, "", logx.String("service.version", "v1"))
+	ctx = logx.Start(context.Background(), "test")
+	defer logx.End(ctx)
+	assert.True(t, oteltrace.SpanContextFromContext(ctx).IsSampled())
+	resource := oteltrace.SpanFromContext(ctx).(sdktrace.ReadOnlySpan).Resource().Attributes()
+	assert.Contains(t, resource, attribute.String("service.name", "env-service"))
+	assert.Contains(t, resource, attribute.String("service.version", "v1"))
+	assert.Contains(t, resource, attribute.String("deployment.environment", "prod"))
+	assert.Contains(t, resource, attribute.String("team", "a b"))
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("LOGX_OUTPUT", "file")
+	t.Setenv("LOGX_LEVEL", "debug")
+	t.Setenv("LOGX_MAX_SIZE", "10")
+	t.Setenv("LOGX_COMPRESS", "true")
+	t.Setenv("LOGX_KAFKA_BROKERS", "a:9092, b:9092")
+	t.Setenv("LOGX_KAFKA_BATCH_TIMEOUT", "2s")
+	t.Setenv("LOGX_LOG_SAMPLING_INITIAL", "5")
+	t.Setenv("LOGX_SAMPLE_RULES", `[{"route":"/healthz","ratio":0}]`)
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318")
+	t.Setenv("OTEL_TRACES_SAMPLER", "parentbased_traceidratio")
+	t.Setenv("OTEL_TRACES_SAMPLER_ARG", "0.1")
+
+	conf, err := logx.ConfigFromEnv()
+	assert.NoError(t, err)
+	assert.Equal(t, "file", conf.Output)
+	assert.Equal(t, "debug", conf.Level)
+	assert.Equal(t, 10, conf.MaxSize)
+	assert.True(t, conf.Compress)
+	assert.Equal(t, []string{"a:9092", "b:9092"}, conf.KafkaBrokers)
+	assert.Equal(t, 2*time.Second, conf.KafkaBatchTimeout)
+	assert.Equal(t, 5, conf.LogSampling.Initial)
+	assert.Equal(t, []logx.SampleRule{{Route: "/healthz"}}, conf.SampleRules)
+	assert.Equal(t, "collector:4318", conf.OTLPEndpoint)
+	assert.Equal(t, "/v1/traces", conf.OTLPEndpointURLPath)
+	assert.True(t, conf.OLTPInsecure)
+	assert.True(t, conf.EnableTrace)
+	assert.Equal(t, "parentbased_ratio", conf.Sampler)
+	assert.Equal(t, 0.1, conf.TraceSampleRatio)
+
+	t.Setenv("LOGX_OTLP_ENDPOINT", "otel:4317")
+	t.Setenv("LOGX_ENABLE_TRACE", "false")
+	t.Setenv("LOGX_MAX_AGE", "7d")
+	t.Setenv("LOGX_DEBUG", "yes")
+	conf, err = logx.ConfigFromEnv()
+	assert.Equal(t, "otel:4317", conf.OTLPEndpoint)
+	assert.False(t, conf.EnableTrace)
+	assert.ErrorContains(t, err, "LOGX_MAX_AGE")
+	assert.ErrorContains(t, err, "LOGX_DEBUG")
+}
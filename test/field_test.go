@@ -0,0 +1,161 @@
+package logx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestFieldsToKeyValues(t *testing.T) {
+	now := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	kvs := logx.FieldsToKeyValues(
+		logx.Uint("uint", 1),
+		logx.Uint64("uint64", math.MaxUint64),
+		logx.Float32("float32", 1.5),
+		logx.Duration("duration", time.Second),
+		logx.Time("time", now),
+		logx.ByteString("byteString", []byte("foo")),
+		logx.Binary("binary", []byte("foo")),
+	)
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.Int64("uint", 1),
+		attribute.String("uint64", "18446744073709551615"),
+		attribute.Float64("float32", 1.5),
+		attribute.String("duration", "1s"),
+		attribute.String("time", "2024-05-01T08:00:00Z"),
+		attribute.String("byteString", "foo"),
+		attribute.String("binary", "Zm9v"),
+	}, kvs)
+}
+
+func TestErrStack(t *testing.T) {
+	err := fmt.Errorf("wrap: %w", errors.New("foo"))
+	kvs := logx.FieldsToKeyValues(logx.ErrStack(err))
+	assert.Len(t, kvs, 3)
+	assert.Equal(t, attribute.String("exception.type", "*fmt.wrapError"), kvs[0])
+	assert.Equal(t, attribute.String("exception.message", "wrap: foo"), kvs[1])
+	assert.Contains(t, kvs[2].Value.AsString(), "TestErrStack")
+}
+
+func TestAttributeNamespace(t *testing.T) {
+	logx.Init(logx.Config{AttributeNamespace: "app"}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	kvs := logx.FieldsToKeyValues(logx.String("user_id", "1"), logx.Int("app.tenant", 2))
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("app.user_id", "1"),
+		attribute.Int("app.tenant", 2),
+	}, kvs)
+}
+
+type testUser struct {
+	Name string
+	Age  int
+	Tags []string
+}
+
+func (u testUser) MarshalLogObject(enc logx.ObjectEncoder) error {
+	enc.AddString("name", u.Name)
+	enc.AddInt("age", u.Age)
+	return enc.AddArray("tags", testTags(u.Tags))
+}
+
+type testTags []string
+
+func (tags testTags) MarshalLogArray(enc logx.ArrayEncoder) error {
+	for _, tag := range tags {
+		enc.AppendString(tag)
+	}
+	return nil
+}
+
+func TestObjectArray(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "run.log")
+	logx.Init(logx.Config{Output: "file", File: file, Level: "info"}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	user := testUser{Name: "foo", Age: 18, Tags: []string{"a", "b"}}
+	logx.Info(context.Background(), "object", logx.Object("user", user), logx.Array("tags", testTags{"x"}))
+
+	entry := lastEntry(t, file)
+	assert.Equal(t, map[string]interface{}{"name": "foo", "age": float64(18), "tags": []interface{}{"a", "b"}}, entry["user"])
+	assert.Equal(t, []interface{}{"x"}, entry["tags"])
+
+	kvs := logx.FieldsToKeyValues(logx.Object("user", user), logx.Array("tags", testTags{"x"}))
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.Int("user.age", 18),
+		attribute.String("user.name", "foo"),
+		attribute.StringSlice("user.tags", []string{"a", "b"}),
+		attribute.StringSlice("tags", []string{"x"}),
+	}, kvs)
+}
+
+func TestNamespace(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "run.log")
+	logx.Init(logx.Config{Output: "file", File: file, Level: "info"}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	fields := []logx.Field{
+		logx.String("route", "/user"),
+		logx.Namespace("http"),
+		logx.String("method", "GET"),
+		logx.Int("status", 200),
+		logx.Namespace("peer"),
+		logx.String("ip", "127.0.0.1"),
+	}
+	logx.Info(context.Background(), "namespace", fields...)
+
+	entry := lastEntry(t, file)
+	assert.Equal(t, "/user", entry["route"])
+	assert.Equal(t, map[string]interface{}{
+		"method": "GET",
+		"status": float64(200),
+		"peer":   map[string]interface{}{"ip": "127.0.0.1"},
+	}, entry["http"])
+
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("route", "/user"),
+		attribute.String("http.method", "GET"),
+		attribute.Int("http.status", 200),
+		attribute.String("http.peer.ip", "127.0.0.1"),
+	}, logx.FieldsToKeyValues(fields...))
+}
+
+func TestFieldsGetter(t *testing.T) {
+	fields := logx.Fields{
+		logx.String("name", "foo"),
+		logx.Int("age", 1),
+		logx.Uint64("big", math.MaxUint64),
+		logx.Float32("score", 1.5),
+		logx.Bool("vip", true),
+		logx.Duration("latency", time.Second),
+		logx.Int64("age", 2),
+	}
+	name, ok := fields.GetString("name")
+	assert.True(t, ok)
+	assert.Equal(t, "foo", name)
+	age, _ := fields.GetInt("age")
+	assert.Equal(t, int64(2), age)
+	_, ok = fields.GetInt("big")
+	assert.False(t, ok)
+	_, ok = fields.GetInt("name")
+	assert.False(t, ok)
+	score, _ := fields.GetFloat("score")
+	assert.Equal(t, 1.5, score)
+	vip, _ := fields.GetBool("vip")
+	assert.True(t, vip)
+	latency, _ := fields.GetDuration("latency")
+	assert.Equal(t, time.Second, latency)
+
+	var keys []string
+	fields.Range(func(key string, value interface{}) bool {
+		keys = append(keys, key)
+		return key != "score"
+	})
+	assert.Equal(t, []string{"name", "age", "big", "score"}, keys)
+}
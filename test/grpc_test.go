@@ -0,0 +1,91 @@
+package logx
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGRPCInterceptors(t *testing.T) {
+	spans := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.AlwaysSample()), sdktrace.WithSpanProcessor(spans))
+	logx.InitWithOptions(logx.Config{EnableTrace: true, SpanNameTimeFormat: "none"}, "local-test", logx.WithTracerProvider(provider))
+	defer logx.Init(logx.Config{}, "local-test")
+
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(logx.UnaryServerInterceptor()),
+		grpc.StreamInterceptor(logx.StreamServerInterceptor()),
+	)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("ok", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(logx.UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(logx.StreamClientInterceptor()),
+	)
+	assert.NoError(t, err)
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	// 成功的调用，服务端的span为客户端span的子span
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "ok"})
+	assert.NoError(t, err)
+	ended := spans.Ended()
+	assert.Len(t, ended, 2)
+	serverSpan, clientSpan := ended[0], ended[1]
+	assert.Equal(t, oteltrace.SpanKindServer, serverSpan.SpanKind())
+	assert.Equal(t, oteltrace.SpanKindClient, clientSpan.SpanKind())
+	assert.Equal(t, "grpc.health.v1.Health/Check", serverSpan.Name())
+	assert.Equal(t, clientSpan.SpanContext().SpanID(), serverSpan.Parent().SpanID())
+	assert.Contains(t, serverSpan.Attributes(), attribute.String("grpc.code", "OK"))
+	assert.Contains(t, serverSpan.Attributes(), attribute.String("rpc.method", "Check"))
+	assert.Equal(t, codes.Unset, serverSpan.Status().Code)
+
+	// 失败的调用记录状态码，span状态为错误
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	assert.Equal(t, grpccodes.NotFound, status.Code(err))
+	ended = spans.Ended()
+	assert.Len(t, ended, 4)
+	for _, span := range ended[2:] {
+		assert.Contains(t, span.Attributes(), attribute.String("grpc.code", "NotFound"))
+		assert.Contains(t, span.Attributes(), attribute.Int("rpc.grpc.status_code", int(grpccodes.NotFound)))
+		assert.Equal(t, codes.Error, span.Status().Code)
+	}
+
+	// 流式调用，服务端结束时结束客户端的span
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "ok"})
+	assert.NoError(t, err)
+	_, err = stream.Recv()
+	assert.NoError(t, err)
+	cancel()
+	_, err = stream.Recv()
+	assert.Equal(t, grpccodes.Canceled, status.Code(err))
+	assert.Eventually(t, func() bool { return len(spans.Ended()) == 6 }, time.Second, 10*time.Millisecond)
+	for _, span := range spans.Ended()[4:] {
+		assert.Equal(t, "grpc.health.v1.Health/Watch", span.Name())
+		assert.Contains(t, span.Attributes(), attribute.String("grpc.code", "Canceled"))
+	}
+}
@@ -0,0 +1,58 @@
+package logx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterHook(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "run.log")
+	logx.Init(logx.Config{Output: "file", File: file, Level: "info"}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	defer logx.ResetHooks()
+
+	logx.RegisterHook(func(e *logx.Entry) {
+		if e.Message == "healthz" {
+			e.Drop = true
+			return
+		}
+		fields := e.Fields[:0]
+		for _, f := range e.Fields {
+			switch f.Key {
+			case "internal":
+				continue
+			case "uid":
+				f.Key = "user_id"
+			}
+			fields = append(fields, f)
+		}
+		e.Fields = fields
+	})
+	logx.RegisterHook(func(e *logx.Entry) {
+		if e.Level != "" {
+			e.Message = "[" + e.Level + "] " + e.Message
+		}
+	})
+
+	ctx := logx.WithFields(context.Background(), logx.String("internal", "x"))
+	logx.Info(ctx, "login", logx.Int("uid", 1))
+	logx.Info(ctx, "healthz")
+
+	content, _ := os.ReadFile(file)
+	assert.Equal(t, 1, strings.Count(string(content), "\n"))
+	entry := lastEntry(t, file)
+	assert.Equal(t, "[info] login", entry["msg"])
+	assert.Equal(t, float64(1), entry["user_id"])
+	assert.NotContains(t, entry, "uid")
+	assert.NotContains(t, entry, "internal")
+	// context中保存的字段不受Hook的修改影响
+	logx.ResetHooks()
+	logx.Info(ctx, "after reset")
+	assert.Equal(t, "x", lastEntry(t, file)["internal"])
+}
@@ -0,0 +1,43 @@
+package logx
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"google.golang.org/grpc"
+)
+
+func TestInstrument(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logx.InitWithOptions(logx.Config{EnableTrace: true, TracerProviderType: "file", InstrumentRecovery: true}, "local-test", logx.WithZapCore(core))
+
+	engine := gin.New()
+	mux := http.NewServeMux()
+	server := &http.Server{Handler: mux}
+	assert.Nil(t, logx.Instrument("test", engine, server))
+	assert.NotNil(t, server.ErrorLog)
+	assert.Error(t, logx.Instrument("test", 1))
+	// grpc,database/sql需在创建时设置
+	assert.ErrorContains(t, logx.Instrument("test", grpc.NewServer()), "logx.UnaryServerInterceptor")
+	assert.ErrorContains(t, logx.Instrument("test", &sql.DB{}), "sqltrace.Open")
+
+	engine.GET("/panic", func(c *gin.Context) { panic("boom") })
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, 1, logs.FilterMessage("panic recovered").Len())
+
+	var traceID string
+	mux.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		traceID = logx.TraceID(r.Context())
+	})
+	server.Handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1", nil))
+	assert.Len(t, traceID, 32)
+}
@@ -0,0 +1,116 @@
+package logx
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetLevel(t *testing.T) {
+	logx.Init(logx.Config{}, "local-test")
+	assert.Nil(t, logx.SetLevel("warn"))
+	assert.NotNil(t, logx.SetLevel("foo"))
+
+	w := httptest.NewRecorder()
+	logx.LevelHandler().ServeHTTP(w, httptest.NewRequest("GET", "/level", nil))
+	assert.JSONEq(t, `{"level":"warn"}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	logx.LevelHandler().ServeHTTP(w, httptest.NewRequest("PUT", "/level", strings.NewReader(`{"level":"debug"}`)))
+	assert.JSONEq(t, `{"level":"debug"}`, w.Body.String())
+}
+
+func TestPanic(t *testing.T) {
+	logx.Init(logx.Config{Output: "console", Level: "info"}, "local-test")
+	assert.PanicsWithValue(t, "boom", func() {
+		logx.Panic(context.Background(), "boom", logx.String("key", "value"))
+	})
+	assert.NotPanics(t, func() {
+		logx.DPanic(context.Background(), "boom")
+	})
+}
+
+func TestErrorBoost(t *testing.T) {
+	conf := logx.Config{Output: "console", Level: "error", ErrorBoost: 2, EnableTrace: true, TracerProviderType: "file"}
+	logx.Init(conf, "local-test")
+
+	ctx := logx.Start(context.Background(), "test")
+	defer logx.End(ctx)
+	logx.Debug(ctx, "before error")
+	logx.Error(ctx, "error")
+	logx.Debug(ctx, "after error 1")
+	logx.Info(ctx, "after error 2")
+	logx.Debug(ctx, "after error 3")
+	logx.Debug(context.Background(), "other trace")
+
+	summary, _ := logx.Shutdown(context.Background())
+	assert.Equal(t, map[string]int64{"debug": 1, "info": 1, "error": 1}, summary.Entries)
+}
+
+// countMarshaler 记录MarshalJSON的调用次数
+type countMarshaler struct{ n *atomic.Int32 }
+
+func (m countMarshaler) MarshalJSON() ([]byte, error) {
+	m.n.Add(1)
+	return []byte(`"payload"`), nil
+}
+
+func TestEnabled(t *testing.T) {
+	ctx := context.Background()
+	logx.Init(logx.Config{Output: "console", Level: "info"}, "local-test")
+	assert.False(t, logx.Enabled(ctx, "debug"))
+	assert.True(t, logx.Enabled(ctx, "info"))
+	assert.False(t, logx.Enabled(ctx, "foo"))
+
+	var n atomic.Int32
+	logx.Debug(ctx, "skipped", logx.Any("payload", countMarshaler{&n}))
+	assert.Equal(t, int32(0), n.Load())
+	logx.Info(ctx, "logged", logx.Any("payload", countMarshaler{&n}))
+	assert.Equal(t, int32(1), n.Load())
+
+	// 开启追踪时，debug日志仍会记录为span事件
+	logx.Init(logx.Config{Output: "console", Level: "info", EnableTrace: true, TracerProviderType: "file"}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	spanCtx := logx.Start(ctx, "enabled")
+	defer logx.End(spanCtx)
+	assert.True(t, logx.Enabled(spanCtx, "debug"))
+	assert.False(t, logx.Enabled(ctx, "debug"))
+}
+
+func TestErrorBuffer(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "run.log")
+	logx.Init(logx.Config{Output: "file", File: file, Level: "error", ErrorBuffer: 2, EnableTrace: true, TracerProviderType: "file"}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+
+	ctx := logx.Start(context.Background(), "test")
+	defer logx.End(ctx)
+	other := logx.Start(context.Background(), "other")
+	defer logx.End(other)
+	logx.Debug(ctx, "debug 1")
+	logx.Debug(ctx, "debug 2")
+	logx.Infof(ctx, "info %d", 3)
+	logx.Debug(other, "other trace")
+	content, _ := os.ReadFile(file)
+	assert.Empty(t, content)
+
+	logx.Error(ctx, "error")
+	content, _ = os.ReadFile(file)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 3)
+	assert.Contains(t, lines[0], `"msg":"debug 2"`)
+	assert.Contains(t, lines[0], "level_test.go")
+	assert.Contains(t, lines[1], `"msg":"info 3"`)
+	assert.Contains(t, lines[2], `"msg":"error"`)
+
+	// 写入后清除，再次发生错误时不重复写入
+	logx.Error(ctx, "error again")
+	content, _ = os.ReadFile(file)
+	assert.Equal(t, 4, strings.Count(string(content), "\n"))
+}
@@ -0,0 +1,51 @@
+package logx
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestFieldLimits(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "run.log")
+	logx.Init(logx.Config{
+		Output:         "file",
+		File:           file,
+		Level:          "info",
+		MaxFieldLength: 8,
+		MaxFields:      3,
+	}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+
+	payload := map[string]string{"body": strings.Repeat("x", 100)}
+	fields := []logx.Field{
+		logx.String("name", "中文中文中文"),
+		logx.Any("payload", payload),
+		logx.Int("n", 1),
+		logx.String("dropped", "x"),
+	}
+	logx.Info(context.Background(), "limit", fields...)
+
+	entry := lastEntry(t, file)
+	assert.Equal(t, true, entry["truncated"])
+	assert.Equal(t, "中文...", entry["name"])
+	assert.Equal(t, `{"body":...`, entry["payload"])
+	assert.Equal(t, float64(1), entry["n"])
+	assert.NotContains(t, entry, "dropped")
+
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.Bool("truncated", true),
+		attribute.String("name", "中文..."),
+		attribute.String("payload", `{"body":...`),
+		attribute.Int("n", 1),
+	}, logx.FieldsToKeyValues(fields...))
+
+	// 未超过限制时不添加truncated
+	assert.Equal(t, []attribute.KeyValue{attribute.String("name", "foo")},
+		logx.FieldsToKeyValues(logx.String("name", "foo")))
+}
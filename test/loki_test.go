@@ -0,0 +1,43 @@
+package logx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFatalCodeLoki 在子进程中调用FatalCode，进程退出前需已推送到loki
+func TestFatalCodeLoki(t *testing.T) {
+	if server := os.Getenv("LOGX_LOKI_SERVER"); server != "" {
+		logx.Init(logx.Config{Output: "console", LokiServer: server}, "local-test")
+		logx.FatalCode(context.Background(), 3, "fatal to loki")
+		return
+	}
+	bodies := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+	}))
+	defer server.Close()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestFatalCodeLoki$")
+	cmd.Env = append(os.Environ(), "LOGX_LOKI_SERVER="+server.URL)
+	err := cmd.Run()
+	exitErr, ok := err.(*exec.ExitError)
+	assert.True(t, ok)
+	assert.Equal(t, 3, exitErr.ExitCode())
+	select {
+	case body := <-bodies:
+		assert.Contains(t, body, "fatal to loki")
+		assert.Contains(t, body, "loki_test.go")
+	default:
+		t.Fatal("loki push not received before exit")
+	}
+}
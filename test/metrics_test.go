@@ -0,0 +1,136 @@
+package logx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/noop"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// fakeMeterProvider 记录指标的MeterProvider
+type fakeMeterProvider struct {
+	noop.MeterProvider
+	meter *fakeMeter
+}
+
+func (p fakeMeterProvider) Meter(string, ...metric.MeterOption) metric.Meter {
+	return p.meter
+}
+
+type fakeMeter struct {
+	noop.Meter
+	values   map[string]float64
+	callback metric.Callback
+}
+
+type fakeCounter struct {
+	noop.Int64Counter
+	name   string
+	values map[string]float64
+}
+
+func (c fakeCounter) Add(ctx context.Context, incr int64, opts ...metric.AddOption) {
+	c.values[c.name] += float64(incr)
+}
+
+type fakeHistogram struct {
+	noop.Float64Histogram
+	name   string
+	values map[string]float64
+}
+
+func (h fakeHistogram) Record(ctx context.Context, value float64, opts ...metric.RecordOption) {
+	h.values[h.name] += value
+}
+
+type fakeObservableCounter struct {
+	noop.Int64ObservableCounter
+	name string
+}
+
+type fakeObserver struct {
+	noop.Observer
+	values map[string]float64
+}
+
+func (o fakeObserver) ObserveInt64(obsrv metric.Int64Observable, value int64, opts ...metric.ObserveOption) {
+	name := obsrv.(fakeObservableCounter).name
+	if set := metric.NewObserveConfig(opts).Attributes(); set.Len() > 0 {
+		level, _ := set.Value(attribute.Key("level"))
+		name += "." + level.AsString()
+	}
+	o.values[name] = float64(value)
+}
+
+func (m *fakeMeter) Int64Counter(name string, opts ...metric.Int64CounterOption) (metric.Int64Counter, error) {
+	return fakeCounter{name: name, values: m.values}, nil
+}
+
+func (m *fakeMeter) Float64Histogram(name string, opts ...metric.Float64HistogramOption) (metric.Float64Histogram, error) {
+	return fakeHistogram{name: name, values: m.values}, nil
+}
+
+func (m *fakeMeter) Int64ObservableCounter(name string, opts ...metric.Int64ObservableCounterOption) (metric.Int64ObservableCounter, error) {
+	return fakeObservableCounter{name: name}, nil
+}
+
+func (m *fakeMeter) RegisterCallback(f metric.Callback, instruments ...metric.Observable) (metric.Registration, error) {
+	m.callback = f
+	return noop.Registration{}, nil
+}
+
+func TestInitMetrics(t *testing.T) {
+	core, _ := observer.New(zap.DebugLevel)
+	logx.InitWithOptions(logx.Config{EnableTrace: true, TracerProviderType: "file"}, "local-test", logx.WithZapCore(core))
+	meter := &fakeMeter{values: map[string]float64{}}
+	assert.Nil(t, logx.InitMetrics(logx.Config{}, logx.WithMeterProvider(fakeMeterProvider{meter: meter})))
+
+	ctx := logx.Start(context.Background(), "test")
+	logx.Counter(ctx, "order.created", 1)
+	logx.Counter(ctx, "order.created", 2)
+	logx.Histogram(ctx, "order.amount", 99.5)
+	logx.Error(ctx, "foo")
+	logx.End(ctx)
+	assert.Equal(t, float64(3), meter.values["order.created"])
+	assert.Equal(t, 99.5, meter.values["order.amount"])
+
+	o := fakeObserver{values: map[string]float64{}}
+	assert.Nil(t, meter.callback(context.Background(), o))
+	assert.Equal(t, float64(1), o.values["logx.log.entries.error"])
+	assert.Equal(t, float64(1), o.values["logx.spans.started"])
+	assert.Equal(t, float64(1), o.values["logx.spans.ended"])
+}
+
+func TestInitMetricsOTLP(t *testing.T) {
+	var received atomic.Bool
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.URL.Path == "/v1/metrics" && strings.Contains(string(body), "order.created") {
+			received.Store(true)
+		}
+	}))
+	defer collector.Close()
+
+	logx.Init(logx.Config{}, "local-test")
+	assert.Nil(t, logx.InitMetrics(logx.Config{
+		OTLPEndpoint:    strings.TrimPrefix(collector.URL, "http://"),
+		OLTPInsecure:    true,
+		MetricsInterval: 20 * time.Millisecond,
+	}))
+	logx.Counter(context.Background(), "order.created", 1)
+	assert.Eventually(t, received.Load, time.Second, 10*time.Millisecond)
+	_, err := logx.Shutdown(context.Background())
+	assert.Nil(t, err)
+}
@@ -0,0 +1,31 @@
+package logx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWithZapCore(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logx.InitWithOptions(logx.Config{}, "local-test", logx.WithResource(logx.String("service.version", "v1")), logx.WithZapCore(core))
+
+	ctx := logx.WithFields(context.Background(), logx.String("user_id", "1"))
+	logx.Info(ctx, "hello", logx.Int("age", 30))
+	entries := logs.All()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "hello", entries[0].Message)
+	assert.Equal(t, map[string]interface{}{"user_id": "1", "age": int64(30)}, entries[0].ContextMap())
+}
+
+// TestInitFields Init仍可传入展开的[]Field作为应用属性
+func TestInitFields(t *testing.T) {
+	attributes := []logx.Field{logx.String("service_version", "v1")}
+	logx.Init(logx.Config{}, "local-test", attributes...)
+	assert.Equal(t, "v1", logx.LokiLabel["service_version"])
+	assert.Equal(t, "local-test", logx.LokiLabel["service_name"])
+}
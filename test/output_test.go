@@ -0,0 +1,270 @@
+package logx
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/itmisx/logx"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+)
+
+func TestElasticsearchOutput(t *testing.T) {
+	var attempts atomic.Int32
+	bodies := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// 第一次请求失败，验证重试
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "/_bulk", r.URL.Path)
+		username, password, _ := r.BasicAuth()
+		assert.Equal(t, "elastic", username)
+		assert.Equal(t, "secret", password)
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	logx.Init(logx.Config{
+		Output:          "elasticsearch",
+		Debug:           true,
+		ESServer:        server.URL,
+		ESUsername:      "elastic",
+		ESPassword:      "secret",
+		ESFlushInterval: time.Hour,
+	}, "local-test")
+	logx.Info(context.Background(), "hello es", logx.String("k", "v"))
+	logx.Shutdown(context.Background())
+
+	select {
+	case body := <-bodies:
+		lines := strings.Split(strings.TrimSuffix(body, "\n"), "\n")
+		if assert.Len(t, lines, 2) {
+			index := "logs-local-test-" + time.Now().Format("2006.01.02")
+			assert.JSONEq(t, `{"index":{"_index":"`+index+`"}}`, lines[0])
+			var doc map[string]interface{}
+			json.Unmarshal([]byte(lines[1]), &doc)
+			assert.Equal(t, "hello es", doc["msg"])
+			assert.Equal(t, "v", doc["k"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("bulk request not received")
+	}
+	assert.Equal(t, int32(2), attempts.Load())
+}
+
+func TestFluentOutput(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer listener.Close()
+	messages := make(chan []interface{}, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handle := &codec.MsgpackHandle{}
+		handle.RawToString = true
+		var message []interface{}
+		if err := codec.NewDecoder(conn, handle).Decode(&message); err == nil {
+			messages <- message
+		}
+	}()
+
+	logx.Init(logx.Config{
+		Output:        "fluent",
+		Level:         "info",
+		FluentAddress: listener.Addr().String(),
+		FluentTag:     "app.{service}.{level}",
+	}, "local-test")
+	logx.Info(context.Background(), "hello fluent", logx.Int("n", 1))
+	logx.Shutdown(context.Background())
+
+	select {
+	case message := <-messages:
+		if assert.Len(t, message, 3) {
+			assert.Equal(t, "app.local-test.info", message[0])
+			record, _ := message[2].(map[interface{}]interface{})
+			assert.Equal(t, "hello fluent", record["msg"])
+			assert.EqualValues(t, 1, record["n"])
+		}
+	case <-time.After(time.Second):
+		t.Fatal("fluent message not received")
+	}
+}
+
+func TestCloudWatchOutput(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+	t.Setenv("AWS_REGION", "us-east-1")
+	var targets []string
+	var mu sync.Mutex
+	var put map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"))
+		target := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "Logs_20140328.")
+		mu.Lock()
+		defer mu.Unlock()
+		targets = append(targets, target)
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case target == "CreateLogStream" && len(targets) == 1:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"ResourceNotFoundException"}`))
+		case target == "PutLogEvents" && body["sequenceToken"] == nil:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type":"InvalidSequenceTokenException","expectedSequenceToken":"t1"}`))
+		case target == "PutLogEvents":
+			put = body
+			w.Write([]byte(`{"nextSequenceToken":"t2"}`))
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	logx.Init(logx.Config{
+		Output:             "cloudwatch",
+		Level:              "info",
+		CloudWatchGroup:    "group",
+		CloudWatchStream:   "stream",
+		CloudWatchEndpoint: server.URL,
+	}, "local-test")
+	logx.Info(context.Background(), "hello cloudwatch")
+	logx.Shutdown(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"CreateLogStream", "CreateLogGroup", "CreateLogStream", "PutLogEvents", "PutLogEvents"}, targets)
+	assert.Equal(t, "t1", put["sequenceToken"])
+	assert.Equal(t, "group", put["logGroupName"])
+	events := put["logEvents"].([]interface{})
+	if assert.Len(t, events, 1) {
+		assert.Contains(t, events[0].(map[string]interface{})["message"], "hello cloudwatch")
+	}
+}
+
+// TestCloudWatchTruncate 超过单条事件限制的日志按utf8字符的边界截断
+func TestCloudWatchTruncate(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "SECRET")
+	t.Setenv("AWS_REGION", "us-east-1")
+	var mu sync.Mutex
+	var messages []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "Logs_20140328.PutLogEvents" {
+			w.Write([]byte(`{}`))
+			return
+		}
+		var body struct {
+			LogEvents []struct {
+				Message string `json:"message"`
+			} `json:"logEvents"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		defer mu.Unlock()
+		for _, event := range body.LogEvents {
+			messages = append(messages, event.Message)
+		}
+		w.Write([]byte(`{"nextSequenceToken":"t1"}`))
+	}))
+	defer server.Close()
+
+	logx.Init(logx.Config{
+		Output:             "cloudwatch",
+		Level:              "info",
+		CloudWatchGroup:    "group",
+		CloudWatchStream:   "stream",
+		CloudWatchEndpoint: server.URL,
+	}, "local-test")
+	// 不同的前缀长度，保证截断位置落在多字节字符的中间
+	for i := 0; i < 3; i++ {
+		logx.Info(context.Background(), strings.Repeat("a", i)+strings.Repeat("中", 100000))
+	}
+	logx.Shutdown(context.Background())
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, messages, 3)
+	for _, message := range messages {
+		assert.LessOrEqual(t, len(message), 262144-26)
+		assert.Greater(t, len(message), 262144-26-3)
+		assert.True(t, utf8.ValidString(message))
+		assert.NotContains(t, message, string(utf8.RuneError))
+	}
+}
+
+// kafkaWriter 记录每次WriteMessages的批次
+type kafkaWriter struct {
+	mu      sync.Mutex
+	batches [][]kafka.Message
+	closed  bool
+}
+
+func (w *kafkaWriter) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.batches = append(w.batches, msgs)
+	return nil
+}
+
+func (w *kafkaWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return nil
+}
+
+func TestKafkaOutput(t *testing.T) {
+	writer := &kafkaWriter{}
+	logx.InitWithOptions(logx.Config{
+		Output:            "kafka",
+		Debug:             true,
+		KafkaBatchSize:    2,
+		KafkaBatchTimeout: time.Hour,
+	}, "local-test", logx.WithKafkaWriter(writer))
+	logx.Info(context.Background(), "hello kafka", logx.String("k", "v"))
+	logx.Info(context.Background(), "second")
+	// 满KafkaBatchSize条后发送
+	assert.Eventually(t, func() bool {
+		writer.mu.Lock()
+		defer writer.mu.Unlock()
+		return len(writer.batches) == 1
+	}, time.Second, 10*time.Millisecond)
+	logx.Info(context.Background(), "third")
+	logx.Shutdown(context.Background())
+
+	writer.mu.Lock()
+	defer writer.mu.Unlock()
+	assert.True(t, writer.closed)
+	if assert.Len(t, writer.batches, 2) {
+		assert.Len(t, writer.batches[0], 2)
+		assert.Len(t, writer.batches[1], 1)
+		value := writer.batches[0][0].Value
+		assert.False(t, strings.HasSuffix(string(value), "\n"))
+		var doc map[string]interface{}
+		assert.Nil(t, json.Unmarshal(value, &doc))
+		assert.Equal(t, "hello kafka", doc["msg"])
+		assert.Equal(t, "v", doc["k"])
+		assert.Contains(t, string(writer.batches[1][0].Value), `"msg":"third"`)
+	}
+}
@@ -0,0 +1,70 @@
+package logx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartitionFile(t *testing.T) {
+	dir := t.TempDir()
+	logx.Init(logx.Config{
+		Output:            "file",
+		File:              filepath.Join(dir, "{tenant}", "run.log"),
+		Level:             "info",
+		PartitionMaxFiles: 1,
+	}, "local-test")
+
+	logx.Info(context.Background(), "tenant a", logx.String("tenant", "a"))
+	ctx := logx.WithFields(context.Background(), logx.String("tenant", "b"))
+	logx.Info(ctx, "tenant b")
+	logx.Info(context.Background(), "no tenant")
+	logx.Info(context.Background(), "escape", logx.String("tenant", "../c"))
+
+	for file, msg := range map[string]string{
+		"a/run.log":       "tenant a",
+		"b/run.log":       "tenant b",
+		"default/run.log": "no tenant",
+		"__c/run.log":     "escape",
+	} {
+		content, err := os.ReadFile(filepath.Join(dir, file))
+		assert.Nil(t, err)
+		assert.Contains(t, string(content), msg)
+	}
+	assert.Nil(t, logx.Rotate())
+	logx.Init(logx.Config{}, "local-test")
+}
+
+func TestPartitionErrorFile(t *testing.T) {
+	dir := t.TempDir()
+	logx.Init(logx.Config{
+		Output:    "file",
+		File:      filepath.Join(dir, "{tenant}", "run.log"),
+		ErrorFile: filepath.Join(dir, "{tenant}", "error.log"),
+		Level:     "info",
+	}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	assert.Nil(t, logx.Config{
+		Output:    "file",
+		File:      filepath.Join(dir, "{tenant}", "run.log"),
+		ErrorFile: filepath.Join(dir, "error.log"),
+	}.Validate())
+
+	ctx := logx.WithFields(context.Background(), logx.String("tenant", "a"))
+	logx.Info(ctx, "info a")
+	logx.Error(ctx, "error a")
+	logx.Zap().Error("zap error a", logx.ZapContext(ctx))
+
+	run, _ := os.ReadFile(filepath.Join(dir, "a", "run.log"))
+	errs, _ := os.ReadFile(filepath.Join(dir, "a", "error.log"))
+	assert.Contains(t, string(run), "info a")
+	assert.NotContains(t, string(run), "error a")
+	assert.Contains(t, string(errs), `"msg":"error a"`)
+	assert.Contains(t, string(errs), "zap error a")
+	assert.NotContains(t, string(errs), "info a")
+	assert.Nil(t, logx.Rotate())
+}
@@ -0,0 +1,30 @@
+package logx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestPresetConfig(t *testing.T) {
+	dev := logx.DevelopmentConfig()
+	assert.NoError(t, dev.Validate())
+	assert.Equal(t, "debug", dev.Level)
+	assert.Equal(t, "file", dev.TracerProviderType)
+
+	prod := logx.ProductionConfig()
+	assert.NoError(t, prod.Validate())
+	assert.Equal(t, "info", prod.Level)
+	assert.Equal(t, "oltp", prod.TracerProviderType)
+	assert.Equal(t, "parentbased_ratio", prod.Sampler)
+
+	logx.Init(dev, "preset-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	assert.True(t, logx.Enabled(context.Background(), "debug"))
+	ctx := logx.Start(context.Background(), "preset")
+	defer logx.End(ctx)
+	assert.True(t, oteltrace.SpanContextFromContext(ctx).IsSampled())
+}
@@ -0,0 +1,264 @@
+package logx
+
+import (
+	"context"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/itmisx/logx"
+	"github.com/itmisx/logx/propagation/extract"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/contrib/propagators/b3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestPropagationHeaders(t *testing.T) {
+	logx.Init(logx.Config{PropagationHeaders: []string{"X-Request-ID", "X-Tenant"}}, "local-test")
+
+	router := gin.New()
+	router.Use(logx.GinMiddleware("local-test"))
+	router.GET("/user/:id", func(c *gin.Context) {
+		ctx := logx.Start(c.Request.Context(), "test")
+		defer logx.End(ctx)
+		request, _ := http.NewRequest("GET", "http://localhost:8080/test", nil)
+		assert.Nil(t, logx.HttpInject(ctx, request))
+		assert.Equal(t, "req-1", request.Header.Get("X-Request-ID"))
+		assert.Equal(t, "tenant-1", request.Header.Get("X-Tenant"))
+		assert.Equal(t, "", request.Header.Get("X-Other"))
+	})
+
+	r := httptest.NewRequest("GET", "/user/123", nil)
+	r.Header.Set("X-Request-ID", "req-1")
+	r.Header.Set("X-Tenant", "tenant-1")
+	r.Header.Set("X-Other", "other")
+	router.ServeHTTP(httptest.NewRecorder(), r)
+}
+
+func TestKafkaPropagation(t *testing.T) {
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file"}, "local-test")
+
+	ctx := logx.Start(context.Background(), "produce")
+	defer logx.End(ctx)
+	headers := map[string]string{}
+	logx.KafkaInject(ctx, headers)
+	assert.Contains(t, headers["b3"], logx.TraceID(ctx))
+
+	consumer := logx.KafkaExtract(context.Background(), headers)
+	assert.Equal(t, logx.TraceID(ctx), logx.TraceID(consumer))
+	consumer = logx.Start(consumer, "consume")
+	defer logx.End(consumer)
+	assert.Equal(t, logx.TraceID(ctx), logx.TraceID(consumer))
+	assert.NotEqual(t, logx.SpanID(ctx), logx.SpanID(consumer))
+}
+
+func TestInjectExtract(t *testing.T) {
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file"}, "local-test")
+
+	ctx := logx.Start(context.Background(), "job")
+	defer logx.End(ctx)
+	carrier := logx.Inject(ctx)
+	assert.NotEmpty(t, carrier)
+
+	worker := logx.Extract(context.Background(), carrier)
+	assert.Equal(t, logx.TraceID(ctx), logx.TraceID(worker))
+	assert.Equal(t, logx.SpanID(ctx), logx.SpanID(worker))
+	assert.Equal(t, "", logx.TraceID(logx.Extract(context.Background(), map[string]string{})))
+}
+
+func TestGinAccessLog(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logx.InitWithOptions(logx.Config{EnableTrace: true, TracerProviderType: "file"}, "local-test", logx.WithZapCore(core))
+
+	router := gin.New()
+	router.Use(logx.GinMiddleware("local-test", logx.GinAccessLog(), extract.WithSkipPaths("/health")))
+	router.GET("/health", func(c *gin.Context) {})
+	router.GET("/user/:id", func(c *gin.Context) {})
+	router.GET("/fail", func(c *gin.Context) {
+		c.Status(http.StatusServiceUnavailable)
+	})
+	for _, path := range []string{"/health", "/user/1", "/fail"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	entries := logs.FilterMessage("access").All()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, zapcore.InfoLevel, entries[0].Level)
+	assert.Equal(t, "/user/:id", entries[0].ContextMap()["http.route"])
+	assert.NotEmpty(t, entries[0].ContextMap()["trace_id"])
+	assert.Equal(t, zapcore.ErrorLevel, entries[1].Level)
+	assert.Equal(t, int64(http.StatusServiceUnavailable), entries[1].ContextMap()["http.status_code"])
+}
+
+// TestGinMiddlewareDefault 默认不记录access日志，日志输出与之前相同
+func TestGinMiddlewareDefault(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logx.InitWithOptions(logx.Config{EnableTrace: true, TracerProviderType: "file"}, "local-test", logx.WithZapCore(core))
+
+	router := gin.New()
+	router.Use(logx.GinMiddleware("local-test"))
+	router.GET("/user/:id", func(c *gin.Context) {
+		logx.Info(c.Request.Context(), "handler")
+	})
+	router.GET("/fail", func(c *gin.Context) {
+		c.Status(http.StatusServiceUnavailable)
+	})
+	for _, path := range []string{"/user/1", "/fail"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	entries := logs.All()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "handler", entries[0].Message)
+}
+
+func TestGinRecovery(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logx.InitWithOptions(logx.Config{EnableTrace: true, TracerProviderType: "file"}, "local-test", logx.WithZapCore(core))
+
+	router := gin.New()
+	router.Use(logx.GinMiddleware("local-test"), logx.GinRecovery())
+	router.GET("/panic", func(c *gin.Context) {
+		panic("foo")
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+
+	entries := logs.FilterMessage("panic recovered").All()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "panic: foo", entries[0].ContextMap()["error"])
+	assert.Contains(t, entries[0].ContextMap()["error_stack"], "TestGinRecovery")
+	assert.NotEmpty(t, entries[0].ContextMap()["trace_id"])
+	assert.Len(t, logs.FilterMessage("access").All(), 0)
+}
+
+func TestTraceparent(t *testing.T) {
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file"}, "local-test")
+
+	assert.Equal(t, "", logx.Traceparent(context.Background()))
+	ctx := logx.Start(context.Background(), "test")
+	defer logx.End(ctx)
+	traceparent := "00-" + logx.TraceID(ctx) + "-" + logx.SpanID(ctx) + "-01"
+	assert.Equal(t, traceparent, logx.Traceparent(ctx))
+	assert.Equal(t, template.HTML(`<meta name="traceparent" content="`+traceparent+`">`), logx.TraceparentMeta(ctx))
+	header := http.Header{}
+	logx.SetServerTiming(ctx, header)
+	assert.Equal(t, `traceparent;desc="`+traceparent+`"`, header.Get("Server-Timing"))
+}
+
+func TestSampleRules(t *testing.T) {
+	logx.Init(logx.Config{
+		EnableTrace:        true,
+		TracerProviderType: "file",
+		SampleRules: []logx.SampleRule{
+			{Route: "/healthz", Ratio: 0},
+			{SpanName: "cache.", Ratio: 0},
+		},
+	}, "local-test")
+
+	ctx := logx.Start(context.Background(), "cache.get")
+	assert.False(t, oteltrace.SpanContextFromContext(ctx).IsSampled())
+	logx.End(ctx)
+	ctx = logx.Start(context.Background(), "checkout")
+	assert.True(t, oteltrace.SpanContextFromContext(ctx).IsSampled())
+	logx.End(ctx)
+
+	sampled := map[string]bool{}
+	engine := gin.New()
+	engine.Use(logx.GinMiddleware("test"))
+	handler := func(c *gin.Context) {
+		sampled[c.FullPath()] = oteltrace.SpanContextFromContext(c.Request.Context()).IsSampled()
+	}
+	engine.GET("/healthz", handler)
+	engine.GET("/checkout", handler)
+	for _, path := range []string{"/healthz", "/checkout"} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	assert.Equal(t, map[string]bool{"/healthz": false, "/checkout": true}, sampled)
+}
+
+func TestHttpInjectPropagator(t *testing.T) {
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file"}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	// 应用之后设置的全局propagator不会被HttpInject覆盖，HttpInject仍使用Init时选择的b3
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	ctx := logx.Start(context.Background(), "test")
+	defer logx.End(ctx)
+
+	request, _ := http.NewRequest("GET", "http://localhost:8080/test", nil)
+	assert.Nil(t, logx.HttpInject(ctx, request))
+	assert.Contains(t, request.Header.Get("b3"), logx.TraceID(ctx))
+	assert.Equal(t, "", request.Header.Get("traceparent"))
+	assert.Equal(t, propagation.TraceContext{}, otel.GetTextMapPropagator())
+
+	request, _ = http.NewRequest("GET", "http://localhost:8080/test", nil)
+	assert.Nil(t, logx.HttpInjectWithPropagator(ctx, request, b3.New(b3.WithInjectEncoding(b3.B3MultipleHeader))))
+	assert.Equal(t, logx.TraceID(ctx), request.Header.Get("X-B3-TraceId"))
+	assert.Equal(t, "", request.Header.Get("traceparent"))
+	assert.Equal(t, propagation.TraceContext{}, otel.GetTextMapPropagator())
+}
+
+func TestOTLPPropagator(t *testing.T) {
+	// 不采样，避免向不存在的OTLP endpoint导出
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "oltp", Sampler: "never"}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	ctx := logx.Start(context.Background(), "test")
+	defer logx.End(ctx)
+
+	request, _ := http.NewRequest("GET", "http://localhost:8080/test", nil)
+	assert.Nil(t, logx.HttpInject(ctx, request))
+	assert.Contains(t, request.Header.Get("b3"), logx.TraceID(ctx))
+	assert.Equal(t, "", request.Header.Get("traceparent"))
+	carrier := logx.Inject(ctx)
+	assert.Contains(t, carrier["b3"], logx.TraceID(ctx))
+	assert.Equal(t, logx.TraceID(ctx), logx.TraceID(logx.Extract(context.Background(), carrier)))
+	headers := map[string]string{}
+	logx.KafkaInject(ctx, headers)
+	assert.Contains(t, headers["b3"], logx.TraceID(ctx))
+	assert.Equal(t, logx.TraceID(ctx), logx.TraceID(logx.KafkaExtract(context.Background(), headers)))
+
+	logx.InitWithOptions(logx.Config{EnableTrace: true, TracerProviderType: "oltp", Sampler: "never"}, "local-test",
+		logx.WithPropagator(propagation.TraceContext{}))
+	ctx = logx.Start(context.Background(), "test")
+	defer logx.End(ctx)
+	carrier = logx.Inject(ctx)
+	assert.Contains(t, carrier["traceparent"], logx.TraceID(ctx))
+	assert.Equal(t, "", carrier["b3"])
+}
+
+func TestHttpExtract(t *testing.T) {
+	logx.Init(logx.Config{
+		EnableTrace:        true,
+		TracerProviderType: "file",
+		PropagationHeaders: []string{"X-Request-ID"},
+	}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	ctx := logx.StartClient(context.Background(), "client")
+	defer logx.End(ctx)
+	request, _ := http.NewRequest("GET", "http://localhost:8080/test", nil)
+	assert.Nil(t, logx.HttpInject(ctx, request))
+	request.Header.Set("X-Request-ID", "req-1")
+
+	serverCtx := logx.HttpExtract(request)
+	assert.Equal(t, logx.TraceID(ctx), logx.TraceID(serverCtx))
+	spanCtx := logx.StartServer(serverCtx, "server")
+	defer logx.End(spanCtx)
+	assert.Equal(t, logx.TraceID(ctx), logx.TraceID(spanCtx))
+	assert.NotEqual(t, logx.SpanID(ctx), logx.SpanID(spanCtx))
+
+	// 转发PropagationHeaders
+	downstream, _ := http.NewRequest("GET", "http://localhost:8081/test", nil)
+	assert.Nil(t, logx.HttpInject(spanCtx, downstream))
+	assert.Equal(t, "req-1", downstream.Header.Get("X-Request-ID"))
+
+	assert.Equal(t, logx.TraceID(ctx), logx.TraceID(logx.ExtractHTTP(request.Header)))
+	assert.Equal(t, "", logx.TraceID(logx.ExtractHTTP(http.Header{})))
+}
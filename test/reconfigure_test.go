@@ -0,0 +1,141 @@
+package logx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconfigure(t *testing.T) {
+	dir := t.TempDir()
+	conf := logx.Config{Output: "file", File: filepath.Join(dir, "run.log"), Level: "info"}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logx.Init(conf, "reconfigure-test")
+		}()
+	}
+	wg.Wait()
+	defer logx.Init(logx.Config{}, "local-test")
+	logx.Info(context.Background(), "before reconfigure")
+
+	conf.File = filepath.Join(dir, "run2.log")
+	assert.NoError(t, logx.Reconfigure(conf))
+	logx.Info(context.Background(), "after reconfigure")
+
+	// 配置错误时不做修改
+	assert.Error(t, logx.Reconfigure(logx.Config{Output: "file"}))
+	logx.Info(context.Background(), "after invalid reconfigure")
+
+	old, _ := os.ReadFile(filepath.Join(dir, "run.log"))
+	current, _ := os.ReadFile(filepath.Join(dir, "run2.log"))
+	assert.Contains(t, string(old), "before reconfigure")
+	assert.NotContains(t, string(old), "after reconfigure")
+	assert.Contains(t, string(current), "after reconfigure")
+	assert.Contains(t, string(current), "after invalid reconfigure")
+}
+
+// TestReconfigureWhileLogging 与日志调用并发执行Reconfigure，需配合-race运行
+func TestReconfigureWhileLogging(t *testing.T) {
+	dir := t.TempDir()
+	conf := logx.Config{Output: "file", File: filepath.Join(dir, "run.log"), Level: "info"}
+	logx.Init(conf, "reconfigure-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				logx.Info(context.Background(), "concurrent", logx.Int("n", i))
+				logx.Enabled(context.Background(), "debug")
+			}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		conf.File = filepath.Join(dir, "run"+strconv.Itoa(i%2)+".log")
+		assert.NoError(t, logx.Reconfigure(conf))
+	}
+	close(done)
+	wg.Wait()
+}
+
+// TestReconfigureFlushesSink 替换后刷新并关闭之前的输出
+func TestReconfigureFlushesSink(t *testing.T) {
+	bodies := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies <- string(body)
+		w.Write([]byte(`{"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+
+	logx.Init(logx.Config{Output: "elasticsearch", Level: "info", ESServer: server.URL, ESFlushInterval: time.Hour}, "reconfigure-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	logx.Info(context.Background(), "buffered before reconfigure")
+	assert.NoError(t, logx.Reconfigure(logx.Config{Output: "none"}))
+	select {
+	case body := <-bodies:
+		assert.Contains(t, body, "buffered before reconfigure")
+	default:
+		t.Fatal("previous output not flushed")
+	}
+}
+
+// TestWithWhileReconfigure 在子进程中与Reconfigure并发调用With，设置的字段都不会丢失
+// With的字段Init后仍然保留，在子进程中调用以免影响其他测试
+func TestWithWhileReconfigure(t *testing.T) {
+	if file := os.Getenv("LOGX_WITH_FILE"); file != "" {
+		conf := logx.Config{Output: "file", File: file, Level: "info"}
+		logx.Init(conf, "reconfigure-test")
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				logx.With(logx.Int("with_"+strconv.Itoa(i), i))
+			}()
+			go func() {
+				defer wg.Done()
+				logx.Reconfigure(conf)
+				logx.Info(context.Background(), "concurrent")
+			}()
+		}
+		wg.Wait()
+		logx.Info(context.Background(), "after with")
+		return
+	}
+	file := filepath.Join(t.TempDir(), "run.log")
+	cmd := exec.Command(os.Args[0], "-test.run=^TestWithWhileReconfigure$")
+	cmd.Env = append(os.Environ(), "LOGX_WITH_FILE="+file)
+	out, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(out))
+
+	content, _ := os.ReadFile(file)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	last := lines[len(lines)-1]
+	assert.Contains(t, last, "after with")
+	for i := 0; i < 8; i++ {
+		assert.Contains(t, last, `"with_`+strconv.Itoa(i)+`"`)
+	}
+}
@@ -0,0 +1,57 @@
+package logx
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestRedact(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "run.log")
+	logx.Init(logx.Config{
+		Output:         "file",
+		File:           file,
+		Level:          "info",
+		RedactKeys:     []string{"password", "Authorization"},
+		RedactPatterns: []string{"email", "phone", "credit_card", `sk-[0-9a-z]+`, "("},
+	}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+
+	fields := []logx.Field{
+		logx.String("PASSWORD", "123456"),
+		logx.String("http.authorization", "Bearer xxx"),
+		logx.String("note", "mail foo@example.com or call 13812345678, key sk-abc"),
+		logx.String("card", "4111 1111 1111 1111"),
+		logx.Secret("api_key", "abc"),
+		logx.Int("age", 18),
+	}
+	logx.Info(context.Background(), "redact", fields...)
+
+	entry := lastEntry(t, file)
+	assert.Equal(t, "******", entry["PASSWORD"])
+	assert.Equal(t, "******", entry["http.authorization"])
+	assert.Equal(t, "mail ****** or call ******, key ******", entry["note"])
+	assert.Equal(t, "******", entry["card"])
+	assert.Equal(t, "******", entry["api_key"])
+	assert.Equal(t, float64(18), entry["age"])
+
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("PASSWORD", "******"),
+		attribute.String("http.authorization", "******"),
+		attribute.String("note", "mail ****** or call ******, key ******"),
+		attribute.String("card", "******"),
+		attribute.String("api_key", "******"),
+		attribute.Int("age", 18),
+	}, logx.FieldsToKeyValues(fields...))
+}
+
+func TestSecret(t *testing.T) {
+	logx.Init(logx.Config{}, "local-test")
+	assert.Equal(t, []attribute.KeyValue{attribute.String("token", "******")},
+		logx.FieldsToKeyValues(logx.Secret("token", "abc")))
+	assert.Equal(t, "******", logx.Secret("token", "abc").Value())
+}
@@ -0,0 +1,219 @@
+package logx
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/itmisx/logx"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotate(t *testing.T) {
+	dir := t.TempDir()
+	logx.Init(logx.Config{
+		Output:           "file",
+		File:             filepath.Join(dir, "run.log"),
+		Level:            "info",
+		RotateFilename:   "{service}-{time}{ext}",
+		RotateTimeFormat: "20060102150405",
+	}, "rotate-test")
+	logx.Info(context.Background(), "before rotate")
+	assert.Nil(t, logx.Rotate())
+	assert.Equal(t, int64(1), logx.Stats().Rotations)
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "rotate-test-*.log"))
+	assert.Len(t, matches, 1)
+	content, _ := os.ReadFile(matches[0])
+	assert.Contains(t, string(content), "before rotate")
+
+	content, _ = os.ReadFile(filepath.Join(dir, "run.log"))
+	assert.Contains(t, string(content), "log file rotated")
+	assert.Contains(t, string(content), matches[0])
+	logx.Init(logx.Config{}, "local-test")
+}
+
+// TestRotateLogWarn 默认的error等级下也会写入切割日志
+func TestRotateLogWarn(t *testing.T) {
+	dir := t.TempDir()
+	logx.Init(logx.Config{Output: "file", File: filepath.Join(dir, "run.log")}, "rotate-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	logx.Error(context.Background(), "before rotate")
+	assert.Nil(t, logx.Rotate())
+
+	content, _ := os.ReadFile(filepath.Join(dir, "run.log"))
+	assert.Contains(t, string(content), `"level":"warn"`)
+	assert.Contains(t, string(content), `"msg":"log file rotated"`)
+}
+
+func TestRotateDaily(t *testing.T) {
+	dir := t.TempDir()
+	logx.Init(logx.Config{
+		Output: "file",
+		File:   filepath.Join(dir, "run.log"),
+		Level:  "info",
+		Rotate: "daily",
+	}, "rotate-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	date := time.Now().Add(-time.Second).Format("2006-01-02")
+
+	logx.Info(context.Background(), "first")
+	assert.Nil(t, logx.Rotate())
+	logx.Info(context.Background(), "second")
+	assert.Nil(t, logx.Rotate())
+
+	content, _ := os.ReadFile(filepath.Join(dir, "run-"+date+".log"))
+	assert.Contains(t, string(content), "first")
+	content, _ = os.ReadFile(filepath.Join(dir, "run-"+date+".1.log"))
+	assert.Contains(t, string(content), "second")
+}
+
+func TestDatedFile(t *testing.T) {
+	dir := t.TempDir()
+	logx.Init(logx.Config{
+		Output:   "file",
+		File:     filepath.Join(dir, "{service}-{2006-01-02}.log"),
+		FileLink: filepath.Join(dir, "run.log"),
+		Level:    "info",
+	}, "dated-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	logx.Info(context.Background(), "dated")
+
+	name := "dated-test-" + time.Now().Format("2006-01-02") + ".log"
+	content, _ := os.ReadFile(filepath.Join(dir, name))
+	assert.Contains(t, string(content), "dated")
+	target, err := os.Readlink(filepath.Join(dir, "run.log"))
+	assert.NoError(t, err)
+	assert.Equal(t, name, target)
+	content, _ = os.ReadFile(filepath.Join(dir, "run.log"))
+	assert.Contains(t, string(content), "dated")
+}
+
+func TestMaxTotalSize(t *testing.T) {
+	dir := t.TempDir()
+	backups := []string{"run-2024-05-01T00-00-00.000.log", "run-2024-05-02T00-00-00.000.log", "run-2024-05-03T00-00-00.000.log.gz"}
+	for i, name := range backups {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.WriteFile(path, make([]byte, 400*1024), 0o644))
+		mtime := time.Now().Add(time.Duration(i-len(backups)) * time.Hour)
+		assert.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+	other := filepath.Join(dir, "audit.log")
+	assert.NoError(t, os.WriteFile(other, make([]byte, 400*1024), 0o644))
+
+	logx.Init(logx.Config{
+		Output:       "file",
+		File:         filepath.Join(dir, "run.log"),
+		Level:        "info",
+		MaxTotalSize: 1,
+	}, "rotate-test")
+	defer logx.Init(logx.Config{}, "local-test")
+
+	assert.NoFileExists(t, filepath.Join(dir, backups[0]))
+	assert.FileExists(t, filepath.Join(dir, backups[1]))
+	assert.FileExists(t, filepath.Join(dir, backups[2]))
+	assert.FileExists(t, other)
+}
+
+func TestErrorFile(t *testing.T) {
+	dir := t.TempDir()
+	logx.Init(logx.Config{
+		Output:    "file",
+		File:      filepath.Join(dir, "run.log"),
+		ErrorFile: filepath.Join(dir, "error.log"),
+		Level:     "info",
+	}, "rotate-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	logx.Debug(context.Background(), "debug entry")
+	logx.Info(context.Background(), "info entry")
+	logx.Error(context.Background(), "error entry")
+
+	run, _ := os.ReadFile(filepath.Join(dir, "run.log"))
+	errs, _ := os.ReadFile(filepath.Join(dir, "error.log"))
+	assert.Contains(t, string(run), "info entry")
+	assert.NotContains(t, string(run), "error entry")
+	assert.NotContains(t, string(run), "debug entry")
+	assert.Contains(t, string(errs), "error entry")
+	assert.NotContains(t, string(errs), "info entry")
+
+	assert.NoError(t, logx.Rotate())
+	matches, _ := filepath.Glob(filepath.Join(dir, "run-*.log"))
+	assert.Len(t, matches, 1)
+	matches, _ = filepath.Glob(filepath.Join(dir, "error-*.log"))
+	assert.Len(t, matches, 1)
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "run.log")
+	logx.Init(logx.Config{
+		Output:         "file",
+		File:           file,
+		Level:          "info",
+		ReopenOnSIGHUP: true,
+	}, "rotate-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	logx.Info(context.Background(), "before reopen")
+	// 模拟logrotate移动文件
+	assert.NoError(t, os.Rename(file, file+".1"))
+	logx.Info(context.Background(), "still old file")
+	assert.NoError(t, logx.Reopen())
+	logx.Info(context.Background(), "after reopen")
+
+	old, _ := os.ReadFile(file + ".1")
+	current, _ := os.ReadFile(file)
+	assert.Contains(t, string(old), "still old file")
+	assert.NotContains(t, string(old), "after reopen")
+	assert.Contains(t, string(current), "after reopen")
+
+	// SIGHUP
+	process, _ := os.FindProcess(os.Getpid())
+	assert.NoError(t, os.Rename(file, file+".2"))
+	if err := process.Signal(syscall.SIGHUP); err != nil {
+		t.Skip("SIGHUP is not supported")
+	}
+	assert.Eventually(t, func() bool {
+		logx.Info(context.Background(), "after sighup")
+		current, _ := os.ReadFile(file)
+		return strings.Contains(string(current), "after sighup")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRotateZstd(t *testing.T) {
+	dir := t.TempDir()
+	logx.Init(logx.Config{
+		Output:        "file",
+		File:          filepath.Join(dir, "run.log"),
+		Level:         "info",
+		Compress:      true,
+		CompressType:  "zstd",
+		CompressLevel: 3,
+	}, "rotate-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	logx.Info(context.Background(), "before rotate")
+	assert.NoError(t, logx.Rotate())
+
+	var matches []string
+	assert.Eventually(t, func() bool {
+		matches, _ = filepath.Glob(filepath.Join(dir, "run-*.log.zst"))
+		return len(matches) == 1
+	}, time.Second, 10*time.Millisecond)
+	backups, _ := filepath.Glob(filepath.Join(dir, "run-*.log"))
+	assert.Len(t, backups, 0)
+
+	file, err := os.Open(matches[0])
+	assert.NoError(t, err)
+	defer file.Close()
+	dec, err := zstd.NewReader(file)
+	assert.NoError(t, err)
+	defer dec.Close()
+	content, err := io.ReadAll(dec)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "before rotate")
+}
@@ -0,0 +1,37 @@
+package logx
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogSampling(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logx.InitWithOptions(logx.Config{
+		LogSampling: logx.LogSampling{Initial: 2, Thereafter: 3, Window: time.Minute},
+	}, "local-test", logx.WithZapCore(core))
+	defer logx.Init(logx.Config{}, "local-test")
+	for i := 0; i < 10; i++ {
+		logx.Error(context.Background(), "loop error")
+	}
+	logx.Error(context.Background(), "other error")
+	// 前2条，之后第5,8条
+	assert.Equal(t, 4, logs.FilterMessage("loop error").Len())
+	assert.Equal(t, 1, logs.FilterMessage("other error").Len())
+	assert.Equal(t, int64(6), logx.Stats().EntriesSampled)
+}
+
+func TestOnceEvery(t *testing.T) {
+	assert.True(t, logx.Once("once-test"))
+	assert.False(t, logx.Once("once-test"))
+	assert.True(t, logx.Every("every-test", 50*time.Millisecond))
+	assert.False(t, logx.Every("every-test", 50*time.Millisecond))
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, logx.Every("every-test", 50*time.Millisecond))
+}
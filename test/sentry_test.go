@@ -0,0 +1,67 @@
+package logx
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSentryDSN(t *testing.T) {
+	events := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/42/envelope/", r.URL.Path)
+		assert.Contains(t, r.Header.Get("X-Sentry-Auth"), "sentry_key=public")
+		scanner := bufio.NewScanner(r.Body)
+		scanner.Buffer(nil, 1024*1024)
+		var lines []string
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		var event map[string]interface{}
+		if assert.Len(t, lines, 3) {
+			json.Unmarshal([]byte(lines[2]), &event)
+		}
+		events <- event
+	}))
+	defer server.Close()
+
+	logx.Init(logx.Config{
+		EnableTrace:        true,
+		TracerProviderType: "file",
+		SentryDSN:          strings.Replace(server.URL, "http://", "http://public@", 1) + "/42",
+		SentryEnvironment:  "test",
+	}, "local-test")
+	defer logx.Shutdown(context.Background())
+
+	ctx := logx.Start(context.Background(), "sentry")
+	logx.ErrorReturn(ctx, errors.New("boom"), logx.String("user", "u1"), logx.Any("payload", []int{1}))
+	logx.End(ctx)
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "error", event["level"])
+		assert.Equal(t, "test", event["environment"])
+		assert.Equal(t, map[string]interface{}{"formatted": "boom"}, event["message"])
+		tags := event["tags"].(map[string]interface{})
+		assert.Equal(t, "u1", tags["user"])
+		assert.Equal(t, "local-test", tags["service.name"])
+		assert.Equal(t, logx.TraceID(ctx), tags["trace_id"])
+		assert.Equal(t, []interface{}{float64(1)}, event["extra"].(map[string]interface{})["payload"])
+		exception := event["exception"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "*errors.errorString", exception["type"])
+		frames := exception["stacktrace"].(map[string]interface{})["frames"].([]interface{})
+		last := frames[len(frames)-1].(map[string]interface{})
+		assert.Contains(t, last["function"], "TestSentryDSN")
+	case <-time.After(time.Second):
+		t.Fatal("sentry event not received")
+	}
+}
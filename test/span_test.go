@@ -0,0 +1,290 @@
+package logx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	grpccodes "google.golang.org/grpc/codes"
+)
+
+func TestWithSpan(t *testing.T) {
+	logx.Init(logx.Config{}, "local-test")
+
+	errFoo := errors.New("foo")
+	err := logx.WithSpan(context.Background(), "test", func(ctx context.Context) error {
+		return errFoo
+	}, logx.String("key", "value"))
+	assert.Equal(t, errFoo, err)
+
+	err = logx.WithSpan(context.Background(), "test", func(ctx context.Context) error {
+		panic("bar")
+	})
+	assert.EqualError(t, err, "panic: bar")
+}
+
+func TestSetTraceSampleRatio(t *testing.T) {
+	assert.NotNil(t, logx.SetTraceSampleRatio(1.5))
+	assert.Nil(t, logx.SetTraceSampleRatio(0.5))
+}
+
+func TestWithScope(t *testing.T) {
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file"}, "local-test")
+
+	ctx := logx.Start(context.Background(), "test")
+	defer logx.End(ctx)
+	span := oteltrace.SpanFromContext(ctx).(sdktrace.ReadOnlySpan)
+	assert.Equal(t, "github.com/itmisx/logx", span.InstrumentationScope().Name)
+
+	ctx = logx.Start(logx.WithScope(ctx, "github.com/foo/bar", "v1.2.0"), "test")
+	defer logx.End(ctx)
+	span = oteltrace.SpanFromContext(ctx).(sdktrace.ReadOnlySpan)
+	assert.Equal(t, "github.com/foo/bar", span.InstrumentationScope().Name)
+	assert.Equal(t, "v1.2.0", span.InstrumentationScope().Version)
+}
+
+func TestDetachContext(t *testing.T) {
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file"}, "local-test")
+
+	ctx := logx.WithFields(context.Background(), logx.String("user_id", "1"))
+	ctx = logx.Start(ctx, "test")
+	defer logx.End(ctx)
+	detached := logx.DetachContext(ctx)
+	assert.Equal(t, logx.TraceID(ctx), logx.TraceID(detached))
+	assert.Equal(t, logx.SpanID(ctx), logx.SpanID(detached))
+	assert.False(t, oteltrace.SpanFromContext(detached).IsRecording())
+
+	child := logx.Start(detached, "child")
+	defer logx.End(child)
+	assert.Equal(t, logx.TraceID(ctx), logx.TraceID(child))
+}
+
+func TestGRPCStatus(t *testing.T) {
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file", AttributeNamespace: "app"}, "local-test")
+
+	ctx := logx.Start(context.Background(), "test")
+	defer logx.End(ctx)
+	logx.SetSpanAttr(ctx, logx.GRPCStatus(grpccodes.Unavailable))
+	span := oteltrace.SpanFromContext(ctx).(sdktrace.ReadOnlySpan)
+	assert.Equal(t, codes.Error, span.Status().Code)
+	assert.Equal(t, "Unavailable", span.Status().Description)
+	assert.Contains(t, span.Attributes(), attribute.Int("rpc.grpc.status_code", 14))
+
+	ctx = logx.Start(ctx, "test", logx.GRPCStatus(grpccodes.OK))
+	defer logx.End(ctx)
+	span = oteltrace.SpanFromContext(ctx).(sdktrace.ReadOnlySpan)
+	assert.Equal(t, codes.Unset, span.Status().Code)
+}
+
+// TestTraceSampleBaggageFile file类型的provider同样根据baggage强制采样
+func TestTraceSampleBaggageFile(t *testing.T) {
+	logx.Init(logx.Config{
+		EnableTrace:        true,
+		TracerProviderType: "file",
+		TraceFile:          filepath.Join(t.TempDir(), "trace.txt"),
+		Sampler:            "never",
+		TraceSampleBaggage: []string{"debug=1"},
+	}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+
+	ctx := logx.Start(context.Background(), "test")
+	assert.False(t, oteltrace.SpanContextFromContext(ctx).IsSampled())
+	logx.End(ctx)
+
+	ctx, err := logx.WithBaggage(context.Background(), "debug", "1")
+	assert.NoError(t, err)
+	ctx = logx.Start(ctx, "test")
+	assert.True(t, oteltrace.SpanContextFromContext(ctx).IsSampled())
+	logx.End(ctx)
+}
+
+func TestWithSampler(t *testing.T) {
+	sampler := logx.SamplerFunc(func(p logx.SamplingParameters) bool {
+		if customer, _ := p.Attributes.GetString("customer"); customer == "vip" {
+			return true
+		}
+		return p.ParentSampled
+	})
+	logx.InitWithOptions(logx.Config{EnableTrace: true, TracerProviderType: "file"}, "local-test", logx.WithSampler(sampler))
+
+	ctx := logx.Start(context.Background(), "test", logx.String("customer", "vip"))
+	defer logx.End(ctx)
+	assert.True(t, oteltrace.SpanContextFromContext(ctx).IsSampled())
+	child := logx.Start(ctx, "child")
+	defer logx.End(child)
+	assert.True(t, oteltrace.SpanContextFromContext(child).IsSampled())
+
+	ctx = logx.Start(context.Background(), "test", logx.String("customer", "free"))
+	defer logx.End(ctx)
+	assert.False(t, oteltrace.SpanContextFromContext(ctx).IsSampled())
+}
+
+func TestFlushErrorSpans(t *testing.T) {
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file", FlushErrorSpans: 2}, "local-test")
+
+	for _, name := range []string{"error-1", "error-2"} {
+		ctx := logx.Start(context.Background(), name)
+		logx.EndWithError(ctx, errors.New("foo"))
+	}
+	assert.Eventually(t, func() bool {
+		data, _ := os.ReadFile("trace.txt")
+		return strings.Contains(string(data), "error-2")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSpanKind(t *testing.T) {
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file"}, "local-test")
+
+	starts := map[oteltrace.SpanKind]func(context.Context, string, ...logx.Field) context.Context{
+		oteltrace.SpanKindInternal: logx.Start,
+		oteltrace.SpanKindServer:   logx.StartServer,
+		oteltrace.SpanKindClient:   logx.StartClient,
+		oteltrace.SpanKindProducer: logx.StartProducer,
+		oteltrace.SpanKindConsumer: logx.StartConsumer,
+	}
+	for kind, start := range starts {
+		ctx := start(context.Background(), "test")
+		span := oteltrace.SpanFromContext(ctx).(sdktrace.ReadOnlySpan)
+		assert.Equal(t, kind, span.SpanKind())
+		logx.End(ctx)
+	}
+}
+
+func TestEvent(t *testing.T) {
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file"}, "local-test")
+
+	ctx := logx.Start(context.Background(), "test")
+	defer logx.End(ctx)
+	ts := time.Now().Add(-time.Hour)
+	logx.Event(ctx, "device online", ts, logx.String("device_id", "d1"))
+	logx.Event(ctx, "now", time.Time{})
+	events := oteltrace.SpanFromContext(ctx).(sdktrace.ReadOnlySpan).Events()
+	assert.Len(t, events, 2)
+	assert.Equal(t, "device online", events[0].Name)
+	assert.True(t, ts.Equal(events[0].Time))
+	assert.Contains(t, events[0].Attributes, attribute.String("device_id", "d1"))
+	assert.WithinDuration(t, time.Now(), events[1].Time, time.Minute)
+}
+
+func TestErrorEvent(t *testing.T) {
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file"}, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+
+	ctx := logx.Start(context.Background(), "test")
+	defer logx.End(ctx)
+	err := fmt.Errorf("wrap: %w", errors.New("foo"))
+	logx.Error(ctx, "query failed", logx.ErrStack(err), logx.String("table", "users"))
+	logx.Error(ctx, "plain error")
+	events := oteltrace.SpanFromContext(ctx).(sdktrace.ReadOnlySpan).Events()
+	assert.Len(t, events, 2)
+
+	attrs := map[attribute.Key]attribute.Value{}
+	for _, kv := range events[0].Attributes {
+		_, dup := attrs[kv.Key]
+		assert.False(t, dup, "duplicate attribute %s", kv.Key)
+		attrs[kv.Key] = kv.Value
+	}
+	assert.Equal(t, "*fmt.wrapError", attrs["exception.type"].AsString())
+	assert.Equal(t, "wrap: foo", attrs["exception.message"].AsString())
+	assert.Contains(t, attrs["exception.stacktrace"].AsString(), "TestErrorEvent")
+	assert.Equal(t, "users", attrs["table"].AsString())
+	assert.Contains(t, events[1].Attributes, attribute.String("exception.message", "plain error"))
+}
+
+func TestConfigSampler(t *testing.T) {
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file", Sampler: "never"}, "local-test")
+	ctx := logx.Start(context.Background(), "test")
+	assert.False(t, oteltrace.SpanContextFromContext(ctx).IsSampled())
+	logx.End(ctx)
+
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file", Sampler: "parentbased_ratio", TraceSampleRatio: 0}, "local-test")
+	parent := oteltrace.ContextWithSpanContext(context.Background(), oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    oteltrace.TraceID{1},
+		SpanID:     oteltrace.SpanID{1},
+		TraceFlags: oteltrace.FlagsSampled,
+		Remote:     true,
+	}))
+	ctx = logx.Start(parent, "test")
+	assert.True(t, oteltrace.SpanContextFromContext(ctx).IsSampled())
+	logx.End(ctx)
+	ctx = logx.Start(context.Background(), "test")
+	assert.False(t, oteltrace.SpanContextFromContext(ctx).IsSampled())
+	logx.End(ctx)
+
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file", Sampler: "ratelimit", SampleRateLimit: 2}, "local-test")
+	var sampled int
+	for i := 0; i < 10; i++ {
+		ctx := logx.Start(context.Background(), "test")
+		if oteltrace.SpanContextFromContext(ctx).IsSampled() {
+			sampled++
+		}
+		logx.End(ctx)
+	}
+	assert.Equal(t, 2, sampled)
+}
+
+func TestTailSampling(t *testing.T) {
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file", TailSampling: "warn"}, "local-test")
+
+	ctx := logx.Start(context.Background(), "tail-ok")
+	child := logx.Start(ctx, "tail-ok-child")
+	logx.End(child)
+	logx.End(ctx)
+
+	ctx = logx.Start(context.Background(), "tail-error")
+	child = logx.Start(ctx, "tail-error-child")
+	logx.Error(child, "foo")
+	logx.End(child)
+	logx.End(ctx)
+
+	ctx = logx.Start(context.Background(), "tail-warn")
+	logx.Warn(ctx, "foo")
+	logx.End(ctx)
+
+	_, err := logx.Shutdown(context.Background())
+	assert.Nil(t, err)
+	data, _ := os.ReadFile("trace.txt")
+	assert.NotContains(t, string(data), "tail-ok")
+	assert.Contains(t, string(data), "tail-error-child")
+	assert.Contains(t, string(data), `"tail-error |`)
+	assert.Contains(t, string(data), "tail-warn")
+}
+
+func TestBatchTimeout(t *testing.T) {
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file", BatchTimeout: 10 * time.Millisecond}, "local-test")
+
+	ctx := logx.Start(context.Background(), "batch-timeout")
+	logx.End(ctx)
+	assert.Eventually(t, func() bool {
+		data, _ := os.ReadFile("trace.txt")
+		return strings.Contains(string(data), "batch-timeout")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestTraceFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "traces", "trace.json")
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file", TraceFile: file, TraceFileMaxSize: 1}, "local-test")
+	logx.End(logx.Start(context.Background(), "trace-file"))
+	logx.Shutdown(context.Background())
+	data, err := os.ReadFile(file)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "trace-file")
+
+	// 无法创建时关闭追踪
+	logx.Init(logx.Config{EnableTrace: true, TracerProviderType: "file", TraceFile: filepath.Join(file, "trace.txt")}, "local-test")
+	ctx := logx.Start(context.Background(), "trace-file")
+	assert.False(t, oteltrace.SpanContextFromContext(ctx).IsValid())
+	logx.End(ctx)
+}
@@ -0,0 +1,74 @@
+package logx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestMaxEntryBytes(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logx.InitWithOptions(logx.Config{MaxEntryBytes: 512}, "local-test", logx.WithZapCore(core))
+
+	msg := strings.Repeat("日志", 300)
+	logx.Info(context.Background(), msg, logx.String("user_id", "1"))
+	logx.Info(context.Background(), "short")
+
+	entries := logs.All()
+	assert.Greater(t, len(entries), 3)
+	var joined string
+	parts := entries[:len(entries)-1]
+	id := parts[0].ContextMap()["log.split_id"]
+	assert.NotEmpty(t, id)
+	assert.Equal(t, "1", parts[0].ContextMap()["user_id"])
+	for i, entry := range parts {
+		fields := entry.ContextMap()
+		assert.Equal(t, id, fields["log.split_id"])
+		assert.Equal(t, int64(i+1), fields["log.part"])
+		assert.Equal(t, int64(len(parts)), fields["log.parts"])
+		assert.LessOrEqual(t, len(entry.Message), 512)
+		joined += entry.Message
+	}
+	assert.Equal(t, msg, joined)
+	assert.Equal(t, map[string]interface{}{}, entries[len(entries)-1].ContextMap())
+	logx.Init(logx.Config{}, "local-test")
+}
+
+func TestMaxEntryBytesErrorFile(t *testing.T) {
+	dir := t.TempDir()
+	var hooked atomic.Int64
+	observed, logs := observer.New(zap.DebugLevel)
+	core := zapcore.RegisterHooks(observed, func(zapcore.Entry) error {
+		hooked.Add(1)
+		return nil
+	})
+	logx.InitWithOptions(logx.Config{
+		Output:        "file",
+		File:          filepath.Join(dir, "run.log"),
+		ErrorFile:     filepath.Join(dir, "error.log"),
+		Level:         "info",
+		MaxEntryBytes: 512,
+	}, "local-test", logx.WithZapCore(core))
+	defer logx.Init(logx.Config{}, "local-test")
+
+	logx.Info(context.Background(), "info "+strings.Repeat("a", 1000))
+	logx.Error(context.Background(), "error "+strings.Repeat("b", 1000))
+
+	run, _ := os.ReadFile(filepath.Join(dir, "run.log"))
+	errs, _ := os.ReadFile(filepath.Join(dir, "error.log"))
+	assert.Contains(t, string(run), `"log.part":2`)
+	assert.NotContains(t, string(run), "bbb")
+	assert.Contains(t, string(errs), `"log.part":2`)
+	assert.NotContains(t, string(errs), "aaa")
+	assert.Greater(t, logs.Len(), 3)
+	assert.Equal(t, int64(logs.Len()), hooked.Load())
+}
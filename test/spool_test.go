@@ -0,0 +1,154 @@
+package logx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpoolDir(t *testing.T) {
+	var fail atomic.Bool
+	var exported atomic.Int32
+	fail.Store(true)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		exported.Add(1)
+	}))
+	defer collector.Close()
+
+	dir := t.TempDir()
+	logx.Init(logx.Config{
+		EnableTrace:  true,
+		Sampler:      "always",
+		OTLPEndpoint: strings.TrimPrefix(collector.URL, "http://"),
+		OLTPInsecure: true,
+		BatchTimeout: 10 * time.Millisecond,
+		SpoolDir:     dir,
+	}, "local-test")
+	spooled := func() int {
+		entries, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		return len(entries)
+	}
+
+	logx.End(logx.Start(context.Background(), "spool-1"))
+	assert.Eventually(t, func() bool { return spooled() == 1 }, time.Second, 10*time.Millisecond)
+
+	fail.Store(false)
+	logx.End(logx.Start(context.Background(), "spool-2"))
+	assert.Eventually(t, func() bool { return spooled() == 0 && exported.Load() == 2 }, time.Second, 10*time.Millisecond)
+	logx.Shutdown(context.Background())
+}
+
+func TestSpoolReplayAfterRestart(t *testing.T) {
+	var fail atomic.Bool
+	var exported atomic.Int32
+	fail.Store(true)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		exported.Add(1)
+	}))
+	defer collector.Close()
+
+	dir := t.TempDir()
+	conf := logx.Config{
+		EnableTrace:  true,
+		Sampler:      "always",
+		OTLPEndpoint: strings.TrimPrefix(collector.URL, "http://"),
+		OLTPInsecure: true,
+		BatchTimeout: 10 * time.Millisecond,
+		SpoolDir:     dir,
+	}
+	logx.Init(conf, "local-test")
+	logx.End(logx.Start(context.Background(), "before restart"))
+	logx.Shutdown(context.Background())
+	entries, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.Len(t, entries, 1)
+
+	// 重新启动后，保存的span在第一次导出成功后重新导出
+	fail.Store(false)
+	logx.Init(conf, "local-test")
+	logx.End(logx.Start(context.Background(), "after restart"))
+	assert.Eventually(t, func() bool {
+		entries, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+		return len(entries) == 0 && exported.Load() == 2
+	}, time.Second, 10*time.Millisecond)
+	logx.Shutdown(context.Background())
+}
+
+func TestReplayPending(t *testing.T) {
+	logx.Init(logx.Config{}, "local-test")
+	_, err := logx.ReplayPending(context.Background())
+	assert.Error(t, err)
+
+	var fail atomic.Bool
+	var exported atomic.Int32
+	fail.Store(true)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		exported.Add(1)
+	}))
+	defer collector.Close()
+
+	dir := t.TempDir()
+	conf := logx.Config{
+		EnableTrace:  true,
+		Sampler:      "always",
+		OTLPEndpoint: strings.TrimPrefix(collector.URL, "http://"),
+		OLTPInsecure: true,
+		BatchTimeout: 10 * time.Millisecond,
+		SpoolDir:     dir,
+	}
+	logx.Init(conf, "local-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	for i := 1; i <= 2; i++ {
+		logx.End(logx.Start(context.Background(), "outage"))
+		assert.Eventually(t, func() bool { return logx.SpoolState().LastSeq == uint64(i) }, time.Second, 10*time.Millisecond)
+	}
+
+	status, err := logx.ReplayPending(context.Background())
+	assert.Error(t, err)
+	assert.Equal(t, 2, status.Pending)
+	assert.Equal(t, uint64(0), status.AckedSeq)
+
+	fail.Store(false)
+	status, err = logx.ReplayPending(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, logx.SpoolStatus{LastSeq: 2, AckedSeq: 2}, status)
+	assert.Equal(t, int32(2), exported.Load())
+	checkpoint, _ := os.ReadFile(filepath.Join(dir, "checkpoint"))
+	assert.Equal(t, "2", string(checkpoint))
+
+	// 放弃无法送达的批次
+	fail.Store(true)
+	logx.End(logx.Start(context.Background(), "lost"))
+	assert.Eventually(t, func() bool { return logx.SpoolState().Pending == 1 }, time.Second, 10*time.Millisecond)
+	assert.NoError(t, logx.AckSpool(logx.SpoolState().LastSeq))
+	assert.Equal(t, logx.SpoolStatus{LastSeq: 3, AckedSeq: 3}, logx.SpoolState())
+	logx.Shutdown(context.Background())
+
+	// 重启后序号继续递增，checkpoint之前的文件不再导出
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "3.json"), []byte("[]"), 0o644))
+	logx.Init(conf, "local-test")
+	assert.Equal(t, logx.SpoolStatus{LastSeq: 3, AckedSeq: 3}, logx.SpoolState())
+	_, err = os.Stat(filepath.Join(dir, "3.json"))
+	assert.True(t, os.IsNotExist(err))
+	logx.Shutdown(context.Background())
+}
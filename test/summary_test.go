@@ -0,0 +1,83 @@
+package logx
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"strings"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestShutdown(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	conf := logx.Config{EnableTrace: true, TracerProviderType: "file", ShutdownSummary: true}
+	logx.InitWithOptions(conf, "local-test", logx.WithZapCore(core))
+
+	ctx := logx.Start(context.Background(), "test")
+	logx.Info(ctx, "foo")
+	logx.Info(ctx, "bar")
+	logx.Error(ctx, "baz")
+	logx.End(ctx)
+	logx.Start(context.Background(), "unfinished")
+
+	summary, err := logx.Shutdown(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]int64{"info": 2, "error": 1}, summary.Entries)
+	assert.Equal(t, int64(2), summary.SpansStarted)
+	assert.Equal(t, int64(1), summary.SpansEnded)
+	assert.Equal(t, int64(0), summary.SpansDropped)
+
+	entries := logs.FilterMessage("logx summary").All()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, int64(2), entries[0].ContextMap()["entries_info"])
+}
+
+func TestStats(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logx.InitWithOptions(logx.Config{EnableTrace: true, TracerProviderType: "file", Expvar: true}, "local-test", logx.WithZapCore(core))
+
+	logx.End(logx.Start(context.Background(), "test"))
+	assert.Equal(t, int64(1), logx.Stats().QueueDepth)
+	otel.Handle(errors.New("queue is full"))
+	summary, err := logx.Shutdown(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(1), summary.SpansExported)
+	assert.Equal(t, int64(0), summary.QueueDepth)
+	assert.Equal(t, int64(1), summary.OTelErrors)
+	assert.Equal(t, 1, logs.FilterMessage("otel error").Len())
+	assert.Contains(t, expvar.Get("logx").String(), `"SpansExported":1`)
+}
+
+func TestCollector(t *testing.T) {
+	core, _ := observer.New(zap.DebugLevel)
+	logx.InitWithOptions(logx.Config{EnableTrace: true, TracerProviderType: "file"}, "local-test", logx.WithZapCore(core))
+	registry := prometheus.NewPedanticRegistry()
+	assert.Nil(t, registry.Register(logx.Collector()))
+
+	ctx := logx.Start(context.Background(), "test")
+	logx.Info(ctx, "foo")
+	logx.Error(ctx, "bar")
+	expected := `# HELP logx_log_entries_total Log entries per level.
+# TYPE logx_log_entries_total counter
+logx_log_entries_total{level="error"} 1
+logx_log_entries_total{level="info"} 1
+# HELP logx_spans_active Spans started and not yet ended.
+# TYPE logx_spans_active gauge
+logx_spans_active 1
+`
+	assert.Nil(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "logx_log_entries_total", "logx_spans_active"))
+	logx.End(ctx)
+	expected = `# HELP logx_spans_active Spans started and not yet ended.
+# TYPE logx_spans_active gauge
+logx_spans_active 0
+`
+	assert.Nil(t, testutil.GatherAndCompare(registry, strings.NewReader(expected), "logx_spans_active"))
+}
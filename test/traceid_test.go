@@ -0,0 +1,39 @@
+package logx
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceIDFormats(t *testing.T) {
+	logx.Init(logx.Config{TraceIDFormats: []string{"xray", "datadog"}}, "local-test")
+	ctx, err := logx.NewRootContext("5759e988bd862e3fe1be46a994272793", "53995c3f42cd8ad8")
+	assert.Nil(t, err)
+	ctx = logx.Start(ctx, "test")
+	defer logx.End(ctx)
+
+	assert.Equal(t, "1-5759e988-bd862e3fe1be46a994272793", logx.XRayTraceID(ctx))
+	assert.Equal(t, "16266516598257821587", logx.DatadogTraceID(ctx))
+	assert.Equal(t, "6023947403358210776", logx.DatadogSpanID(ctx))
+
+	request, _ := http.NewRequest("GET", "http://localhost:8080/test", nil)
+	assert.Nil(t, logx.HttpInject(ctx, request))
+	assert.Equal(t, "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=0", request.Header.Get("X-Amzn-Trace-Id"))
+	assert.Equal(t, "16266516598257821587", request.Header.Get("X-Datadog-Trace-Id"))
+
+	assert.Equal(t, "", logx.XRayTraceID(context.Background()))
+}
+
+func TestGenTraceID(t *testing.T) {
+	logx.Init(logx.Config{}, "local-test")
+	traceID, spanID := logx.GenTraceID(), logx.GenSpanID()
+	assert.Len(t, traceID, 32)
+	assert.Len(t, spanID, 16)
+	assert.NotEqual(t, traceID, logx.GenTraceID())
+	_, err := logx.NewRootContext(traceID, spanID)
+	assert.Nil(t, err)
+}
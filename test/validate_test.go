@@ -0,0 +1,49 @@
+package logx
+
+import (
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	assert.NoError(t, logx.Config{}.Validate())
+	assert.NoError(t, logx.Config{
+		Output:           "file",
+		File:             "./logs/run.log",
+		Level:            "info",
+		Rotate:           "daily",
+		EnableTrace:      true,
+		OTLPEndpoint:     "collector:4318",
+		TraceSampleRatio: 0.5,
+		Sampler:          "parentbased_ratio",
+		LokiServer:       "http://loki:3100",
+	}.Validate())
+
+	err := logx.Config{
+		Output:           "kafka",
+		Level:            "verbose",
+		Rotate:           "0 0 25 * * *",
+		TraceSampleRatio: 1.5,
+		OTLPEndpoint:     "http://collector:4318",
+		LokiServer:       "loki:3100",
+		CompressType:     "lz4",
+		RedactPatterns:   []string{"email", "("},
+	}.Validate()
+	assert.Error(t, err)
+	for _, problem := range []string{
+		"invalid level",
+		"kafka_brokers and kafka_topic are required",
+		"rotate requires output file",
+		"invalid rotate",
+		"trace_sample_ratio must be 0-1",
+		"oltp_endpoint should be host:port",
+		"invalid loki_server",
+		"invalid compress_type",
+		"invalid redact_patterns \"(\"",
+	} {
+		assert.ErrorContains(t, err, problem)
+	}
+	assert.NotContains(t, err.Error(), "\"email\"")
+}
@@ -0,0 +1,100 @@
+package logx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log.yaml")
+	file := filepath.Join(dir, "run.log")
+	assert.NoError(t, os.WriteFile(path, []byte("output: file\nfile: "+file+"\nlevel: info\n"), 0o644))
+	logx.Init(logx.Config{Output: "file", File: file, Level: "info"}, "watch-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	assert.NoError(t, logx.Watch(path))
+	defer logx.Shutdown(context.Background())
+
+	logx.Debug(context.Background(), "debug before reload")
+	// 修改日志等级
+	assert.NoError(t, os.WriteFile(path, []byte("output: file\nfile: "+file+"\nlevel: debug\n"), 0o644))
+	assert.Eventually(t, func() bool {
+		return logx.Enabled(context.Background(), "debug")
+	}, 3*time.Second, 50*time.Millisecond)
+	logx.Debug(context.Background(), "debug after reload")
+	content, _ := os.ReadFile(file)
+	assert.NotContains(t, string(content), "debug before reload")
+	assert.Contains(t, string(content), "debug after reload")
+
+	// 修改日志文件，json格式
+	file2 := filepath.Join(dir, "run2.log")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"output":"file","file":"`+file2+`","level":"debug"}`), 0o644))
+	assert.Eventually(t, func() bool {
+		logx.Info(context.Background(), "after file reload")
+		content, _ := os.ReadFile(file2)
+		return strings.Contains(string(content), "after file reload")
+	}, 3*time.Second, 50*time.Millisecond)
+
+	// 解析失败时保留当前配置
+	assert.NoError(t, os.WriteFile(path, []byte("level: [\n"), 0o644))
+	time.Sleep(1500 * time.Millisecond)
+	assert.True(t, logx.Enabled(context.Background(), "debug"))
+
+	// 先写临时文件再rename覆盖
+	tmp := filepath.Join(dir, "log.yaml.tmp")
+	assert.NoError(t, os.WriteFile(tmp, []byte(`{"output":"file","file":"`+file2+`","level":"info"}`), 0o644))
+	assert.NoError(t, os.Rename(tmp, path))
+	assert.Eventually(t, func() bool {
+		return !logx.Enabled(context.Background(), "debug")
+	}, 3*time.Second, 50*time.Millisecond)
+}
+
+// fixedIDGenerator 生成固定的traceID和spanID
+type fixedIDGenerator struct{}
+
+func (fixedIDGenerator) NewIDs(ctx context.Context) (oteltrace.TraceID, oteltrace.SpanID) {
+	return oteltrace.TraceID{1}, oteltrace.SpanID{1}
+}
+
+func (fixedIDGenerator) NewSpanID(ctx context.Context, traceID oteltrace.TraceID) oteltrace.SpanID {
+	return oteltrace.SpanID{1}
+}
+
+// TestWatchMergeConfig 文件中无法配置的字段保留，只修改Level后再改回也能生效
+func TestWatchMergeConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "log.yaml")
+	file := filepath.Join(dir, "run.log")
+	assert.NoError(t, os.WriteFile(path, []byte("output: file\nfile: "+file+"\nlevel: info\n"), 0o644))
+	logx.Init(logx.Config{Output: "file", File: file, Level: "info", IDGenerator: fixedIDGenerator{}}, "watch-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	assert.NoError(t, logx.Watch(path))
+	defer logx.Shutdown(context.Background())
+
+	assert.NoError(t, os.WriteFile(path, []byte("output: file\nfile: "+file+"\nlevel: debug\n"), 0o644))
+	assert.Eventually(t, func() bool {
+		return logx.Enabled(context.Background(), "debug")
+	}, 3*time.Second, 50*time.Millisecond)
+	assert.NoError(t, os.WriteFile(path, []byte("output: file\nfile: "+file+"\nlevel: info\n"), 0o644))
+	assert.Eventually(t, func() bool {
+		return !logx.Enabled(context.Background(), "debug")
+	}, 3*time.Second, 50*time.Millisecond)
+
+	// 修改日志文件时重新初始化，保留IDGenerator
+	file2 := filepath.Join(dir, "run2.log")
+	assert.NoError(t, os.WriteFile(path, []byte("output: file\nfile: "+file2+"\nlevel: info\n"), 0o644))
+	assert.Eventually(t, func() bool {
+		logx.Info(context.Background(), "after file reload")
+		content, _ := os.ReadFile(file2)
+		return strings.Contains(string(content), "after file reload")
+	}, 3*time.Second, 50*time.Millisecond)
+	assert.Equal(t, oteltrace.TraceID{1}.String(), logx.GenTraceID())
+}
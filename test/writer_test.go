@@ -0,0 +1,32 @@
+package logx
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWriter(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	logx.InitWithOptions(logx.Config{}, "local-test", logx.WithZapCore(core))
+
+	fmt.Fprintln(logx.Writer(context.Background(), "warn"), "line1\nline2")
+	entries := logs.TakeAll()
+	assert.Len(t, entries, 2)
+	assert.Equal(t, zapcore.WarnLevel, entries[0].Level)
+	assert.Equal(t, "line2", entries[1].Message)
+
+	restore := logx.RedirectStdLog()
+	log.Printf("hello %s", "world")
+	restore()
+	entries = logs.TakeAll()
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "hello world", entries[0].Message)
+}
@@ -0,0 +1,38 @@
+package logx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itmisx/logx"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestZapFile(t *testing.T) {
+	dir := t.TempDir()
+	logx.Init(logx.Config{
+		Output:    "file",
+		File:      filepath.Join(dir, "run.log"),
+		ErrorFile: filepath.Join(dir, "error.log"),
+		Level:     "info",
+	}, "zap-test")
+	defer logx.Init(logx.Config{}, "local-test")
+	ctx := logx.WithFields(context.Background(), logx.String("uid", "u1"))
+	logx.Zap().Debug("zap debug", logx.ZapContext(ctx))
+	logx.Zap().Info("zap info", logx.ZapContext(ctx), zap.Int("n", 1))
+	logx.Zap().With(logx.ZapContext(ctx)).Error("zap error")
+
+	run, _ := os.ReadFile(filepath.Join(dir, "run.log"))
+	errs, _ := os.ReadFile(filepath.Join(dir, "error.log"))
+	assert.Contains(t, string(run), `"msg":"zap info"`)
+	assert.Contains(t, string(run), `"uid":"u1"`)
+	assert.Contains(t, string(run), `"n":1`)
+	assert.NotContains(t, string(run), "zap debug")
+	assert.NotContains(t, string(run), "zap error")
+	assert.Contains(t, string(errs), `"msg":"zap error"`)
+	assert.Contains(t, string(errs), `"uid":"u1"`)
+	assert.NotContains(t, string(errs), "zap info")
+}
@@ -2,22 +2,44 @@ package logx
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"math"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
-	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-type Trace struct{}
+type Trace struct {
+	// Init时为正在创建的logState，用于读取WithSampler等可选项及保存磁盘队列
+	state *logState
+}
+
+// stateOf Init时为正在创建的logState，直接调用NewOLTPProvider等时为当前生效的
+func (tx Trace) stateOf() *logState {
+	if tx.state != nil {
+		return tx.state
+	}
+	return loadState()
+}
 
 // NewOLTPProvider
 func (tx Trace) NewOLTPProvider(
@@ -36,6 +58,14 @@ func (tx Trace) NewOLTPProvider(
 	if conf.OLTPInsecure {
 		options = append(options, otlptracehttp.WithInsecure())
 	}
+	if conf.ExportRetry > 0 {
+		options = append(options, otlptracehttp.WithRetry(otlptracehttp.RetryConfig{
+			Enabled:         true,
+			InitialInterval: 5 * time.Second,
+			MaxInterval:     30 * time.Second,
+			MaxElapsedTime:  conf.ExportRetry,
+		}))
+	}
 	if conf.OTLPToken != "" {
 		options = append(options, otlptracehttp.WithHeaders(map[string]string{
 			"Authorization": "Basic " + conf.OTLPToken,
@@ -52,41 +82,213 @@ func (tx Trace) NewOLTPProvider(
 	attributes = append(attributes, String("service.name", serviceName))
 	// For the demonstration, use sdktrace.AlwaysSample sampler to sample all traces.
 	// In a production application, use sdktrace.ProbabilitySampler with a desired probability.
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithSampler(
-			sdktrace.TraceIDRatioBased(conf.TraceSampleRatio), // 没父 span 的时候按 10 % 随机采样
-		),
-		sdktrace.WithBatcher(exporter),
+	var sampler sdktrace.Sampler = samplerOf(conf, traceSampler.set(conf.TraceSampleRatio)) // 没父 span 的时候按 10 % 随机采样
+	if o := tx.stateOf().opts; o.sampler != nil {
+		sampler = o.sampler
+	}
+	var spanExporter sdktrace.SpanExporter = countingExporter{exporter}
+	// 导出失败时保存到磁盘
+	if conf.SpoolDir != "" {
+		spooler := newSpoolExporter(spanExporter, conf.SpoolDir, conf.SpoolMaxBytes, conf.SpoolMaxAge)
+		spanExporter = spooler
+		if tx.state != nil {
+			tx.state.spooler = spooler
+		}
+	}
+	sampler = countingSampler{base: newBaggageSampler(conf.TraceSampleBaggage, newRuleSampler(conf.SampleRules, sampler))}
+	providerOptions := []sdktrace.TracerProviderOption{
+		sdktrace.WithSpanProcessor(batcherOf(conf, spanExporter)),
+		sdktrace.WithSpanProcessor(countingProcessor{}),
 		sdktrace.WithResource(resource.NewWithAttributes(
 			semconv.SchemaURL,
-			FieldsToKeyValues(attributes...)...,
+			tx.stateOf().fieldsToKeyValues("", attributes...)...,
 		)),
-	)
+		sdktrace.WithIDGenerator(idGeneratorOf(conf)),
+		spanLimitsOf(conf),
+	}
+	if conf.FlushErrorSpans > 0 {
+		providerOptions = append(providerOptions, sdktrace.WithSpanProcessor(newErrorFlushProcessor(conf.FlushErrorSpans, conf.FlushErrorWindow)))
+	}
+	// 保存最近结束的span，未采样的span也需要记录
+	if conf.RecentSpans > 0 {
+		recentSpans = newRecentSpanProcessor(conf.RecentSpans, exporter)
+		sampler = recordOnlySampler{base: sampler}
+		providerOptions = append(providerOptions, sdktrace.WithSpanProcessor(recentSpans))
+	}
+	tp := sdktrace.NewTracerProvider(append(providerOptions, sdktrace.WithSampler(sampler))...)
 	otel.SetTracerProvider(tp)
-	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
 	return tp, err
 }
 
 // NewFileProvider
+// TraceFile为空时写入当前目录的trace.txt，未配置切割时每次启动清空文件
 func (tx Trace) NewFileProvider(conf Config, serviceName string, attributes ...Field) (*sdktrace.TracerProvider, error) {
-	f, _ := os.Create("trace.txt")
-	exp, _ := newExporter(f)
+	w, err := traceFileWriter(conf)
+	if err != nil {
+		return nil, err
+	}
+	exp, err := newExporter(w)
+	if err != nil {
+		return nil, err
+	}
 	attributes = append(attributes, String("service.name", serviceName))
-	tp := sdktrace.NewTracerProvider(
+	sampler := samplerOf(conf, sdktrace.AlwaysSample())
+	if o := tx.stateOf().opts; o.sampler != nil {
+		sampler = o.sampler
+	}
+	providerOptions := []sdktrace.TracerProviderOption{
 		// Always be sure to batch in production.
-		sdktrace.WithBatcher(exp),
+		sdktrace.WithSpanProcessor(batcherOf(conf, countingExporter{exp})),
+		sdktrace.WithSpanProcessor(countingProcessor{}),
 		// Record information about this application in an Resource.
 		sdktrace.WithResource(resource.NewWithAttributes(
 			semconv.SchemaURL,
-			FieldsToKeyValues(attributes...)...,
+			tx.stateOf().fieldsToKeyValues("", attributes...)...,
 		)),
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
-	)
+		sdktrace.WithSampler(countingSampler{base: newBaggageSampler(conf.TraceSampleBaggage, newRuleSampler(conf.SampleRules, sampler))}),
+		sdktrace.WithIDGenerator(idGeneratorOf(conf)),
+		spanLimitsOf(conf),
+	}
+	if conf.FlushErrorSpans > 0 {
+		providerOptions = append(providerOptions, sdktrace.WithSpanProcessor(newErrorFlushProcessor(conf.FlushErrorSpans, conf.FlushErrorWindow)))
+	}
+	tp := sdktrace.NewTracerProvider(providerOptions...)
 
 	otel.SetTracerProvider(tp)
 	return tp, nil
 }
 
+// traceFileWriter 打开追踪文件，配置TraceFileMaxSize或TraceFileMaxAge时按大小及时间切割
+func traceFileWriter(conf Config) (io.Writer, error) {
+	path := conf.TraceFile
+	if path == "" {
+		path = "trace.txt"
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+	if conf.TraceFileMaxSize <= 0 && conf.TraceFileMaxAge <= 0 {
+		return os.Create(path)
+	}
+	// lumberjack在写入时才打开文件，提前检查是否可写
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    conf.TraceFileMaxSize,
+		MaxAge:     conf.TraceFileMaxAge,
+		MaxBackups: conf.TraceFileMaxBackups,
+		LocalTime:  true,
+	}, nil
+}
+
+// traceSampler 按比率采样，支持运行时修改
+var traceSampler = &ratioSampler{}
+
+// ratioSampler 按比率采样的采样器，通过SetTraceSampleRatio线程安全地修改比率
+type ratioSampler struct {
+	current atomic.Pointer[ratioSamplerState]
+}
+
+type ratioSamplerState struct {
+	ratio   float64
+	sampler sdktrace.Sampler
+}
+
+// set 设置采样比率
+func (s *ratioSampler) set(ratio float64) *ratioSampler {
+	s.current.Store(&ratioSamplerState{ratio: ratio, sampler: sdktrace.TraceIDRatioBased(ratio)})
+	return s
+}
+
+func (s *ratioSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	state := s.current.Load()
+	if state == nil {
+		return sdktrace.NeverSample().ShouldSample(p)
+	}
+	return state.sampler.ShouldSample(p)
+}
+
+func (s *ratioSampler) Description() string {
+	state := s.current.Load()
+	if state == nil {
+		return "RatioSampler{}"
+	}
+	return "RatioSampler{" + state.sampler.Description() + "}"
+}
+
+// SetTraceSampleRatio 运行时修改追踪采样的比率，0.0-1
+// 仅对oltp类型的tracerProvider生效
+func SetTraceSampleRatio(ratio float64) error {
+	if ratio < 0 || ratio > 1 {
+		return errors.New("invalid trace sample ratio")
+	}
+	var old float64
+	if state := traceSampler.current.Load(); state != nil {
+		old = state.ratio
+	}
+	traceSampler.set(ratio)
+	Info(context.Background(), "trace sample ratio changed", Float64("old", old), Float64("new", ratio))
+	return nil
+}
+
+// baggageSampler baggage匹配时强制采样，否则使用base采样
+type baggageSampler struct {
+	rules map[string]string
+	base  sdktrace.Sampler
+}
+
+// newBaggageSampler rules格式为key或key=value
+func newBaggageSampler(rules []string, base sdktrace.Sampler) sdktrace.Sampler {
+	if len(rules) == 0 {
+		return base
+	}
+	sampler := baggageSampler{rules: map[string]string{}, base: base}
+	for _, rule := range rules {
+		key, value, _ := strings.Cut(rule, "=")
+		sampler.rules[key] = value
+	}
+	return sampler
+}
+
+func (s baggageSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	bag := baggage.FromContext(p.ParentContext)
+	for key, value := range s.rules {
+		member := bag.Member(key)
+		if member.Key() == "" {
+			continue
+		}
+		if value == "" || member.Value() == value {
+			return sdktrace.SamplingResult{
+				Decision:   sdktrace.RecordAndSample,
+				Tracestate: oteltrace.SpanContextFromContext(p.ParentContext).TraceState(),
+			}
+		}
+	}
+	return s.base.ShouldSample(p)
+}
+
+func (s baggageSampler) Description() string {
+	return "BaggageSampler{" + s.base.Description() + "}"
+}
+
+// WithBaggage 设置baggage，baggage会随HttpInject传递到下游服务
+// 配合TraceSampleBaggage可以在入口处强制采样
+func WithBaggage(ctx context.Context, key, value string) (context.Context, error) {
+	member, err := baggage.NewMember(key, value)
+	if err != nil {
+		return ctx, err
+	}
+	bag, err := baggage.FromContext(ctx).SetMember(member)
+	if err != nil {
+		return ctx, err
+	}
+	return baggage.ContextWithBaggage(ctx, bag), nil
+}
+
 func newExporter(w io.Writer) (sdktrace.SpanExporter, error) {
 	return stdouttrace.New(
 		stdouttrace.WithWriter(w),
@@ -99,41 +301,113 @@ func newExporter(w io.Writer) (sdktrace.SpanExporter, error) {
 
 // FieldsToKeyValue
 func FieldsToKeyValues(fields ...Field) []attribute.KeyValue {
-	kvs := []attribute.KeyValue{}
+	s := loadState()
+	return s.fieldsToKeyValues(s.config.AttributeNamespace, s.limitFields(fields)...)
+}
+
+// fieldsToKeyValues namespace不为空时，为key添加前缀
+func (s *logState) fieldsToKeyValues(namespace string, fields ...Field) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, fieldsCap(fields, 2))
+	group := ""
 	for _, f := range fields {
+		f = s.redactField(f)
+		key := namespaceKey(namespace, group+f.Key)
 		switch f.Type {
+		case namespaceType:
+			group += f.Key + "."
 		case boolType:
-			kvs = append(kvs, attribute.Bool(f.Key, f.Bool))
+			kvs = append(kvs, attribute.Bool(key, f.Bool))
 		case boolSliceType:
-			kvs = append(kvs, attribute.BoolSlice(f.Key, f.Bools))
+			kvs = append(kvs, attribute.BoolSlice(key, f.Bools))
 		case intType:
-			kvs = append(kvs, attribute.Int(f.Key, f.Integer))
+			kvs = append(kvs, attribute.Int(key, f.Integer))
 		case intSliceType:
-			kvs = append(kvs, attribute.IntSlice(f.Key, f.Integers))
+			kvs = append(kvs, attribute.IntSlice(key, f.Integers))
 		case int64Type:
-			kvs = append(kvs, attribute.Int64(f.Key, f.Integer64))
+			kvs = append(kvs, attribute.Int64(key, f.Integer64))
 		case int64SliceType:
-			kvs = append(kvs, attribute.Int64Slice(f.Key, f.Integer64s))
+			kvs = append(kvs, attribute.Int64Slice(key, f.Integer64s))
 		case float64Type:
-			kvs = append(kvs, attribute.Float64(f.Key, f.Float64))
+			kvs = append(kvs, attribute.Float64(key, f.Float64))
 		case float64SliceType:
-			kvs = append(kvs, attribute.Float64Slice(f.Key, f.Float64s))
+			kvs = append(kvs, attribute.Float64Slice(key, f.Float64s))
 		case stringType:
-			kvs = append(kvs, attribute.String(f.Key, f.String))
+			kvs = append(kvs, attribute.String(key, f.String))
 		case stringSliceType:
-			kvs = append(kvs, attribute.StringSlice(f.Key, f.Strings))
+			kvs = append(kvs, attribute.StringSlice(key, f.Strings))
 		case stringerType:
 			stringer := stringer{str: f.String}
-			kvs = append(kvs, attribute.Stringer(f.Key, stringer))
+			kvs = append(kvs, attribute.Stringer(key, stringer))
 		case anyType:
 			if str, err := json.Marshal(f.Any); err == nil {
-				kvs = append(kvs, attribute.String(f.Key, string(str)))
+				kvs = append(kvs, attribute.String(key, string(str)))
+			}
+		case uintType:
+			kvs = append(kvs, uint64Attribute(key, uint64(f.Uinteger)))
+		case uint64Type:
+			kvs = append(kvs, uint64Attribute(key, f.Uinteger64))
+		case float32Type:
+			kvs = append(kvs, attribute.Float64(key, float64(f.Float32)))
+		case durationType:
+			kvs = append(kvs, attribute.String(key, f.Duration.String()))
+		case timeType:
+			kvs = append(kvs, attribute.String(key, f.Time.Format(time.RFC3339Nano)))
+		case byteStringType:
+			kvs = append(kvs, attribute.String(key, string(f.Bytes)))
+		case binaryType:
+			kvs = append(kvs, attribute.String(key, base64.StdEncoding.EncodeToString(f.Bytes)))
+		case grpcStatusType:
+			kvs = append(kvs, attribute.Int(f.Key, f.Integer))
+		case objectType, arrayType:
+			kvs = marshalerKeyValues(key, marshalerValue(f), kvs)
+		case errType:
+			kvs = append(kvs,
+				semconv.ExceptionTypeKey.String(fmt.Sprintf("%T", f.Err)),
+				semconv.ExceptionMessageKey.String(f.Err.Error()),
+			)
+			if f.Stack != "" {
+				kvs = append(kvs, semconv.ExceptionStacktraceKey.String(f.Stack))
 			}
 		}
 	}
 	return kvs
 }
 
+// spanRecordError 将错误记录为span的exception事件，exception.type,exception.message由otel按记录的错误生成
+// err为nil时记录attributes中ErrStack的错误，都没有时以msg作为错误，ErrStack只额外添加exception.stacktrace
+func spanRecordError(span oteltrace.Span, err error, msg string, attributes []Field) {
+	var stack string
+	fields := make([]Field, 0, len(attributes))
+	for _, f := range attributes {
+		if f.Type != errType {
+			fields = append(fields, f)
+			continue
+		}
+		if err == nil {
+			err = f.Err
+		}
+		if stack == "" {
+			stack = f.Stack
+		}
+	}
+	if err == nil {
+		err = errors.New(msg)
+	}
+	kvs := FieldsToKeyValues(fields...)
+	if stack != "" {
+		kvs = append(kvs, semconv.ExceptionStacktraceKey.String(stack))
+	}
+	span.RecordError(err, oteltrace.WithAttributes(kvs...))
+}
+
+// uint64Attribute otel不支持uint64，超过int64范围的以字符串表示
+func uint64Attribute(key string, val uint64) attribute.KeyValue {
+	if val > math.MaxInt64 {
+		return attribute.String(key, strconv.FormatUint(val, 10))
+	}
+	return attribute.Int64(key, int64(val))
+}
+
 // stringer fmt.Stringer
 type stringer struct {
 	str string
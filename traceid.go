@@ -0,0 +1,128 @@
+package logx
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"net/http"
+	"strconv"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// IDGenerator 生成traceID和spanID
+type IDGenerator = sdktrace.IDGenerator
+
+// idGeneratorOf 返回配置的IDGenerator，未配置时使用crypto/rand
+func idGeneratorOf(conf Config) IDGenerator {
+	if conf.IDGenerator != nil {
+		return conf.IDGenerator
+	}
+	return randomIDGenerator{}
+}
+
+// randomIDGenerator 使用crypto/rand生成符合W3C规范（非全0）的traceID和spanID
+type randomIDGenerator struct{}
+
+func (gen randomIDGenerator) NewIDs(ctx context.Context) (oteltrace.TraceID, oteltrace.SpanID) {
+	var traceID oteltrace.TraceID
+	for !traceID.IsValid() {
+		_, _ = rand.Read(traceID[:])
+	}
+	return traceID, gen.NewSpanID(ctx, traceID)
+}
+
+func (gen randomIDGenerator) NewSpanID(ctx context.Context, traceID oteltrace.TraceID) oteltrace.SpanID {
+	var spanID oteltrace.SpanID
+	for !spanID.IsValid() {
+		_, _ = rand.Read(spanID[:])
+	}
+	return spanID
+}
+
+// spanContext return the spanContext of the logx span in ctx
+func spanContext(ctx context.Context) oteltrace.SpanContext {
+	loggerSpanContext, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext)
+	if !ok {
+		return oteltrace.SpanContext{}
+	}
+	return loggerSpanContext.span.SpanContext()
+}
+
+// XRayTraceID return traceID in aws x-ray format
+// 1-{8位16进制时间戳}-{24位16进制随机数}
+func XRayTraceID(ctx context.Context) string {
+	sc := spanContext(ctx)
+	if !sc.TraceID().IsValid() {
+		return ""
+	}
+	traceID := sc.TraceID().String()
+	return "1-" + traceID[:8] + "-" + traceID[8:]
+}
+
+// DatadogTraceID return the low 64 bits of traceID in decimal
+func DatadogTraceID(ctx context.Context) string {
+	sc := spanContext(ctx)
+	if !sc.TraceID().IsValid() {
+		return ""
+	}
+	tID := sc.TraceID()
+	return strconv.FormatUint(binary.BigEndian.Uint64(tID[8:]), 10)
+}
+
+// DatadogSpanID return spanID in decimal
+func DatadogSpanID(ctx context.Context) string {
+	sc := spanContext(ctx)
+	if !sc.SpanID().IsValid() {
+		return ""
+	}
+	sID := sc.SpanID()
+	return strconv.FormatUint(binary.BigEndian.Uint64(sID[:]), 10)
+}
+
+// traceIDFormatFields 根据配置的TraceIDFormats生成日志字段
+func (s *logState) traceIDFormatFields(ctx context.Context) []Field {
+	var fields []Field
+	if s.config.Encoder == "gcp" {
+		fields = append(fields, gcpTraceFields(ctx, s.config.GCPProject)...)
+	}
+	for _, format := range s.config.TraceIDFormats {
+		switch format {
+		case "xray":
+			if traceID := XRayTraceID(ctx); traceID != "" {
+				fields = append(fields, String("xray_trace_id", traceID))
+			}
+		case "datadog":
+			if traceID := DatadogTraceID(ctx); traceID != "" {
+				fields = append(fields, String("dd.trace_id", traceID))
+			}
+			if spanID := DatadogSpanID(ctx); spanID != "" {
+				fields = append(fields, String("dd.span_id", spanID))
+			}
+		}
+	}
+	return fields
+}
+
+// injectTraceIDFormats 根据配置的TraceIDFormats注入对应的header
+func injectTraceIDFormats(ctx context.Context, header http.Header) {
+	sc := spanContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	sampled := "0"
+	if sc.IsSampled() {
+		sampled = "1"
+	}
+	for _, format := range loadState().config.TraceIDFormats {
+		switch format {
+		case "xray":
+			header.Set("X-Amzn-Trace-Id", "Root="+XRayTraceID(ctx)+";Parent="+sc.SpanID().String()+";Sampled="+sampled)
+		case "datadog":
+			header.Set("X-Datadog-Trace-Id", DatadogTraceID(ctx))
+			header.Set("X-Datadog-Parent-Id", DatadogSpanID(ctx))
+			header.Set("X-Datadog-Sampling-Priority", sampled)
+		}
+	}
+}
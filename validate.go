@@ -0,0 +1,175 @@
+package logx
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap/zapcore"
+)
+
+// cronParser 与rotateCrond一致，精确到秒
+var cronParser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// Validate 检查配置，返回所有的问题，便于启动时发现错误的配置，而不是使用默认值
+// Init不会调用Validate，需在Init之前自行调用
+//
+// example:
+//
+//	if err := conf.Validate(); err != nil {
+//		log.Fatal(err)
+//	}
+func (conf Config) Validate() error {
+	var errs []error
+	add := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("logx: "+format, args...))
+	}
+	for _, v := range []struct{ name, value string }{{"level", conf.Level}, {"stacktrace_level", conf.StacktraceLevel}} {
+		if v.value == "" {
+			continue
+		}
+		if _, err := zapcore.ParseLevel(v.value); err != nil {
+			add("invalid %s %q", v.name, v.value)
+		}
+	}
+	switch conf.Output {
+	case "", "none", "console":
+	case "file":
+		if conf.File == "" {
+			add("file is required for output file")
+		}
+	case "kafka":
+		if len(conf.KafkaBrokers) == 0 || conf.KafkaTopic == "" {
+			add("kafka_brokers and kafka_topic are required for output kafka")
+		}
+	case "elasticsearch":
+		if conf.ESServer == "" {
+			add("es_server is required for output elasticsearch")
+		}
+	case "fluent":
+	case "cloudwatch":
+		if conf.CloudWatchGroup == "" {
+			add("cloudwatch_group is required for output cloudwatch")
+		}
+	default:
+		add("invalid output %q", conf.Output)
+	}
+	if conf.Output != "file" {
+		for _, v := range []struct{ name, value string }{{"error_file", conf.ErrorFile}, {"file_link", conf.FileLink}, {"rotate", conf.Rotate}} {
+			if v.value != "" {
+				add("%s requires output file", v.name)
+			}
+		}
+	}
+	switch conf.Encoder {
+	case "", "json", "console", "gcp", "ecs", "logfmt":
+	default:
+		add("invalid encoder %q", conf.Encoder)
+	}
+	if conf.TimeZone != "" {
+		if _, err := time.LoadLocation(conf.TimeZone); err != nil {
+			add("invalid time_zone %q", conf.TimeZone)
+		}
+	}
+	if conf.Rotate != "" {
+		if _, err := cronParser.Parse(rotateSpec(conf.Rotate)); err != nil {
+			add("invalid rotate %q: %v", conf.Rotate, err)
+		}
+	}
+	if conf.RotateFilename != "" && lumberjackCompress(conf) {
+		add("rotate_filename does not take effect with compress unless compress_type or compress_level is set")
+	}
+	switch conf.CompressType {
+	case "", "gzip":
+		if conf.CompressLevel != 0 && (conf.CompressLevel < 1 || conf.CompressLevel > 9) {
+			add("compress_level must be 1-9 for gzip")
+		}
+	case "zstd":
+		if conf.CompressLevel < 0 || conf.CompressLevel > 22 {
+			add("compress_level must be 1-22 for zstd")
+		}
+	default:
+		add("invalid compress_type %q", conf.CompressType)
+	}
+	for _, v := range []struct {
+		name  string
+		value int
+	}{{"max_size", conf.MaxSize}, {"max_backups", conf.MaxBackups}, {"max_age", conf.MaxAge}, {"max_total_size", conf.MaxTotalSize},
+		{"max_entry_bytes", conf.MaxEntryBytes}, {"max_field_length", conf.MaxFieldLength}, {"max_fields", conf.MaxFields}} {
+		if v.value < 0 {
+			add("%s must not be negative", v.name)
+		}
+	}
+	switch conf.TracerProviderType {
+	case "", "oltp", "file":
+	default:
+		add("invalid tracer_provider_type %q", conf.TracerProviderType)
+	}
+	if conf.TraceSampleRatio < 0 || conf.TraceSampleRatio > 1 {
+		add("trace_sample_ratio must be 0-1")
+	}
+	switch conf.Sampler {
+	case "", "always", "never", "ratio", "parentbased_ratio":
+	case "ratelimit", "parentbased_ratelimit":
+		if conf.SampleRateLimit <= 0 {
+			add("sample_rate_limit must be positive for sampler %s", conf.Sampler)
+		}
+	default:
+		add("invalid sampler %q", conf.Sampler)
+	}
+	for i, rule := range conf.SampleRules {
+		if rule.Ratio < 0 || rule.Ratio > 1 {
+			add("sample_rules[%d].ratio must be 0-1", i)
+		}
+	}
+	switch conf.TailSampling {
+	case "", "error", "warn":
+	default:
+		add("invalid tail_sampling %q", conf.TailSampling)
+	}
+	for _, format := range conf.TraceIDFormats {
+		if format != "xray" && format != "datadog" {
+			add("invalid trace_id_formats %q", format)
+		}
+	}
+	if conf.OTLPEndpoint != "" {
+		if strings.Contains(conf.OTLPEndpoint, "://") {
+			add("oltp_endpoint should be host:port without scheme, got %q", conf.OTLPEndpoint)
+		} else if _, _, err := net.SplitHostPort(conf.OTLPEndpoint); err != nil {
+			add("invalid oltp_endpoint %q: %v", conf.OTLPEndpoint, err)
+		}
+	}
+	for _, v := range []struct{ name, value string }{{"loki_server", conf.LokiServer}, {"es_server", conf.ESServer},
+		{"cloudwatch_endpoint", conf.CloudWatchEndpoint}, {"sentry_dsn", conf.SentryDSN}, {"alert_webhook", conf.AlertWebhook}} {
+		if v.value == "" {
+			continue
+		}
+		if u, err := url.Parse(v.value); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			add("invalid %s %q, expected http(s)://host", v.name, v.value)
+		}
+	}
+	if conf.FluentAddress != "" {
+		if _, _, err := net.SplitHostPort(conf.FluentAddress); err != nil {
+			add("invalid fluent_address %q: %v", conf.FluentAddress, err)
+		}
+	}
+	switch conf.AlertWebhookType {
+	case "", "slack", "dingtalk", "wecom":
+	default:
+		add("invalid alert_webhook_type %q", conf.AlertWebhookType)
+	}
+	for _, pattern := range conf.RedactPatterns {
+		if _, ok := redactPatterns[pattern]; ok {
+			continue
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			add("invalid redact_patterns %q: %v", pattern, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,192 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"gopkg.in/yaml.v3"
+)
+
+// watchDebounce 文件事件后等待的时间，合并一次保存产生的多个事件，避免读到写入一半的文件
+const watchDebounce = 100 * time.Millisecond
+
+// initArgs 最近一次Init的参数，用于热加载时重新初始化
+var initArgs struct {
+	serviceName string
+	options     []Option
+}
+
+var (
+	watchMu   sync.Mutex
+	watchStop chan struct{}
+)
+
+// Watch 监听yaml或json格式的配置文件，文件变化时热加载配置
+// 文件的配置合并到最近一次应用的配置，IDGenerator等文件中无法配置的字段保持不变
+// Level,TraceSampleRatio变化时直接修改，其他配置(输出、文件、采样等)变化时调用Reconfigure
+// 重复调用时停止之前的监听，文件解析失败时记录日志并保留当前配置
+// 监听的是文件所在的目录，编辑器先写临时文件再rename、替换软链接(如k8s configmap)等方式也能感知
+//
+// example:
+//
+//	logx.Init(conf, "service1")
+//	logx.Watch("./config/log.yaml")
+func Watch(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if _, err := parseConfig(content); err != nil {
+		return err
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return err
+	}
+	path = filepath.Clean(path)
+	realPath, _ := filepath.EvalSymlinks(path)
+	stop := make(chan struct{})
+	watchMu.Lock()
+	if watchStop != nil {
+		close(watchStop)
+	}
+	watchStop = stop
+	watchMu.Unlock()
+	go func() {
+		defer watcher.Close()
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-stop:
+				return
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Printf("logx: watch config %s failed: %v", path, err)
+				continue
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// 只处理配置文件本身，或软链接指向的文件发生变化
+				target, _ := filepath.EvalSymlinks(path)
+				if filepath.Clean(event.Name) == path || target != realPath {
+					realPath = target
+					debounce = time.After(watchDebounce)
+				}
+				continue
+			case <-debounce:
+				debounce = nil
+			}
+			latest, err := os.ReadFile(path)
+			if err != nil || bytes.Equal(latest, content) {
+				continue
+			}
+			content = latest
+			conf, err := parseConfig(latest)
+			if err != nil {
+				log.Printf("logx: reload config %s failed: %v", path, err)
+				continue
+			}
+			reloadConfig(conf)
+		}
+	}()
+	return nil
+}
+
+// stopWatch 停止监听配置文件
+func stopWatch() {
+	watchMu.Lock()
+	defer watchMu.Unlock()
+	if watchStop != nil {
+		close(watchStop)
+		watchStop = nil
+	}
+}
+
+// parseConfig 解析yaml或json格式的配置，json为yaml的子集，字段名与yaml tag一致
+func parseConfig(content []byte) (Config, error) {
+	var conf Config
+	if err := yaml.Unmarshal(content, &conf); err != nil {
+		return conf, err
+	}
+	return conf, nil
+}
+
+// reloadConfig 将文件的配置合并到最近一次应用的配置，并应用变化
+func reloadConfig(file Config) {
+	old := loadState().applied
+	conf := mergeConfig(old, file)
+	if reflect.DeepEqual(old, conf) {
+		return
+	}
+	rest, oldRest := conf, old
+	rest.Level, oldRest.Level = "", ""
+	rest.TraceSampleRatio, oldRest.TraceSampleRatio = 0, 0
+	if !reflect.DeepEqual(rest, oldRest) {
+		if err := Reconfigure(conf); err != nil {
+			log.Printf("logx: reload config failed: %v", err)
+			return
+		}
+		Info(context.Background(), "log config reloaded")
+		return
+	}
+	if conf.Level != old.Level {
+		// 与Init一致，未配置时按Debug为debug或error
+		level := conf.Level
+		if level == "" && conf.Debug {
+			level = "debug"
+		} else if level == "" {
+			level = "error"
+		}
+		if err := SetLevel(level); err != nil {
+			Error(context.Background(), "reload log level failed", Err(err))
+		} else {
+			updateConfig(func(c *Config) { c.Level = conf.Level })
+		}
+	}
+	if conf.TraceSampleRatio != old.TraceSampleRatio {
+		if err := SetTraceSampleRatio(conf.TraceSampleRatio); err != nil {
+			Error(context.Background(), "reload trace sample ratio failed", Err(err))
+		} else {
+			updateConfig(func(c *Config) { c.TraceSampleRatio = conf.TraceSampleRatio })
+		}
+	}
+}
+
+// mergeConfig 文件中无法配置的字段(yaml:"-"，如IDGenerator)保留applied的值
+func mergeConfig(applied, file Config) Config {
+	dst, src := reflect.ValueOf(&file).Elem(), reflect.ValueOf(applied)
+	for i := 0; i < dst.NumField(); i++ {
+		if dst.Type().Field(i).Tag.Get("yaml") == "-" {
+			dst.Field(i).Set(src.Field(i))
+		}
+	}
+	return file
+}
+
+// updateConfig 修改当前的配置，用于只修改Level等不需要重新初始化的配置
+// 之后Reconfigure或热加载时以修改后的配置比较
+func updateConfig(update func(conf *Config)) {
+	initMu.Lock()
+	defer initMu.Unlock()
+	if current.Load() == nil {
+		return
+	}
+	next := *loadState()
+	update(&next.config)
+	update(&next.applied)
+	storeState(&next)
+}
@@ -0,0 +1,60 @@
+package logx
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// logWriter 将写入的内容按行记录为日志
+type logWriter struct {
+	ctx   context.Context
+	level zapcore.Level
+}
+
+// Writer 返回一个io.Writer，写入的每一行都会以level等级记录为日志
+// 日志附带ctx中的traceID,spanID及fields。level无效时使用info，高于error的等级按error记录
+//
+// example:
+// server := &http.Server{ErrorLog: log.New(Writer(ctx, "error"), "", 0)}
+func Writer(ctx context.Context, level string) io.Writer {
+	l, err := zapcore.ParseLevel(level)
+	if err != nil {
+		l = zapcore.InfoLevel
+	}
+	return logWriter{ctx: ctx, level: l}
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	for _, line := range bytes.Split(bytes.TrimRight(p, "\n"), []byte("\n")) {
+		msg := string(line)
+		switch {
+		case w.level <= zapcore.DebugLevel:
+			Debug(w.ctx, msg)
+		case w.level == zapcore.InfoLevel:
+			Info(w.ctx, msg)
+		case w.level == zapcore.WarnLevel:
+			Warn(w.ctx, msg)
+		default:
+			Error(w.ctx, msg)
+		}
+	}
+	return len(p), nil
+}
+
+// RedirectStdLog 将标准库log的输出重定向为info日志，返回恢复原输出的函数
+// 用于记录第三方库通过log.Printf等输出的内容
+func RedirectStdLog() func() {
+	flags, prefix, output := log.Flags(), log.Prefix(), log.Writer()
+	log.SetFlags(0)
+	log.SetPrefix("")
+	log.SetOutput(Writer(context.Background(), "info"))
+	return func() {
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+		log.SetOutput(output)
+	}
+}
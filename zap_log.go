@@ -1,11 +1,15 @@
 package logx
 
 import (
+	"context"
+	"io"
+	"log"
+	"net/http"
 	"os"
-	"sync"
 	"time"
 
 	"github.com/robfig/cron/v3"
+	oteltrace "go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
@@ -14,17 +18,60 @@ import (
 // var zlogger *zap.Logger
 type zapLogger struct {
 	Logger    *zap.Logger
-	lumLogger *lumberjack.Logger
+	lumLogger fileRotator
+	// 不受日志等级限制的logger，用于ErrorBoost
+	Boost *zap.Logger
+	// 用于Rotate的日志文件，未输出到文件时为nil
+	rotator fileRotator
+	// 日志文件及kafka等输出，替换时关闭
+	closers []io.Closer
 }
 
-var rotateCrondOnce sync.Once
+// rotateCron 定时切割日志文件，未配置Rotate时为nil
+var rotateCron *cron.Cron
 
-// newZLogger init a zap logger
-func newZapLogger(conf Config) zapLogger {
-	// maxage default 7 days
-	if config.MaxAge == 0 {
-		config.MaxAge = 7
+// sink 发送到外部服务的输出，替换或Shutdown时关闭
+type sink interface {
+	zapcore.WriteSyncer
+	io.Closer
+}
+
+// isSinkOutput 是否为发送到外部服务的输出
+func isSinkOutput(output string) bool {
+	return output == "kafka" || output == "elasticsearch" || output == "fluent" || output == "cloudwatch"
+}
+
+// newSink 根据Output创建输出
+func newSink(conf Config, service string, o initOptions) (sink, error) {
+	switch conf.Output {
+	case "elasticsearch":
+		return newESSink(conf, service)
+	case "fluent":
+		return newFluentSink(conf, service)
+	case "cloudwatch":
+		return newCloudWatchSink(conf, service)
 	}
+	return newKafkaSink(conf, o.kafka)
+}
+
+// atomicLevel 日志等级，支持运行时修改
+var atomicLevel = zap.NewAtomicLevelAt(zap.ErrorLevel)
+
+// levelRange 配置ErrorFile时，errors为false时只包含低于error的等级，为true时只包含error及以上
+type levelRange struct {
+	level  zapcore.LevelEnabler
+	errors bool
+}
+
+func (r levelRange) Enabled(l zapcore.Level) bool {
+	return r.level.Enabled(l) && (l >= zapcore.ErrorLevel) == r.errors
+}
+
+// newZLogger init a zap logger
+// o.zapCores 自定义的zap core，与默认的core同时输出
+func newZapLogger(conf Config, serviceName string, o initOptions) zapLogger {
+	var zl zapLogger
+	cores := o.zapCores
 	if conf.File == "" {
 		conf.File = "./logs/run.log"
 	}
@@ -38,21 +85,129 @@ func newZapLogger(conf Config) zapLogger {
 		MaxBackups: conf.MaxBackups,
 		MaxAge:     conf.MaxAge,
 		LocalTime:  true,
-		Compress:   conf.Compress,
+		Compress:   lumberjackCompress(conf),
 	}
 	// Multi writer
 	// lumberWriter and consoleWrite
 	var multiWriter zapcore.WriteSyncer
 	var writeSyncers []zapcore.WriteSyncer
-	if conf.Output == "file" {
-		writeSyncers = append(writeSyncers, zapcore.AddSync(&hook))
+	var lumLogger fileRotator = newRotateWriter(&hook, conf, serviceName)
+	// 按字段的值写入不同的日志文件
+	var partitions *partitionFiles
+	key := partitionKey(conf.File)
+	if conf.Output == "file" && key != "" {
+		conf.File = expandFile(conf.File, serviceName, time.Now())
+		partitions = newPartitionFiles(conf, serviceName)
+		lumLogger = partitions
+		zl.rotator = partitions
+		zl.closers = append(zl.closers, partitions)
+	} else if conf.Output == "file" {
+		writer := newFileWriter(conf, serviceName)
+		lumLogger = writer
+		zl.rotator = writer
+		zl.closers = append(zl.closers, writer)
+		writeSyncers = append(writeSyncers, writer)
+	} else if isSinkOutput(conf.Output) {
+		sink, err := newSink(conf, serviceName, o)
+		if err != nil {
+			log.Printf("logx: create %s output failed: %v", conf.Output, err)
+			writeSyncers = append(writeSyncers, zapcore.AddSync(os.Stdout))
+		} else {
+			zl.closers = append(zl.closers, sink)
+			writeSyncers = append(writeSyncers, sink)
+		}
 	} else {
 		writeSyncers = append(writeSyncers, zapcore.AddSync(os.Stdout))
 	}
 	if len(writeSyncers) > 0 {
-		multiWriter = zapcore.NewMultiWriteSyncer(writeSyncers...)
+		multiWriter = countingWriteSyncer{zapcore.NewMultiWriteSyncer(writeSyncers...)}
+	}
+	// error及以上等级的日志写入ErrorFile，与File各自切割，ErrorFile也可以按字段分区
+	var errorWriter zapcore.WriteSyncer
+	var errorPartitions *partitionFiles
+	errorKey := partitionKey(conf.ErrorFile)
+	if conf.Output == "file" && conf.ErrorFile != "" {
+		errorConf := conf
+		errorConf.File = conf.ErrorFile
+		errorConf.FileLink = ""
+		if errorKey != "" {
+			errorConf.File = expandFile(errorConf.File, serviceName, time.Now())
+			errorPartitions = newPartitionFiles(errorConf, serviceName)
+			lumLogger = multiRotator{lumLogger, errorPartitions}
+			zl.closers = append(zl.closers, errorPartitions)
+		} else {
+			writer := newFileWriter(errorConf, serviceName)
+			lumLogger = multiRotator{lumLogger, writer}
+			zl.closers = append(zl.closers, writer)
+			errorWriter = countingWriteSyncer{writer}
+		}
+		zl.rotator = lumLogger
+	}
+
+	enco := newEncoder(conf)
+	if conf.Level != "" {
+		if err := SetLevel(conf.Level); err != nil {
+			atomicLevel.SetLevel(zap.ErrorLevel)
+		}
+	} else if conf.Debug {
+		atomicLevel.SetLevel(zap.DebugLevel)
+	} else {
+		atomicLevel.SetLevel(zap.ErrorLevel)
+	}
+
+	// new core config
+	// 按字段分区时写入各分区的文件
+	newCore := func(level zapcore.LevelEnabler) zapcore.Core {
+		if partitions != nil {
+			return newPartitionCore(enco, level, key, partitions)
+		}
+		return zapcore.NewCore(enco, multiWriter, level)
+	}
+	core := newCore(atomicLevel)
+	boostCore := newCore(zap.DebugLevel)
+	if errorWriter != nil || errorPartitions != nil {
+		newErrorCore := func(level zapcore.LevelEnabler) zapcore.Core {
+			if errorPartitions != nil {
+				return newPartitionCore(enco, level, errorKey, errorPartitions)
+			}
+			return zapcore.NewCore(enco, errorWriter, level)
+		}
+		core = zapcore.NewTee(
+			newCore(levelRange{atomicLevel, false}),
+			newErrorCore(levelRange{atomicLevel, true}),
+		)
+		boostCore = zapcore.NewTee(
+			newCore(levelRange{zap.DebugLevel, false}),
+			newErrorCore(levelRange{zap.DebugLevel, true}),
+		)
+	}
+
+	// 重命名为ECS的字段
+	if conf.Encoder == "ecs" {
+		core = ecsCore{Core: core, namespace: conf.AttributeNamespace}
+		boostCore = ecsCore{Core: boostCore, namespace: conf.AttributeNamespace}
 	}
 
+	// 超长日志拆分
+	if conf.MaxEntryBytes > 0 {
+		core = splitCores(conf.MaxEntryBytes, enco, core)[0]
+		boostCore = splitCores(conf.MaxEntryBytes, enco, boostCore)[0]
+		cores = splitCores(conf.MaxEntryBytes, enco, cores...)
+	}
+
+	// new logger
+	if len(cores) > 0 {
+		core = zapcore.NewTee(append([]zapcore.Core{core}, cores...)...)
+		boostCore = zapcore.NewTee(append([]zapcore.Core{boostCore}, cores...)...)
+	}
+	zl.Logger = zap.New(core, zapOptions(conf)...)
+	zl.lumLogger = lumLogger
+	zl.Boost = zap.New(boostCore, zapOptions(conf)...)
+	return zl
+}
+
+// newEncoder 按conf.Encoder创建日志的编码器
+func newEncoder(conf Config) zapcore.Encoder {
 	// encoderConfig
 	encoderConfig := zapcore.EncoderConfig{
 		TimeKey:       "time",
@@ -70,40 +225,280 @@ func newZapLogger(conf Config) zapLogger {
 		EncodeDuration: zapcore.SecondsDurationEncoder,
 		EncodeCaller:   zapcore.FullCallerEncoder,
 	}
-	// logLevel
+	applyEncoderKeys(&encoderConfig, conf)
 	// Encoder console or json
-	enco := zapcore.NewJSONEncoder(encoderConfig)
-	var atomicLevel zap.AtomicLevel
-	if conf.Debug {
-		atomicLevel = zap.NewAtomicLevelAt(zap.DebugLevel)
+	var enco zapcore.Encoder
+	if conf.Encoder == "gcp" {
+		enco = zapcore.NewJSONEncoder(gcpEncoderConfig(encoderConfig))
+	} else if conf.Encoder == "ecs" {
+		enco = zapcore.NewJSONEncoder(ecsEncoderConfig(encoderConfig))
+		enco.AddString("ecs.version", ecsVersion)
+	} else if conf.Encoder == "logfmt" {
+		enco = newLogfmtEncoder(encoderConfig)
+	} else if conf.Output == "console" && conf.Encoder == "console" {
+		if conf.Color {
+			encoderConfig.EncodeLevel = zapcore.LowercaseColorLevelEncoder
+		}
+		encoderConfig.EncodeCaller = zapcore.ShortCallerEncoder
+		enco = zapcore.NewConsoleEncoder(encoderConfig)
 	} else {
-		atomicLevel = zap.NewAtomicLevelAt(zap.ErrorLevel)
+		enco = zapcore.NewJSONEncoder(encoderConfig)
 	}
+	return enco
+}
 
-	// new core config
-	core := zapcore.NewCore(
-		enco,
-		multiWriter,
-		atomicLevel,
-	)
+// applyEncoderKeys 按配置修改json的key及时间格式，"-"为不输出该key
+func applyEncoderKeys(encoderConfig *zapcore.EncoderConfig, conf Config) {
+	keys := map[*string]string{
+		&encoderConfig.TimeKey:    conf.TimeKey,
+		&encoderConfig.LevelKey:   conf.LevelKey,
+		&encoderConfig.MessageKey: conf.MessageKey,
+		&encoderConfig.CallerKey:  conf.CallerKey,
+	}
+	for key, value := range keys {
+		switch value {
+		case "":
+		case "-":
+			*key = zapcore.OmitKey
+		default:
+			*key = value
+		}
+	}
+	if conf.TimeFormat == "" && conf.TimeZone == "" {
+		return
+	}
+	loc := time.Local
+	if conf.TimeZone != "" {
+		if l, err := time.LoadLocation(conf.TimeZone); err == nil {
+			loc = l
+		} else {
+			log.Printf("logx: load time zone failed: %v", err)
+		}
+	}
+	encoderConfig.EncodeTime = func(t time.Time, encoder zapcore.PrimitiveArrayEncoder) {
+		t = t.In(loc)
+		switch conf.TimeFormat {
+		case "epoch":
+			encoder.AppendInt64(t.Unix())
+		case "epochmillis":
+			encoder.AppendInt64(t.UnixMilli())
+		case "epochnanos":
+			encoder.AppendInt64(t.UnixNano())
+		case "rfc3339":
+			encoder.AppendString(t.Format(time.RFC3339))
+		case "rfc3339nano":
+			encoder.AppendString(t.Format(time.RFC3339Nano))
+		case "":
+			encoder.AppendString(t.Format("2006-01-02 15:04:05"))
+		default:
+			encoder.AppendString(t.Format(conf.TimeFormat))
+		}
+	}
+}
 
-	// new logger
-	logger := zap.New(core, zap.AddCaller(), zap.AddCallerSkip(1))
-	return zapLogger{
-		Logger:    logger,
-		lumLogger: &hook,
+// objectCallerEncoder 将调用位置编码为对象，如{"file":"main.go","line":10,"function":"main.main"}
+func objectCallerEncoder(fileKey, lineKey, functionKey string) zapcore.CallerEncoder {
+	return func(caller zapcore.EntryCaller, enc zapcore.PrimitiveArrayEncoder) {
+		// json编码器同时实现了ArrayEncoder
+		if arr, ok := enc.(zapcore.ArrayEncoder); ok {
+			arr.AppendObject(zapcore.ObjectMarshalerFunc(func(obj zapcore.ObjectEncoder) error {
+				obj.AddString(fileKey, caller.File)
+				obj.AddInt(lineKey, caller.Line)
+				obj.AddString(functionKey, caller.Function)
+				return nil
+			}))
+			return
+		}
+		enc.AppendString(caller.String())
+	}
+}
+
+// zapOptions 调用位置、堆栈及采样的配置，默认跳过logx自身的一层调用
+func zapOptions(conf Config) []zap.Option {
+	options := []zap.Option{zap.AddCallerSkip(1 + conf.CallerSkip)}
+	if conf.LogSampling.Initial > 0 {
+		options = append(options, zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return samplingCore(conf.LogSampling, core)
+		}))
+	}
+	if !conf.DisableCaller {
+		options = append(options, zap.AddCaller())
+	}
+	if conf.StacktraceLevel != "" {
+		if level, err := zapcore.ParseLevel(conf.StacktraceLevel); err == nil {
+			options = append(options, zap.AddStacktrace(level))
+		}
 	}
+	return options
 }
 
-// rotateCrond
+// SetLevel 运行时修改日志等级
+// level: debug,info,warn,error,dpanic,panic,fatal
+func SetLevel(level string) error {
+	l, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	atomicLevel.SetLevel(l)
+	return nil
+}
+
+// Enabled level等级的日志在ctx下是否会被记录，包括写入日志、推送Loki及记录为span事件
+// 可以用于跳过构建开销较大的字段，level无效时返回false
+//
+// example:
+//
+//	if logx.Enabled(ctx, "debug") {
+//		logx.Debug(ctx, "request", logx.Any("payload", dump(req)))
+//	}
+func Enabled(ctx context.Context, level string) bool {
+	l, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return false
+	}
+	return enabled(ctx, l)
+}
+
+func enabled(ctx context.Context, level zapcore.Level) bool {
+	s := loadState()
+	if disabled.Load() {
+		return false
+	}
+	if s.config.LokiServer != "" {
+		return true
+	}
+	if s.config.EnableTrace {
+		if _, ok := ctx.Value(loggerSpanContextKey).(LoggerSpanContext); ok {
+			return true
+		}
+	}
+	if !s.enabled {
+		return false
+	}
+	if s.logger.Core().Enabled(level) {
+		return true
+	}
+	if s.boost == nil {
+		return false
+	}
+	sc := oteltrace.SpanContextFromContext(ctx)
+	return (s.config.ErrorBuffer > 0 && sc.IsValid()) || errorTraces.boosted(sc)
+}
+
+// LevelHandler 返回修改日志等级的http.Handler，与zap的level endpoint兼容
+//
+// GET 获取当前的日志等级
+// PUT 修改日志等级，如 curl -X PUT -d '{"level":"debug"}'
+func LevelHandler() http.Handler {
+	return atomicLevel
+}
+
+// rotateCrond 按Rotate定时切割，重新Init时停止之前的定时任务
 func (zl zapLogger) rotateCrond(conf Config) {
+	if rotateCron != nil {
+		rotateCron.Stop()
+		rotateCron = nil
+	}
 	if conf.Rotate != "" {
-		rotateCrondOnce.Do(func() {
-			cron := cron.New(cron.WithSeconds())
-			cron.AddFunc(conf.Rotate, func() {
-				zl.lumLogger.Rotate()
-			})
-			cron.Start()
+		rotateCron = cron.New(cron.WithSeconds())
+		rotateCron.AddFunc(rotateSpec(conf.Rotate), func() {
+			zl.lumLogger.Rotate()
 		})
+		rotateCron.Start()
+	}
+}
+
+// exitHook 写入fatal日志后以指定的code退出
+type exitHook int
+
+func (code exitHook) OnWrite(*zapcore.CheckedEntry, []zapcore.Field) {
+	os.Exit(int(code))
+}
+
+// Zap 返回底层的*zap.Logger，用于zap.Object等zap的高级特性
+// 通过ZapContext(ctx)传入context，可以保留trace_id,span_id等字段的注入
+// 未开启日志时返回zap.NewNop()
+//
+// example:
+// Zap().Info("msg", ZapContext(ctx), zap.Object("obj", obj))
+func Zap() *zap.Logger {
+	s := loadState()
+	if !s.enabled {
+		return zap.NewNop()
+	}
+	return s.logger.WithOptions(
+		zap.AddCallerSkip(-1),
+		zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+			return contextCore{core}
+		}),
+	)
+}
+
+const zapContextKey = "logx.context"
+
+// ZapContext 将context作为zap字段传入Zap()返回的logger
+func ZapContext(ctx context.Context) zap.Field {
+	return zap.Field{Key: zapContextKey, Type: zapcore.SkipType, Interface: ctx}
+}
+
+// contextCore 将ZapContext字段展开为trace_id,span_id等字段
+type contextCore struct {
+	zapcore.Core
+}
+
+func (c contextCore) With(fields []zapcore.Field) zapcore.Core {
+	return contextCore{c.Core.With(expandZapContext(fields))}
+}
+
+func (c contextCore) Check(entry zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checkInner(c.Core, entry, ce, func(checked *zapcore.CheckedEntry, fields []zapcore.Field) {
+		checked.Write(expandZapContext(fields)...)
+	})
+}
+
+func (c contextCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(entry, expandZapContext(fields))
+}
+
+// checkInner 由内层core的Check决定写入哪些core(采样、ErrorFile的等级路由、zap.Hooks等)，写入时由write转换字段
+// 包装的core不能直接ce.AddCore(ent, c)后调用内层core的Write，zap.Hooks包装的core的Write只执行hook而不输出
+func checkInner(inner zapcore.Core, ent zapcore.Entry, ce *zapcore.CheckedEntry, write func(*zapcore.CheckedEntry, []zapcore.Field)) *zapcore.CheckedEntry {
+	checked := inner.Check(ent, nil)
+	if checked == nil {
+		return ce
+	}
+	c := &checkedCore{Core: inner, checked: checked, write: write}
+	ce = ce.AddCore(ent, c)
+	c.outer = ce
+	return ce
+}
+
+// checkedCore 写入内层core Check返回的CheckedEntry
+type checkedCore struct {
+	zapcore.Core
+	checked *zapcore.CheckedEntry
+	outer   *zapcore.CheckedEntry
+	write   func(*zapcore.CheckedEntry, []zapcore.Field)
+}
+
+func (c *checkedCore) Write(_ zapcore.Entry, fields []zapcore.Field) error {
+	c.checked.ErrorOutput = c.outer.ErrorOutput
+	c.write(c.checked, fields)
+	return nil
+}
+
+// expandZapContext 展开ZapContext字段
+func expandZapContext(fields []zapcore.Field) []zapcore.Field {
+	for i, f := range fields {
+		if f.Key != zapContextKey || f.Type != zapcore.SkipType {
+			continue
+		}
+		ctx, ok := f.Interface.(context.Context)
+		if !ok {
+			continue
+		}
+		expanded := append(fields[:i:i], FieldsToZapFields(ctx, contextFields(ctx)...)...)
+		return append(expanded, expandZapContext(fields[i+1:])...)
 	}
+	return fields
 }